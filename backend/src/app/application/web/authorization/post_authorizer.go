@@ -0,0 +1,68 @@
+package authorization
+
+import (
+	"errors"
+	"proto-pulse-plat/auth"
+	"proto-pulse-plat/domain/apperror"
+	"proto-pulse-plat/domain/entity"
+	"proto-pulse-plat/domain/repository"
+
+	"gorm.io/gorm"
+)
+
+// 投稿の更新・削除・画像変更の前に、操作者が投稿者本人であることを確認する
+type PostAuthorizer interface {
+	AuthorizeMutation(userID uint, postID int) (*entity.Post, error)
+	// 削除はモデレーター以上であれば投稿者以外も行える
+	AuthorizeDeletion(principal *auth.Principal, postID int) (*entity.Post, error)
+}
+
+type postAuthorizer struct {
+	postRepo repository.PostRepository
+}
+
+func NewPostAuthorizer(postRepo repository.PostRepository) PostAuthorizer {
+	return &postAuthorizer{
+		postRepo: postRepo,
+	}
+}
+
+func (a *postAuthorizer) AuthorizeMutation(userID uint, postID int) (*entity.Post, error) {
+	if userID == 0 {
+		return nil, apperror.Unauthorized("authentication is required")
+	}
+
+	post, err := a.postRepo.FindByID(postID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, apperror.NotFound("post %d was not found", postID)
+		}
+		return nil, err
+	}
+
+	if post.UserID != userID {
+		return nil, apperror.Forbidden("user %d is not the author of post %d", userID, postID)
+	}
+
+	return post, nil
+}
+
+func (a *postAuthorizer) AuthorizeDeletion(principal *auth.Principal, postID int) (*entity.Post, error) {
+	if principal == nil {
+		return nil, apperror.Unauthorized("authentication is required")
+	}
+
+	if !principal.HasRole(auth.RoleModerator) {
+		return a.AuthorizeMutation(principal.UserID, postID)
+	}
+
+	post, err := a.postRepo.FindByIDIncludingSuspended(postID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, apperror.NotFound("post %d was not found", postID)
+		}
+		return nil, err
+	}
+
+	return post, nil
+}
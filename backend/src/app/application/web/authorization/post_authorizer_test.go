@@ -0,0 +1,114 @@
+package authorization
+
+import (
+	"errors"
+	"testing"
+
+	"proto-pulse-plat/auth"
+	"proto-pulse-plat/domain/apperror"
+	"proto-pulse-plat/domain/entity"
+	"proto-pulse-plat/domain/repository"
+
+	"gorm.io/gorm"
+)
+
+// FindByID は利用停止中のユーザーの投稿を返さない
+type fakePostRepository struct {
+	repository.PostRepository
+	posts          map[int]*entity.Post
+	suspendedPosts map[int]bool
+}
+
+func (r *fakePostRepository) FindByID(postID int) (*entity.Post, error) {
+	if r.suspendedPosts[postID] {
+		return nil, gorm.ErrRecordNotFound
+	}
+	return r.FindByIDIncludingSuspended(postID)
+}
+
+func (r *fakePostRepository) FindByIDIncludingSuspended(postID int) (*entity.Post, error) {
+	post, ok := r.posts[postID]
+	if !ok {
+		return nil, gorm.ErrRecordNotFound
+	}
+	return post, nil
+}
+
+func newTestPostAuthorizer() PostAuthorizer {
+	return NewPostAuthorizer(&fakePostRepository{
+		posts: map[int]*entity.Post{
+			1: {ID: 1, UserID: 10},
+			2: {ID: 2, UserID: 20},
+		},
+		suspendedPosts: map[int]bool{2: true},
+	})
+}
+
+func TestAuthorizeMutation(t *testing.T) {
+	tests := []struct {
+		name     string
+		userID   uint
+		postID   int
+		wantCode apperror.Code
+	}{
+		{name: "author", userID: 10, postID: 1},
+		{name: "other user", userID: 11, postID: 1, wantCode: apperror.CodeForbidden},
+		{name: "anonymous", userID: 0, postID: 1, wantCode: apperror.CodeUnauthorized},
+		{name: "missing post", userID: 10, postID: 99, wantCode: apperror.CodeNotFound},
+		// 利用停止中の投稿者本人も更新できない
+		{name: "suspended author", userID: 20, postID: 2, wantCode: apperror.CodeNotFound},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			post, err := newTestPostAuthorizer().AuthorizeMutation(tt.userID, tt.postID)
+			assertAuthorization(t, post, err, tt.postID, tt.wantCode)
+		})
+	}
+}
+
+func TestAuthorizeDeletion(t *testing.T) {
+	tests := []struct {
+		name      string
+		principal *auth.Principal
+		postID    int
+		wantCode  apperror.Code
+	}{
+		{name: "author", principal: &auth.Principal{UserID: 10, Role: auth.RoleUser}, postID: 1},
+		{name: "other user", principal: &auth.Principal{UserID: 11, Role: auth.RoleUser}, postID: 1, wantCode: apperror.CodeForbidden},
+		{name: "anonymous", principal: nil, postID: 1, wantCode: apperror.CodeUnauthorized},
+		{name: "missing post", principal: &auth.Principal{UserID: 10, Role: auth.RoleUser}, postID: 99, wantCode: apperror.CodeNotFound},
+		{name: "moderator", principal: &auth.Principal{UserID: 11, Role: auth.RoleModerator}, postID: 1},
+		{name: "admin", principal: &auth.Principal{UserID: 11, Role: auth.RoleAdmin}, postID: 1},
+		// モデレーターは利用停止中のユーザーの投稿も削除できる
+		{name: "moderator on suspended author", principal: &auth.Principal{UserID: 11, Role: auth.RoleModerator}, postID: 2},
+		{name: "moderator on missing post", principal: &auth.Principal{UserID: 11, Role: auth.RoleModerator}, postID: 99, wantCode: apperror.CodeNotFound},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			post, err := newTestPostAuthorizer().AuthorizeDeletion(tt.principal, tt.postID)
+			assertAuthorization(t, post, err, tt.postID, tt.wantCode)
+		})
+	}
+}
+
+// wantCode が空のときは postID の投稿が返ることを確認する
+func assertAuthorization(t *testing.T, post *entity.Post, err error, postID int, wantCode apperror.Code) {
+	t.Helper()
+
+	if wantCode == "" {
+		if err != nil {
+			t.Fatalf("err = %v, want nil", err)
+		}
+		if post == nil || post.ID != uint(postID) {
+			t.Errorf("post = %+v, want post %d", post, postID)
+		}
+		return
+	}
+
+	if !errors.Is(err, &apperror.Error{Code: wantCode}) {
+		t.Errorf("err = %v, want %s", err, wantCode)
+	}
+	if post != nil {
+		t.Errorf("post = %+v, want nil", post)
+	}
+}
@@ -0,0 +1,264 @@
+package usecase
+
+import (
+	"errors"
+	"net/http"
+	"proto-pulse-plat/auth"
+	"proto-pulse-plat/domain/apperror"
+	"proto-pulse-plat/domain/entity"
+	"proto-pulse-plat/domain/repository"
+	"proto-pulse-plat/helper"
+	"proto-pulse-plat/infrastructure/mapper"
+	"proto-pulse-plat/infrastructure/response"
+	"strconv"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type AdminUsecase interface {
+	UpdateRole(r *http.Request) error
+	Suspend(r *http.Request) error
+	Unsuspend(r *http.Request) error
+	GetSettings(r *http.Request) (*response.AppSettings, error)
+	UpdateSettings(r *http.Request) (*response.AppSettings, error)
+}
+
+type adminUsecase struct {
+	userRepo       repository.UsersRepository
+	suspensionRepo repository.UserSuspensionsRepository
+	sessionRepo    repository.SessionsRepository
+	settingsRepo   repository.AppSettingsRepository
+}
+
+func NewAdminUsecase(
+	userRepo repository.UsersRepository,
+	suspensionRepo repository.UserSuspensionsRepository,
+	sessionRepo repository.SessionsRepository,
+	settingsRepo repository.AppSettingsRepository,
+) AdminUsecase {
+	return &adminUsecase{
+		userRepo:       userRepo,
+		suspensionRepo: suspensionRepo,
+		sessionRepo:    sessionRepo,
+		settingsRepo:   settingsRepo,
+	}
+}
+
+type UpdateRoleRequest struct {
+	UserID uint   `json:"user_id"`
+	Role   string `json:"role"`
+}
+
+// ExpiresAt を省略した場合は解除されるまで無期限
+type SuspendUserRequest struct {
+	UserID    uint       `json:"user_id"`
+	Reason    string     `json:"reason"`
+	ExpiresAt *time.Time `json:"expires_at"`
+}
+
+type UnsuspendUserRequest struct {
+	UserID uint `json:"user_id"`
+}
+
+// 省略した項目は変更しない
+type UpdateAppSettingsRequest struct {
+	KeepUploadGPS *bool `json:"keep_upload_gps"`
+}
+
+const maxSuspensionReasonLength = 1000
+
+// アプリケーション全体の設定を返す。管理者のみ実行できる
+func (u *adminUsecase) GetSettings(r *http.Request) (*response.AppSettings, error) {
+	if _, err := u.admin(r); err != nil {
+		return nil, err
+	}
+
+	return u.settings()
+}
+
+func (u *adminUsecase) UpdateSettings(r *http.Request) (*response.AppSettings, error) {
+	err := helper.ValidateMethod(r, http.MethodPost)
+	if err != nil {
+		return nil, err
+	}
+
+	principal, err := u.admin(r)
+	if err != nil {
+		return nil, err
+	}
+
+	var req UpdateAppSettingsRequest
+	if err := helper.DecodeJSONBody(r, &req); err != nil {
+		return nil, err
+	}
+
+	if req.KeepUploadGPS != nil {
+		_, err := u.settingsRepo.Save(
+			mapper.ToModelAppSetting(entity.AppSettingKeepUploadGPS, strconv.FormatBool(*req.KeepUploadGPS), principal.UserID),
+		)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return u.settings()
+}
+
+func (u *adminUsecase) settings() (*response.AppSettings, error) {
+	keepUploadGPS, err := boolSetting(u.settingsRepo, entity.AppSettingKeepUploadGPS)
+	if err != nil {
+		return nil, err
+	}
+
+	return &response.AppSettings{
+		KeepUploadGPS: keepUploadGPS,
+	}, nil
+}
+
+func (u *adminUsecase) admin(r *http.Request) (*auth.Principal, error) {
+	principal, err := currentPrincipal(r)
+	if err != nil {
+		return nil, err
+	}
+	if !principal.HasRole(auth.RoleAdmin) {
+		return nil, apperror.Forbidden("user %d is not an admin", principal.UserID)
+	}
+	return principal, nil
+}
+
+// ユーザーのロールを変更する。管理者のみ実行でき、自分自身のロールは変更できない
+func (u *adminUsecase) UpdateRole(r *http.Request) error {
+	err := helper.ValidateMethod(r, http.MethodPost)
+	if err != nil {
+		return err
+	}
+
+	principal, err := u.admin(r)
+	if err != nil {
+		return err
+	}
+
+	var req UpdateRoleRequest
+	if err := helper.DecodeJSONBody(r, &req); err != nil {
+		return err
+	}
+
+	if !auth.IsValidRole(req.Role) {
+		return apperror.InvalidArgument("role %q is not valid", req.Role)
+	}
+	if req.UserID == principal.UserID {
+		return apperror.Forbidden("cannot change own role")
+	}
+
+	if err := u.userRepo.UpdateRole(req.UserID, req.Role); err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return apperror.NotFound("user %d was not found", req.UserID)
+		}
+		return err
+	}
+
+	return nil
+}
+
+// ユーザーを利用停止にし、全セッションを失効させる
+func (u *adminUsecase) Suspend(r *http.Request) error {
+	principal, err := u.moderator(r)
+	if err != nil {
+		return err
+	}
+
+	var req SuspendUserRequest
+	if err := helper.DecodeJSONBody(r, &req); err != nil {
+		return err
+	}
+
+	req.Reason = strings.TrimSpace(req.Reason)
+	if req.Reason == "" {
+		return apperror.InvalidArgument("reason is required")
+	}
+	if len([]rune(req.Reason)) > maxSuspensionReasonLength {
+		return apperror.InvalidArgument("reason must be at most %d characters", maxSuspensionReasonLength)
+	}
+	if req.ExpiresAt != nil && !req.ExpiresAt.After(time.Now()) {
+		return apperror.InvalidArgument("expires_at must be in the future")
+	}
+
+	target, err := u.moderationTarget(principal, req.UserID)
+	if err != nil {
+		return err
+	}
+
+	_, err = u.suspensionRepo.Save(mapper.ToModelUserSuspension(target.ID, req.Reason, principal.UserID, req.ExpiresAt))
+	if err != nil {
+		return err
+	}
+
+	if err := u.sessionRepo.RevokeAllByUserID(target.ID); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// 有効な利用停止をすべて解除し、投稿を再び表示する
+func (u *adminUsecase) Unsuspend(r *http.Request) error {
+	principal, err := u.moderator(r)
+	if err != nil {
+		return err
+	}
+
+	var req UnsuspendUserRequest
+	if err := helper.DecodeJSONBody(r, &req); err != nil {
+		return err
+	}
+
+	target, err := u.moderationTarget(principal, req.UserID)
+	if err != nil {
+		return err
+	}
+
+	if err := u.suspensionRepo.LiftActiveByUserID(target.ID, principal.UserID); err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return apperror.NotFound("user %d is not suspended", target.ID)
+		}
+		return err
+	}
+
+	return nil
+}
+
+func (u *adminUsecase) moderator(r *http.Request) (*auth.Principal, error) {
+	err := helper.ValidateMethod(r, http.MethodPost)
+	if err != nil {
+		return nil, err
+	}
+
+	principal, err := currentPrincipal(r)
+	if err != nil {
+		return nil, err
+	}
+	if !principal.HasRole(auth.RoleModerator) {
+		return nil, apperror.Forbidden("user %d is not a moderator", principal.UserID)
+	}
+
+	return principal, nil
+}
+
+// 利用停止の対象ユーザーを読み込む。自分より上位または同じロールのユーザーは対象にできない
+func (u *adminUsecase) moderationTarget(principal *auth.Principal, userID uint) (*entity.User, error) {
+	target, err := u.userRepo.FindForAuthentication(userID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, apperror.NotFound("user %d was not found", userID)
+		}
+		return nil, err
+	}
+
+	if !auth.OutranksRole(principal.Role, target.Role) {
+		return nil, apperror.Forbidden("user %d cannot moderate user %d", principal.UserID, target.ID)
+	}
+
+	return target, nil
+}
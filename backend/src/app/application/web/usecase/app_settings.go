@@ -0,0 +1,26 @@
+package usecase
+
+import (
+	"errors"
+	"proto-pulse-plat/domain/repository"
+	"strconv"
+
+	"gorm.io/gorm"
+)
+
+// 未設定の場合は false とする
+func boolSetting(settingsRepo repository.AppSettingsRepository, key string) (bool, error) {
+	setting, err := settingsRepo.Find(key)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return false, nil
+		}
+		return false, err
+	}
+
+	value, err := strconv.ParseBool(setting.Value)
+	if err != nil {
+		return false, nil
+	}
+	return value, nil
+}
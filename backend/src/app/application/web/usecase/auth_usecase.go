@@ -0,0 +1,100 @@
+package usecase
+
+import (
+	"errors"
+	"net/http"
+	"proto-pulse-plat/auth"
+	"proto-pulse-plat/domain/apperror"
+	"proto-pulse-plat/domain/repository"
+	"proto-pulse-plat/helper"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type AuthUsecase interface {
+	Refresh(r *http.Request) (*auth.TokenPair, error)
+}
+
+type authUsecase struct {
+	userRepo         repository.UsersRepository
+	sessionRepo      repository.SessionsRepository
+	refreshTokenRepo repository.RefreshTokensRepository
+	tokenIssuer      TokenIssuer
+}
+
+func NewAuthUsecase(
+	userRepo repository.UsersRepository,
+	sessionRepo repository.SessionsRepository,
+	refreshTokenRepo repository.RefreshTokensRepository,
+	tokenIssuer TokenIssuer,
+) AuthUsecase {
+	return &authUsecase{
+		userRepo:         userRepo,
+		sessionRepo:      sessionRepo,
+		refreshTokenRepo: refreshTokenRepo,
+		tokenIssuer:      tokenIssuer,
+	}
+}
+
+// リフレッシュトークンを使用済みにして新しいトークンを発行する。
+// 使用済みのトークンが再提示された場合は漏洩とみなし、ファミリー (セッション) ごと失効させる
+func (u *authUsecase) Refresh(r *http.Request) (*auth.TokenPair, error) {
+	err := helper.ValidateMethod(r, http.MethodPost)
+	if err != nil {
+		return nil, err
+	}
+
+	cookie, err := r.Cookie(helper.RefreshCookieName)
+	if err != nil || cookie.Value == "" {
+		return nil, apperror.Unauthorized("refresh token is missing")
+	}
+
+	refreshToken, err := u.refreshTokenRepo.FindByHash(auth.HashRefreshToken(cookie.Value))
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, apperror.Unauthorized("refresh token is unknown")
+		}
+		return nil, err
+	}
+
+	if refreshToken.UsedAt != nil {
+		return nil, u.revokeFamily(refreshToken.UserID, refreshToken.SessionID)
+	}
+
+	if time.Now().After(refreshToken.ExpiresAt) {
+		return nil, apperror.Unauthorized("refresh token is expired")
+	}
+
+	marked, err := u.refreshTokenRepo.MarkUsed(refreshToken.ID)
+	if err != nil {
+		return nil, err
+	}
+	if !marked {
+		// 同じトークンが並行して使用された
+		return nil, u.revokeFamily(refreshToken.UserID, refreshToken.SessionID)
+	}
+
+	session, err := u.sessionRepo.FindActiveByID(refreshToken.SessionID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, apperror.Unauthorized("session is not active")
+		}
+		return nil, err
+	}
+
+	user, err := u.userRepo.Find(refreshToken.UserID)
+	if err != nil {
+		return nil, err
+	}
+
+	return u.tokenIssuer.Rotate(*user, *session)
+}
+
+func (u *authUsecase) revokeFamily(userID uint, sessionID string) error {
+	err := u.sessionRepo.Revoke(userID, sessionID)
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		return err
+	}
+	return apperror.Unauthorized("refresh token reuse detected for session %q", sessionID)
+}
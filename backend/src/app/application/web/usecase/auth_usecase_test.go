@@ -0,0 +1,244 @@
+package usecase
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"proto-pulse-plat/auth"
+	"proto-pulse-plat/config"
+	"proto-pulse-plat/domain/apperror"
+	"proto-pulse-plat/domain/entity"
+	"proto-pulse-plat/domain/repository"
+	"proto-pulse-plat/helper"
+	"proto-pulse-plat/infrastructure/model"
+	"strings"
+	"testing"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type fakeUsersRepository struct {
+	repository.UsersRepository
+	users map[uint]*entity.User
+	// ログイン方法が紐付いていないユーザーのスクリーンネーム
+	unlinkedAccountIDs []string
+}
+
+func (f *fakeUsersRepository) Find(id uint) (*entity.User, error) {
+	user, ok := f.users[id]
+	if !ok {
+		return nil, gorm.ErrRecordNotFound
+	}
+	return user, nil
+}
+
+func (f *fakeUsersRepository) ExistsUnlinkedByAccountID(accountID string) (bool, error) {
+	for _, unlinked := range f.unlinkedAccountIDs {
+		if strings.EqualFold(unlinked, accountID) {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+type fakeSessionsRepository struct {
+	repository.SessionsRepository
+	sessions map[string]*entity.Session
+}
+
+func (f *fakeSessionsRepository) Save(session model.Session) (*entity.Session, error) {
+	saved := &entity.Session{
+		ID:         session.ID,
+		UserID:     session.UserID,
+		UserAgent:  session.UserAgent,
+		LastSeenAt: session.LastSeenAt,
+		ExpiresAt:  session.ExpiresAt,
+	}
+	f.sessions[session.ID] = saved
+	return saved, nil
+}
+
+func (f *fakeSessionsRepository) FindActiveByID(id string) (*entity.Session, error) {
+	session, ok := f.sessions[id]
+	if !ok || session.RevokedAt != nil {
+		return nil, gorm.ErrRecordNotFound
+	}
+	return session, nil
+}
+
+func (f *fakeSessionsRepository) Revoke(userID uint, id string) error {
+	session, ok := f.sessions[id]
+	if !ok || session.UserID != userID || session.RevokedAt != nil {
+		return gorm.ErrRecordNotFound
+	}
+	now := time.Now()
+	session.RevokedAt = &now
+	return nil
+}
+
+type fakeRefreshTokensRepository struct {
+	repository.RefreshTokensRepository
+	tokens map[string]*entity.RefreshToken
+}
+
+func (f *fakeRefreshTokensRepository) Save(token model.RefreshToken) (*entity.RefreshToken, error) {
+	saved := &entity.RefreshToken{
+		ID:        uint(len(f.tokens) + 1),
+		SessionID: token.SessionID,
+		UserID:    token.UserID,
+		TokenHash: token.TokenHash,
+		ExpiresAt: token.ExpiresAt,
+	}
+	f.tokens[token.TokenHash] = saved
+	return saved, nil
+}
+
+func (f *fakeRefreshTokensRepository) FindByHash(tokenHash string) (*entity.RefreshToken, error) {
+	token, ok := f.tokens[tokenHash]
+	if !ok {
+		return nil, gorm.ErrRecordNotFound
+	}
+	// 呼び出し側で書き換えられないよう複製を返す
+	copied := *token
+	return &copied, nil
+}
+
+func (f *fakeRefreshTokensRepository) MarkUsed(id uint) (bool, error) {
+	for _, token := range f.tokens {
+		if token.ID != id {
+			continue
+		}
+		if token.UsedAt != nil {
+			return false, nil
+		}
+		now := time.Now()
+		token.UsedAt = &now
+		return true, nil
+	}
+	return false, gorm.ErrRecordNotFound
+}
+
+type authTestEnv struct {
+	usecase       AuthUsecase
+	issuer        TokenIssuer
+	user          entity.User
+	sessions      *fakeSessionsRepository
+	refreshTokens *fakeRefreshTokensRepository
+}
+
+func newAuthTestEnv(t *testing.T) *authTestEnv {
+	t.Helper()
+	t.Setenv("JWT_SECRET_KEY", "test-secret")
+	t.Setenv("JWT_KEYS", "")
+	t.Setenv("JWT_ACTIVE_KID", "")
+	keyRing, err := config.LoadKeyRing()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	user := entity.User{ID: 1, UserName: "Alice", AccountID: "alice", Role: auth.RoleUser}
+	users := &fakeUsersRepository{users: map[uint]*entity.User{user.ID: &user}}
+	sessions := &fakeSessionsRepository{sessions: map[string]*entity.Session{}}
+	refreshTokens := &fakeRefreshTokensRepository{tokens: map[string]*entity.RefreshToken{}}
+	issuer := NewTokenIssuer(keyRing, sessions, refreshTokens)
+
+	return &authTestEnv{
+		usecase:       NewAuthUsecase(users, sessions, refreshTokens, issuer),
+		issuer:        issuer,
+		user:          user,
+		sessions:      sessions,
+		refreshTokens: refreshTokens,
+	}
+}
+
+func (env *authTestEnv) refresh(refreshToken string) (*auth.TokenPair, error) {
+	r := httptest.NewRequest(http.MethodPost, "/api/auth/refresh", nil)
+	r.AddCookie(&http.Cookie{Name: helper.RefreshCookieName, Value: refreshToken})
+	return env.usecase.Refresh(r)
+}
+
+func TestRefreshRotatesToken(t *testing.T) {
+	env := newAuthTestEnv(t)
+	issued, err := env.issuer.Issue(env.user, "test")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rotated, err := env.refresh(issued.RefreshToken)
+	if err != nil {
+		t.Fatalf("Refresh: %v", err)
+	}
+	if rotated.RefreshToken == issued.RefreshToken {
+		t.Errorf("refresh token was not rotated")
+	}
+
+	if _, err := env.refresh(rotated.RefreshToken); err != nil {
+		t.Errorf("Refresh with the rotated token: %v", err)
+	}
+}
+
+// 使用済みのトークンが再提示された場合は、後から発行したトークンも含めてファミリーごと失効させる
+func TestRefreshReuseRevokesFamily(t *testing.T) {
+	env := newAuthTestEnv(t)
+	issued, err := env.issuer.Issue(env.user, "test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// 同じユーザーの別のセッションは影響を受けない
+	other, err := env.issuer.Issue(env.user, "other")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rotated, err := env.refresh(issued.RefreshToken)
+	if err != nil {
+		t.Fatalf("Refresh: %v", err)
+	}
+
+	_, err = env.refresh(issued.RefreshToken)
+	if !errors.Is(err, apperror.ErrUnauthorized) {
+		t.Fatalf("reusing a refresh token returned %v, want unauthorized", err)
+	}
+
+	if _, err := env.refresh(rotated.RefreshToken); !errors.Is(err, apperror.ErrUnauthorized) {
+		t.Errorf("token issued after the reused one returned %v, want unauthorized", err)
+	}
+	if _, err := env.refresh(other.RefreshToken); err != nil {
+		t.Errorf("token of another session: %v", err)
+	}
+}
+
+func TestRefreshRejectsInvalidTokens(t *testing.T) {
+	env := newAuthTestEnv(t)
+	issued, err := env.issuer.Issue(env.user, "test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	expired, err := env.issuer.Issue(env.user, "expired")
+	if err != nil {
+		t.Fatal(err)
+	}
+	env.refreshTokens.tokens[auth.HashRefreshToken(expired.RefreshToken)].ExpiresAt = time.Now().Add(-time.Minute)
+
+	tests := []struct {
+		name  string
+		token string
+	}{
+		{name: "missing", token: ""},
+		{name: "unknown", token: "unknown"},
+		{name: "expired", token: expired.RefreshToken},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := env.refresh(tt.token); !errors.Is(err, apperror.ErrUnauthorized) {
+				t.Errorf("got %v, want unauthorized", err)
+			}
+		})
+	}
+
+	// 期限切れや不明なトークンでは、セッションを失効させない
+	if _, err := env.refresh(issued.RefreshToken); err != nil {
+		t.Errorf("Refresh: %v", err)
+	}
+}
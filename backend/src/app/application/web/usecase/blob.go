@@ -0,0 +1,102 @@
+package usecase
+
+import (
+	"io"
+	"log"
+	"proto-pulse-plat/domain/repository"
+	"proto-pulse-plat/domain/storage"
+	"proto-pulse-plat/helper"
+	"proto-pulse-plat/infrastructure/blobstore"
+)
+
+// BlobStore に保存する内容。キーは内容のハッシュから決まる
+type blobContent struct {
+	namespace string
+	data      []byte
+	mimeType  string
+}
+
+func (c blobContent) checksum() string {
+	return helper.ContentHash(c.data)
+}
+
+func (c blobContent) key() string {
+	return blobstore.ContentKey(c.namespace, c.checksum())
+}
+
+func blobContentKeys(contents []blobContent) []string {
+	keys := make([]string, 0, len(contents))
+	for _, content := range contents {
+		keys = append(keys, content.key())
+	}
+	return keys
+}
+
+// 内容を保存してから fn で参照する行を保存する。fn が終わるまで同じキーの内容は削除されない
+func withStoredBlobs(
+	blobLocks repository.BlobLocksRepository,
+	blobStore storage.BlobStore,
+	contents []blobContent,
+	fn func() error,
+) error {
+	return blobLocks.WithSharedLock(blobContentKeys(contents), func() error {
+		for _, content := range contents {
+			if err := blobStore.Put(content.key(), content.data, content.mimeType); err != nil {
+				return err
+			}
+		}
+		return fn()
+	})
+}
+
+// 内容のハッシュをキーにして保存し、キーとハッシュを返す。同じ内容は同じキーに保存される
+func putContent(blobStore storage.BlobStore, namespace string, data []byte, mimeType string) (string, string, error) {
+	checksum := helper.ContentHash(data)
+	key := blobstore.ContentKey(namespace, checksum)
+	if err := blobStore.Put(key, data, mimeType); err != nil {
+		return "", "", err
+	}
+	return key, checksum, nil
+}
+
+// 内容をすべて読み込む。画像を展開するときなど、配信以外で使う
+func readBlob(blobStore storage.BlobStore, key string) ([]byte, error) {
+	content, err := blobStore.Get(key)
+	if err != nil {
+		return nil, err
+	}
+	defer content.Close()
+
+	return io.ReadAll(content)
+}
+
+// どの行からも参照されなくなった内容を削除する。削除に失敗しても投稿の操作は成功とする。
+// 数えてから削除するまでの間に同じ内容が保存されて参照されないよう、キーの排他ロックを取って行う
+func deleteUnreferencedBlobs(
+	blobLocks repository.BlobLocksRepository,
+	blobStore storage.BlobStore,
+	keys []string,
+	countReferences func(storageKey string) (int64, error),
+) {
+	deleted := make(map[string]bool)
+	for _, key := range keys {
+		if key == "" || deleted[key] {
+			continue
+		}
+		deleted[key] = true
+
+		err := blobLocks.WithExclusiveLock(key, func() error {
+			count, err := countReferences(key)
+			if err != nil {
+				return err
+			}
+			if count > 0 {
+				return nil
+			}
+			return blobStore.Delete(key)
+		})
+		if err != nil {
+			log.Printf("Error deleting blob %q: %v", key, err)
+		}
+	}
+}
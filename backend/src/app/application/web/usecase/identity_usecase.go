@@ -0,0 +1,88 @@
+package usecase
+
+import (
+	"errors"
+	"net/http"
+	"proto-pulse-plat/domain/apperror"
+	"proto-pulse-plat/domain/repository"
+	"proto-pulse-plat/helper"
+	"proto-pulse-plat/infrastructure/response"
+
+	"gorm.io/gorm"
+)
+
+type IdentityUsecase interface {
+	List(r *http.Request) (response.IdentityList, error)
+	Unlink(r *http.Request) error
+}
+
+type identityUsecase struct {
+	identityRepo repository.UserIdentitiesRepository
+}
+
+func NewIdentityUsecase(identityRepo repository.UserIdentitiesRepository) IdentityUsecase {
+	return &identityUsecase{
+		identityRepo: identityRepo,
+	}
+}
+
+type UnlinkIdentityRequest struct {
+	IdentityID uint `json:"identity_id"`
+}
+
+func (u *identityUsecase) List(r *http.Request) (response.IdentityList, error) {
+	principal, err := currentPrincipal(r)
+	if err != nil {
+		return response.IdentityList{}, err
+	}
+
+	identities, err := u.identityRepo.FindByUserID(principal.UserID)
+	if err != nil {
+		return response.IdentityList{}, err
+	}
+
+	return helper.BuildIdentityListResponse(identities), nil
+}
+
+// ログイン方法の紐付けを解除する。最後の1つは解除できない
+func (u *identityUsecase) Unlink(r *http.Request) error {
+	err := helper.ValidateMethod(r, http.MethodPost)
+	if err != nil {
+		return err
+	}
+
+	principal, err := currentPrincipal(r)
+	if err != nil {
+		return err
+	}
+
+	var req UnlinkIdentityRequest
+	if err := helper.DecodeJSONBody(r, &req); err != nil {
+		return err
+	}
+
+	identities, err := u.identityRepo.FindByUserID(principal.UserID)
+	if err != nil {
+		return err
+	}
+
+	found := false
+	for _, identity := range identities {
+		if identity.ID == req.IdentityID {
+			found = true
+			break
+		}
+	}
+	if !found {
+		return apperror.NotFound("identity %d was not found", req.IdentityID)
+	}
+
+	if err := u.identityRepo.Delete(principal.UserID, req.IdentityID); err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return apperror.Conflict("cannot unlink the last identity")
+		}
+		return err
+	}
+
+	return nil
+}
@@ -0,0 +1,181 @@
+package usecase
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"proto-pulse-plat/domain/apperror"
+	"proto-pulse-plat/domain/repository"
+	"proto-pulse-plat/domain/storage"
+	"proto-pulse-plat/helper"
+	"proto-pulse-plat/infrastructure/imaging"
+	"strconv"
+
+	"github.com/gorilla/mux"
+	"gorm.io/gorm"
+)
+
+type ImageUsecase interface {
+	GetPostImage(r *http.Request) (*Image, error)
+	GetUserIcon(r *http.Request) (*Image, error)
+}
+
+// 配信する画像。Checksum は内容の SHA-256 で、ETag に使う。Content は呼び出し側で閉じる
+type Image struct {
+	Content  io.ReadSeekCloser
+	MimeType string
+	Checksum string
+}
+
+type imageUsecase struct {
+	postRepo      repository.PostRepository
+	postImageRepo repository.PostImagesRepository
+	variantRepo   repository.PostImageVariantsRepository
+	userRepo      repository.UsersRepository
+	blobStore     storage.BlobStore
+}
+
+func NewImageUsecase(
+	postRepo repository.PostRepository,
+	postImageRepo repository.PostImagesRepository,
+	variantRepo repository.PostImageVariantsRepository,
+	userRepo repository.UsersRepository,
+	blobStore storage.BlobStore,
+) ImageUsecase {
+	return &imageUsecase{
+		postRepo:      postRepo,
+		postImageRepo: postImageRepo,
+		variantRepo:   variantRepo,
+		userRepo:      userRepo,
+		blobStore:     blobStore,
+	}
+}
+
+// variant に thumbnail, medium を指定すると縮小画像を返す。省略時は元画像
+func (u *imageUsecase) GetPostImage(r *http.Request) (*Image, error) {
+	if err := validateReadMethod(r); err != nil {
+		return nil, err
+	}
+
+	imageID, err := strconv.ParseUint(mux.Vars(r)["id"], 10, 0)
+	if err != nil || imageID == 0 {
+		return nil, apperror.NotFound("image %q was not found", mux.Vars(r)["id"])
+	}
+
+	postImage, err := u.postImageRepo.FindByID(uint(imageID))
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, apperror.NotFound("image %d was not found", imageID)
+		}
+		return nil, err
+	}
+
+	// 利用停止中のユーザーの投稿の画像は返さない
+	if _, err := u.postRepo.FindByID(int(postImage.PostID)); err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, apperror.NotFound("image %d was not found", imageID)
+		}
+		return nil, err
+	}
+
+	name := r.URL.Query().Get("variant")
+	if name == "" || name == imaging.VariantOriginal {
+		return u.load(r, postImage.StorageKey, postImage.MimeType, postImage.Checksum)
+	}
+
+	variant, err := u.variantRepo.Find(postImage.ID, name)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, apperror.NotFound("%s variant of image %d was not found", name, imageID)
+		}
+		return nil, err
+	}
+
+	return u.load(r, variant.StorageKey, variant.MimeType, variant.Checksum)
+}
+
+func (u *imageUsecase) GetUserIcon(r *http.Request) (*Image, error) {
+	if err := validateReadMethod(r); err != nil {
+		return nil, err
+	}
+
+	userID, err := strconv.ParseUint(mux.Vars(r)["id"], 10, 0)
+	if err != nil || userID == 0 {
+		return nil, apperror.NotFound("user %q was not found", mux.Vars(r)["id"])
+	}
+
+	// 投稿の画像と同じく、利用停止中のユーザーのアイコンは返さない
+	user, err := u.userRepo.FindNotSuspended(uint(userID))
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, apperror.NotFound("user %d was not found", userID)
+		}
+		return nil, err
+	}
+
+	if user.IconStorageKey == "" {
+		return nil, apperror.NotFound("user %d has no icon", userID)
+	}
+
+	return u.load(r, user.IconStorageKey, user.IconMimeType, user.IconHash)
+}
+
+// 保存済みのハッシュが If-None-Match と一致する場合は BlobStore から取得せず、Content を nil にして返す
+func (u *imageUsecase) load(r *http.Request, storageKey, mimeType, checksum string) (*Image, error) {
+	if storageKey == "" {
+		return nil, apperror.NotFound("image was not found")
+	}
+
+	if checksum != "" && helper.ImageNotModified(r, checksum) {
+		return &Image{MimeType: mimeType, Checksum: checksum}, nil
+	}
+
+	content, err := u.blobStore.Get(storageKey)
+	if err != nil {
+		if errors.Is(err, storage.ErrBlobNotFound) {
+			return nil, apperror.NotFound("image was not found")
+		}
+		return nil, err
+	}
+
+	// 古い行には MIME タイプやハッシュが無い場合があり、その場合だけ内容を読み込む
+	if mimeType == "" || checksum == "" {
+		mimeType, checksum, err = describeContent(content, mimeType, checksum)
+		if err != nil {
+			content.Close()
+			return nil, err
+		}
+	}
+
+	return &Image{
+		Content:  content,
+		MimeType: mimeType,
+		Checksum: checksum,
+	}, nil
+}
+
+func describeContent(content io.ReadSeeker, mimeType, checksum string) (string, string, error) {
+	data, err := io.ReadAll(content)
+	if err != nil {
+		return "", "", err
+	}
+	if _, err := content.Seek(0, io.SeekStart); err != nil {
+		return "", "", err
+	}
+
+	if mimeType == "" {
+		mimeType = http.DetectContentType(data)
+	}
+	if checksum == "" {
+		checksum = helper.ContentHash(data)
+	}
+	return mimeType, checksum, nil
+}
+
+// 画像は GET と HEAD で取得できる
+func validateReadMethod(r *http.Request) error {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		return apperror.MethodNotAllowed(r.Method)
+	}
+	return nil
+}
@@ -0,0 +1,178 @@
+package usecase
+
+import (
+	"fmt"
+	"proto-pulse-plat/config"
+	"proto-pulse-plat/domain/entity"
+	"proto-pulse-plat/domain/repository"
+	"proto-pulse-plat/domain/storage"
+	"proto-pulse-plat/infrastructure/blobstore"
+	"proto-pulse-plat/infrastructure/imaging"
+	"proto-pulse-plat/infrastructure/mapper"
+)
+
+// 投稿画像の縮小画像 (一覧用のサムネイルと詳細用の中サイズ) とプレースホルダーを生成して保存する
+type ImageVariantUsecase interface {
+	Generate(data []byte) (*DerivedImages, error)
+	// 保存済みの画像から現在の設定で作り直す。cmd/regenerateimages で使う
+	Regenerate(postImage entity.PostImage) error
+}
+
+// 画像の内容から生成する縮小画像と、読み込み中に表示するプレースホルダー
+type DerivedImages struct {
+	Variants    []imaging.Variant
+	Placeholder imaging.Placeholder
+}
+
+type imageVariantUsecase struct {
+	postImageRepo repository.PostImagesRepository
+	variantRepo   repository.PostImageVariantsRepository
+	blobLocks     repository.BlobLocksRepository
+	blobStore     storage.BlobStore
+	options       imaging.VariantOptions
+}
+
+func NewImageVariantUsecase(
+	postImageRepo repository.PostImagesRepository,
+	variantRepo repository.PostImageVariantsRepository,
+	blobLocks repository.BlobLocksRepository,
+	blobStore storage.BlobStore,
+	variantConfig *config.ImageVariantConfig,
+) ImageVariantUsecase {
+	return &imageVariantUsecase{
+		postImageRepo: postImageRepo,
+		variantRepo:   variantRepo,
+		blobLocks:     blobLocks,
+		blobStore:     blobStore,
+		options: imaging.VariantOptions{
+			Specs: []imaging.VariantSpec{
+				{Name: imaging.VariantThumbnail, MaxSize: variantConfig.ThumbnailSize},
+				{Name: imaging.VariantMedium, MaxSize: variantConfig.MediumSize},
+			},
+			JPEGQuality: variantConfig.JPEGQuality,
+		},
+	}
+}
+
+// 画素データの展開は1回で済ませる
+func (u *imageVariantUsecase) Generate(data []byte) (*DerivedImages, error) {
+	img, err := imaging.Decode(data)
+	if err != nil {
+		return nil, err
+	}
+
+	variants, err := imaging.GenerateVariants(img, u.options)
+	if err != nil {
+		return nil, err
+	}
+
+	return &DerivedImages{
+		Variants:    variants,
+		Placeholder: imaging.ComputePlaceholder(img),
+	}, nil
+}
+
+// 同じ名前の派生画像は置き換え、生成しなかった名前の派生画像は削除する
+func (u *imageVariantUsecase) save(postImageID uint, variants []imaging.Variant) error {
+	existing, err := u.variantRepo.FindByPostImageIDs([]uint{postImageID})
+	if err != nil {
+		return err
+	}
+
+	err = withStoredBlobs(u.blobLocks, u.blobStore, variantContents(variants), func() error {
+		return saveVariantRows(u.variantRepo, postImageID, variants)
+	})
+	if err != nil {
+		return err
+	}
+
+	// 品質や大きさの設定を変えて作り直した場合、以前の内容は参照されなくなる
+	deleteUnreferencedBlobs(u.blobLocks, u.blobStore, variantStorageKeys(existing), u.variantRepo.CountByStorageKey)
+
+	return nil
+}
+
+func (u *imageVariantUsecase) Regenerate(postImage entity.PostImage) error {
+	if postImage.StorageKey == "" {
+		return fmt.Errorf("postImage %d has not been copied to the blob store", postImage.ID)
+	}
+
+	data, err := readBlob(u.blobStore, postImage.StorageKey)
+	if err != nil {
+		return fmt.Errorf("failed to load postImage %d: %w", postImage.ID, err)
+	}
+
+	info, err := imaging.Inspect("", data)
+	if err != nil {
+		return fmt.Errorf("postImage %d is not a supported image: %w", postImage.ID, err)
+	}
+	derived, err := u.Generate(data)
+	if err != nil {
+		return fmt.Errorf("failed to generate variants of postImage %d: %w", postImage.ID, err)
+	}
+
+	placeholder := derived.Placeholder
+	if info.Width != postImage.Width || info.Height != postImage.Height ||
+		placeholder.BlurHash != postImage.BlurHash || placeholder.DominantColor != postImage.DominantColor {
+		err := u.postImageRepo.UpdateDerivedAttributes(
+			postImage.ID, info.Width, info.Height, placeholder.BlurHash, placeholder.DominantColor,
+		)
+		if err != nil {
+			return err
+		}
+	}
+
+	return u.save(postImage.ID, derived.Variants)
+}
+
+func variantContent(variant imaging.Variant) blobContent {
+	return blobContent{
+		namespace: blobstore.PostImageVariantNamespace,
+		data:      variant.Data,
+		mimeType:  variant.MimeType,
+	}
+}
+
+func variantContents(variants []imaging.Variant) []blobContent {
+	contents := make([]blobContent, 0, len(variants))
+	for _, variant := range variants {
+		contents = append(contents, variantContent(variant))
+	}
+	return contents
+}
+
+// 内容は保存済みとして派生画像の行を保存する。生成しなかった名前の行は削除する
+func saveVariantRows(variantRepo repository.PostImageVariantsRepository, postImageID uint, variants []imaging.Variant) error {
+	names := make([]string, 0, len(variants))
+	for _, variant := range variants {
+		content := variantContent(variant)
+		err := variantRepo.Save(
+			mapper.ToModelPostImageVariant(
+				postImageID, variant.Name, content.key(), variant.MimeType,
+				variant.Width, variant.Height, int64(len(variant.Data)), content.checksum(),
+			),
+		)
+		if err != nil {
+			return err
+		}
+		names = append(names, variant.Name)
+	}
+
+	return variantRepo.DeleteExcept(postImageID, names)
+}
+
+func variantStorageKeys(variants []entity.PostImageVariant) []string {
+	keys := make([]string, 0, len(variants))
+	for _, variant := range variants {
+		keys = append(keys, variant.StorageKey)
+	}
+	return keys
+}
+
+func groupVariantsByPostImage(variants []entity.PostImageVariant) map[uint][]entity.PostImageVariant {
+	grouped := make(map[uint][]entity.PostImageVariant)
+	for _, variant := range variants {
+		grouped[variant.PostImageID] = append(grouped[variant.PostImageID], variant)
+	}
+	return grouped
+}
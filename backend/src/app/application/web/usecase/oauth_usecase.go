@@ -1,193 +1,438 @@
 package usecase
 
 import (
-	"encoding/json"
 	"errors"
 	"fmt"
 	"io"
 	"log"
+	"mime"
 	"net/http"
 	"net/url"
+	"proto-pulse-plat/app/presentation/http/web/validation"
+	"proto-pulse-plat/auth"
 	"proto-pulse-plat/config"
+	"proto-pulse-plat/domain/apperror"
 	"proto-pulse-plat/domain/entity"
+	"proto-pulse-plat/domain/identity"
 	"proto-pulse-plat/domain/repository"
+	"proto-pulse-plat/domain/storage"
 	"proto-pulse-plat/helper"
+	"proto-pulse-plat/infrastructure/blobstore"
+	"proto-pulse-plat/infrastructure/identityprovider"
+	"proto-pulse-plat/infrastructure/imaging"
 	"proto-pulse-plat/infrastructure/mapper"
-	"proto-pulse-plat/infrastructure/response"
+	"proto-pulse-plat/infrastructure/model"
 	"strings"
 	"time"
 
-	"github.com/dghubble/oauth1"
 	"gorm.io/gorm"
 )
 
 type OAuthUsecase interface {
-	MakeOAuthRequest() (*http.Request, error)
-	MakeOAuthClient(params url.Values) (*http.Client, error)
-	GetOAuthResponse(r *http.Request) (string, error)
+	MakeOAuthRequest(r *http.Request, provider string) (*OAuthRequest, error)
+	MakeLinkRequest(r *http.Request, provider string) (*OAuthRequest, error)
+	GetOAuthResponse(r *http.Request, request *OAuthRequest) (*auth.TokenPair, error)
+	LinkIdentity(r *http.Request, request *OAuthRequest) error
+}
+
+// 認可を開始したプロバイダーと、コールバックの検証に必要な値。認可を開始したブラウザの Cookie に保持する
+type OAuthRequest struct {
+	AuthorizationURL string             `json:"-"`
+	Provider         string             `json:"provider"`
+	State            identity.FlowState `json:"state"`
+	// ログイン中のユーザーにログイン方法を追加する場合のみ設定する
+	LinkUserID    uint   `json:"link_user_id,omitempty"`
+	LinkSessionID string `json:"link_session_id,omitempty"`
+}
+
+func (req *OAuthRequest) IsLink() bool {
+	return req.LinkUserID != 0
 }
 
 type oauthUsecase struct {
-	xConfig  *config.Xconfig
-	userRepo repository.UsersRepository
+	providers      *identity.Registry
+	userRepo       repository.UsersRepository
+	identityRepo   repository.UserIdentitiesRepository
+	suspensionRepo repository.UserSuspensionsRepository
+	sessionRepo    repository.SessionsRepository
+	tokenIssuer    TokenIssuer
+	blobStore      storage.BlobStore
 }
 
-func NewOAuthUseCase(xConfig *config.Xconfig, userRepo repository.UsersRepository) OAuthUsecase {
+func NewOAuthUseCase(
+	providers *identity.Registry,
+	userRepo repository.UsersRepository,
+	identityRepo repository.UserIdentitiesRepository,
+	suspensionRepo repository.UserSuspensionsRepository,
+	sessionRepo repository.SessionsRepository,
+	tokenIssuer TokenIssuer,
+	blobStore storage.BlobStore,
+) OAuthUsecase {
 	return &oauthUsecase{
-		xConfig:  xConfig,
-		userRepo: userRepo,
+		providers:      providers,
+		userRepo:       userRepo,
+		identityRepo:   identityRepo,
+		suspensionRepo: suspensionRepo,
+		sessionRepo:    sessionRepo,
+		tokenIssuer:    tokenIssuer,
+		blobStore:      blobStore,
 	}
 }
 
-func (ou *oauthUsecase) MakeOAuthRequest() (*http.Request, error) {
-	oauthTokenRequestURL, err := ou.makeOAuthTokenRequestURL()
+func (ou *oauthUsecase) MakeOAuthRequest(r *http.Request, providerName string) (*OAuthRequest, error) {
+	return ou.begin(r, providerName)
+}
+
+// ログイン中のユーザーに別のログイン方法を紐付けるための認可を開始する
+func (ou *oauthUsecase) MakeLinkRequest(r *http.Request, providerName string) (*OAuthRequest, error) {
+	principal, err := currentPrincipal(r)
 	if err != nil {
-		log.Fatal("Error oauthTokenRequestURL:", err)
+		return nil, err
 	}
 
-	req, err := http.NewRequest(http.MethodPost, oauthTokenRequestURL, nil)
+	request, err := ou.begin(r, providerName)
 	if err != nil {
-		log.Fatal("Error creating request:", err)
+		return nil, err
 	}
+	request.LinkUserID = principal.UserID
+	request.LinkSessionID = principal.SessionID
 
-	client := &http.Client{}
-	resp, err := client.Do(req)
+	return request, nil
+}
+
+func (ou *oauthUsecase) begin(r *http.Request, providerName string) (*OAuthRequest, error) {
+	provider, ok := ou.providers.Lookup(providerName)
+	if !ok {
+		return nil, apperror.NotFound("identity provider %q was not found", providerName)
+	}
+
+	authorizationURL, state, err := provider.Begin(r.Context())
 	if err != nil {
-		log.Fatal("Error sending request:", err)
+		return nil, err
 	}
-	defer resp.Body.Close()
 
-	body, err := io.ReadAll(resp.Body)
+	return &OAuthRequest{
+		AuthorizationURL: authorizationURL,
+		Provider:         provider.Name(),
+		State:            state,
+	}, nil
+}
+
+func (ou *oauthUsecase) GetOAuthResponse(r *http.Request, request *OAuthRequest) (*auth.TokenPair, error) {
+	profile, err := ou.complete(r, request)
 	if err != nil {
-		log.Fatal("Error reading response:", err)
+		return nil, err
 	}
 
-	values, err := url.ParseQuery(string(body))
+	// 画像をダウンロード。失敗してもログインは継続し、アイコンの更新のみ行わない
+	var imageData []byte
+	if profile.AvatarURL != "" {
+		imageData, err = downloadImage(profile.AvatarURL)
+		if err != nil {
+			log.Println("Error downloading profile image:", err)
+		}
+	}
+
+	registerdUser, err := ou.findRegisteredUser(profile)
 	if err != nil {
-		log.Fatal("Error parsing response:", err)
+		return nil, fmt.Errorf("failed to find user: %w", err)
 	}
 
-	return http.NewRequest(
-		http.MethodGet,
-		fmt.Sprintf("%s?oauth_token=%s", ou.xConfig.AuthorizeURL, values.Get("oauth_token")),
-		nil,
-	)
+	if registerdUser != nil {
+		if err := ou.checkNotSuspended(registerdUser.ID); err != nil {
+			return nil, err
+		}
+	}
+
+	var user *entity.User
+	if registerdUser == nil {
+		var iconStorageKey, iconMimeType, iconHash string
+		if len(imageData) > 0 {
+			iconStorageKey, iconMimeType, iconHash, err = ou.storeIcon(imageData)
+			if err != nil {
+				log.Println("Error storing profile image:", err)
+			}
+		}
+
+		user, err = ou.userRepo.Save(
+			mapper.ToModelUser(
+				profile.DisplayName,
+				profile.Handle,
+				helper.IconFileName(profile.AvatarURL),
+				iconStorageKey,
+				iconMimeType,
+				iconHash,
+			),
+			mapper.ToModelUserIdentity(0, profile.Provider, profile.ExternalID, profile.Handle),
+		)
+		if err != nil {
+			return nil, errors.New("failed to save user")
+		}
+	} else {
+		user, err = ou.syncProfile(*registerdUser, profile, imageData)
+		if err != nil {
+			return nil, errors.New("failed to update user")
+		}
+	}
+
+	// ログインごとに新しいセッションとトークンを発行する
+	tokenPair, err := ou.tokenIssuer.Issue(*user, r.UserAgent())
+	if err != nil {
+		return nil, err
+	}
+
+	return tokenPair, nil
 }
 
-func (ou *oauthUsecase) makeOAuthTokenRequestURL() (string, error) {
-	nonce, err := helper.GenerateNonce(16)
+// コールバックで得たログイン方法を、認可を開始したユーザーに紐付ける
+func (ou *oauthUsecase) LinkIdentity(r *http.Request, request *OAuthRequest) error {
+	if request == nil || !request.IsLink() {
+		return errors.New("no pending link request")
+	}
+
+	// 認可の開始後にログアウトしたセッションからの紐付けは受け付けない
+	session, err := ou.sessionRepo.FindActiveByID(request.LinkSessionID)
 	if err != nil {
-		fmt.Println("Error generating nonce:", err)
-		return "", nil
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return apperror.Unauthorized("authentication is required")
+		}
+		return err
+	}
+	if session.UserID != request.LinkUserID {
+		return apperror.Unauthorized("authentication is required")
 	}
 
-	params := url.Values{}
-	params.Set("oauth_consumer_key", ou.xConfig.ConsumerKey)
-	params.Set("oauth_signature_method", "HMAC-SHA1")
-	params.Set("oauth_timestamp", fmt.Sprintf("%d", time.Now().Unix()))
-	params.Set("oauth_nonce", nonce)
-	params.Set("oauth_version", "1.0")
-	params.Set("oauth_callback", ou.xConfig.CallBackURL)
-	signatureBaseString := helper.CreateSignatureBaseString(http.MethodPost, ou.xConfig.RequestTokenURL, params)
-	oauthSignature, err := helper.GenerateHMACSHA1Signature(signatureBaseString, ou.xConfig.ConsumerSecret+"&")
+	profile, err := ou.complete(r, request)
 	if err != nil {
-		fmt.Println("Error generating signature:", err)
-		return "", nil
+		return err
 	}
-	params.Set("oauth_signature", oauthSignature)
 
-	return ou.xConfig.RequestTokenURL + "?" + params.Encode(), nil
-}
+	linked, err := ou.identityRepo.FindByExternalID(profile.Provider, profile.ExternalID)
+	if err == nil {
+		if linked.UserID != request.LinkUserID {
+			return apperror.Conflict("%s identity is linked to another user", profile.Provider)
+		}
+		return ou.syncIdentityHandle(*linked, profile)
+	}
+	if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return err
+	}
 
-func (ou *oauthUsecase) MakeOAuthClient(params url.Values) (*http.Client, error) {
-	resp, err := http.PostForm(ou.xConfig.AccessTokenURL, params)
+	_, err = ou.identityRepo.Save(
+		mapper.ToModelUserIdentity(request.LinkUserID, profile.Provider, profile.ExternalID, profile.Handle),
+	)
 	if err != nil {
-		fmt.Println("Error getting access token:", err)
-		return nil, err
+		return err
 	}
-	defer resp.Body.Close()
 
-	body, err := io.ReadAll(resp.Body)
+	return nil
+}
+
+func (ou *oauthUsecase) complete(r *http.Request, request *OAuthRequest) (*identity.Profile, error) {
+	if request == nil {
+		return nil, errors.New("no pending oauth request")
+	}
+
+	provider, ok := ou.providers.Lookup(request.Provider)
+	if !ok {
+		return nil, apperror.NotFound("identity provider %q was not found", request.Provider)
+	}
+
+	profile, err := provider.Complete(r.Context(), r.URL.Query(), request.State)
 	if err != nil {
-		fmt.Println("Error reading response:", err)
 		return nil, err
 	}
 
-	oauthTokenValues, err := url.ParseQuery(string(body))
-	if err != nil {
-		fmt.Println("Error parsing access token response:", err)
+	if profile.ExternalID == "" {
+		return nil, fmt.Errorf("%s profile has no external id", provider.Name())
+	}
+
+	return profile, nil
+}
+
+// 紐付け済みのログイン方法から登録済みユーザーを探す。
+// スクリーンネームは他人が取得し直せるため照合に使わない。外部ID導入前のユーザーは cmd/backfillxids で紐付ける
+func (ou *oauthUsecase) findRegisteredUser(profile *identity.Profile) (*entity.User, error) {
+	linked, err := ou.identityRepo.FindByExternalID(profile.Provider, profile.ExternalID)
+	if err == nil {
+		if err := ou.syncIdentityHandle(*linked, profile); err != nil {
+			return nil, err
+		}
+		return ou.userRepo.Find(linked.UserID)
+	}
+	if !errors.Is(err, gorm.ErrRecordNotFound) {
 		return nil, err
 	}
 
-	config := oauth1.NewConfig(ou.xConfig.ConsumerKey, ou.xConfig.ConsumerSecret)
-	token := oauth1.NewToken(oauthTokenValues.Get("oauth_token"), oauthTokenValues.Get("oauth_token_secret"))
-	oauthClient := config.Client(oauth1.NoContext, token)
+	if err := ou.checkNoUnlinkedLegacyUser(profile); err != nil {
+		return nil, err
+	}
 
-	return oauthClient, nil
+	return nil, nil
 }
 
-func (ou *oauthUsecase) GetOAuthResponse(r *http.Request) (string, error) {
-	params := url.Values{}
-	params.Add("oauth_token", r.URL.Query().Get("oauth_token"))
-	params.Add("oauth_verifier", r.URL.Query().Get("oauth_verifier"))
+// cmd/backfillxids で紐付けられなかった既存ユーザーと同じスクリーンネームの場合は、新しいアカウントを作らずに拒否する。
+// 本人であれば投稿が別のアカウントに分かれてしまい、別人であれば既存のアカウントを引き継げないため、管理者が確認して紐付ける
+func (ou *oauthUsecase) checkNoUnlinkedLegacyUser(profile *identity.Profile) error {
+	if profile.Provider != identityprovider.XProviderName || profile.Handle == "" {
+		return nil
+	}
 
-	oauthClient, err := ou.MakeOAuthClient(params)
+	exists, err := ou.userRepo.ExistsUnlinkedByAccountID(profile.Handle)
 	if err != nil {
-		return "", errors.New(err.Error())
+		return err
+	}
+	if exists {
+		return apperror.Conflict("an account registered as @%s has not been migrated yet; ask an administrator to link it", profile.Handle)
 	}
+	return nil
+}
 
-	resp, err := oauthClient.Get(ou.xConfig.VerifyCredentials)
-	if err != nil {
-		return "", errors.New(err.Error())
+// 利用停止中のユーザーにはセッションを発行しない
+func (ou *oauthUsecase) checkNotSuspended(userID uint) error {
+	_, err := ou.suspensionRepo.FindActiveByUserID(userID)
+	if err == nil {
+		return apperror.AccountSuspended()
 	}
-	defer resp.Body.Close()
+	if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return err
+	}
+	return nil
+}
 
-	body, err := io.ReadAll(resp.Body)
-	if err != nil {
-		return "", errors.New(err.Error())
+func (ou *oauthUsecase) syncIdentityHandle(linked entity.UserIdentity, profile *identity.Profile) error {
+	if profile.Handle == "" || profile.Handle == linked.Handle {
+		return nil
 	}
+	return ou.identityRepo.UpdateHandle(linked.ID, profile.Handle)
+}
 
-	var profile response.UserProfile
-	if err := json.Unmarshal(body, &profile); err != nil {
-		return "", errors.New(err.Error())
+// 表示名・ハンドル・アイコンが変わっていれば保存する。アイコンは内容のハッシュで比較する
+func (ou *oauthUsecase) syncProfile(
+	user entity.User,
+	profile *identity.Profile,
+	imageData []byte,
+) (*entity.User, error) {
+	changes := model.User{ID: user.ID}
+	changed := false
+
+	if profile.DisplayName != "" && profile.DisplayName != user.UserName {
+		changes.UserName = profile.DisplayName
+		user.UserName = profile.DisplayName
+		changed = true
 	}
 
-	// 画像をダウンロード
-	resp, err = http.Get(strings.Replace(profile.ProfileImageUrl, "_normal", "", 1))
-	if err != nil {
-		log.Fatal(err)
+	if profile.Handle != "" && profile.Handle != user.AccountID {
+		changes.AccountID = profile.Handle
+		user.AccountID = profile.Handle
+		changed = true
+	}
+
+	if len(imageData) > 0 {
+		if helper.ContentHash(imageData) != user.IconHash {
+			iconStorageKey, iconMimeType, iconHash, err := ou.storeIcon(imageData)
+			if err != nil {
+				// アイコンを保存できなくてもログインは継続する
+				log.Println("Error storing profile image:", err)
+			} else {
+				changes.IconFileName = helper.IconFileName(profile.AvatarURL)
+				changes.IconStorageKey = iconStorageKey
+				changes.IconMimeType = iconMimeType
+				changes.IconHash = iconHash
+				user.IconFileName = changes.IconFileName
+				user.IconStorageKey = iconStorageKey
+				user.IconMimeType = iconMimeType
+				user.IconHash = iconHash
+				changed = true
+			}
+		}
+	}
+
+	if !changed {
+		return &user, nil
 	}
-	defer resp.Body.Close()
 
-	// 画像データを読み込む
-	imageData, err := io.ReadAll(resp.Body)
+	if err := ou.userRepo.Update(changes); err != nil {
+		return nil, err
+	}
+
+	return &user, nil
+}
+
+// 画像であることを確認して BlobStore に保存し、キー・MIME タイプ・内容のハッシュを返す
+func (ou *oauthUsecase) storeIcon(imageData []byte) (string, string, string, error) {
+	info, err := imaging.Inspect("", imageData)
 	if err != nil {
-		log.Fatal(err)
+		return "", "", "", err
 	}
 
-	registerdUser, err := ou.userRepo.FindByUserName(profile.ScreenName)
+	storageKey, iconHash, err := putContent(ou.blobStore, blobstore.UserIconNamespace, imageData, info.MimeType)
 	if err != nil {
-		if errors.Is(err, gorm.ErrRecordNotFound) {
-			registerdUser = nil
-		}else {
-			return "", errors.New("failed to save user")
-		}
+		return "", "", "", err
 	}
+	return storageKey, info.MimeType, iconHash, nil
+}
 
-	var user *entity.User
-	if registerdUser == nil {
-		user, err = ou.userRepo.Save(mapper.ToModelUser(profile.ScreenName, profile.Name, "test.jpg", imageData))
-		if err != nil {
-			return "", errors.New("failed to save user")
+const (
+	avatarDownloadTimeout = 10 * time.Second
+	// 投稿画像のアップロードと同じ上限にする
+	maxAvatarSize = validation.MaxPostImageSize
+)
+
+// プロフィール画像の取得に使う。応答しないサーバーでログインが止まらないよう時間を制限し、リダイレクト先も検証する
+var avatarClient = &http.Client{
+	Timeout: avatarDownloadTimeout,
+	CheckRedirect: func(req *http.Request, via []*http.Request) error {
+		if len(via) >= 5 {
+			return errors.New("too many redirects")
 		}
-	}else {
-		user = registerdUser
+		return checkAvatarURL(req.URL)
+	},
+}
+
+// プロバイダーが返した URL から画像を取得する。https 以外、画像以外、上限を超える大きさの場合はエラーにする
+func downloadImage(imageURL string) ([]byte, error) {
+	parsed, err := url.Parse(imageURL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid image URL %q: %w", imageURL, err)
+	}
+	if err := checkAvatarURL(parsed); err != nil {
+		return nil, err
 	}
 
-	tokenString, err := helper.GenerateJWT(profile, *user)
+	resp, err := avatarClient.Get(parsed.String())
 	if err != nil {
-		return "", errors.New(err.Error())
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected status %d downloading %s", resp.StatusCode, imageURL)
+	}
+
+	mediaType, _, err := mime.ParseMediaType(resp.Header.Get("Content-Type"))
+	if err != nil || !strings.HasPrefix(mediaType, "image/") {
+		return nil, fmt.Errorf("unexpected content type %q downloading %s", resp.Header.Get("Content-Type"), imageURL)
+	}
+	if resp.ContentLength > maxAvatarSize {
+		return nil, fmt.Errorf("image %s is larger than %d bytes", imageURL, maxAvatarSize)
 	}
 
-	return tokenString, nil
+	// Content-Length が無い場合や偽っている場合に備え、上限を1バイト超えるまでしか読まない
+	data, err := io.ReadAll(io.LimitReader(resp.Body, maxAvatarSize+1))
+	if err != nil {
+		return nil, err
+	}
+	if len(data) > maxAvatarSize {
+		return nil, fmt.Errorf("image %s is larger than %d bytes", imageURL, maxAvatarSize)
+	}
+	return data, nil
+}
+
+// 偽 X は開発環境でのみ使い、http で画像を返すため許可する
+func checkAvatarURL(imageURL *url.URL) error {
+	if imageURL.Scheme == "https" || (imageURL.Scheme == "http" && config.FakeXEnabled()) {
+		return nil
+	}
+	return fmt.Errorf("image URL %q must use https", imageURL.String())
 }
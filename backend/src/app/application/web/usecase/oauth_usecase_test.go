@@ -0,0 +1,83 @@
+package usecase
+
+import (
+	"errors"
+	"proto-pulse-plat/domain/apperror"
+	"proto-pulse-plat/domain/entity"
+	"proto-pulse-plat/domain/identity"
+	"proto-pulse-plat/domain/repository"
+	"proto-pulse-plat/infrastructure/identityprovider"
+	"testing"
+
+	"gorm.io/gorm"
+)
+
+type fakeUserIdentitiesRepository struct {
+	repository.UserIdentitiesRepository
+	identities []entity.UserIdentity
+}
+
+func (f *fakeUserIdentitiesRepository) FindByExternalID(provider, externalID string) (*entity.UserIdentity, error) {
+	for _, identity := range f.identities {
+		if identity.Provider == provider && identity.ExternalID == externalID {
+			return &identity, nil
+		}
+	}
+	return nil, gorm.ErrRecordNotFound
+}
+
+func (f *fakeUserIdentitiesRepository) UpdateHandle(id uint, handle string) error {
+	return nil
+}
+
+func TestFindRegisteredUser(t *testing.T) {
+	alice := entity.User{ID: 1, UserName: "Alice", AccountID: "alice"}
+	ou := &oauthUsecase{
+		userRepo: &fakeUsersRepository{
+			users: map[uint]*entity.User{alice.ID: &alice},
+			// 外部ID導入前に登録され、cmd/backfillxids で紐付けられなかったユーザー
+			unlinkedAccountIDs: []string{"legacy_user"},
+		},
+		identityRepo: &fakeUserIdentitiesRepository{identities: []entity.UserIdentity{
+			{ID: 1, UserID: alice.ID, Provider: identityprovider.XProviderName, ExternalID: "1000000001", Handle: "alice"},
+		}},
+	}
+
+	tests := []struct {
+		name         string
+		profile      identity.Profile
+		wantUserID   uint
+		wantConflict bool
+	}{
+		{name: "linked identity", profile: identity.Profile{Provider: "x", ExternalID: "1000000001", Handle: "alice"}, wantUserID: alice.ID},
+		// スクリーンネームを変更していても外部IDで見つける
+		{name: "linked identity with new handle", profile: identity.Profile{Provider: "x", ExternalID: "1000000001", Handle: "alice_renamed"}, wantUserID: alice.ID},
+		{name: "new user", profile: identity.Profile{Provider: "x", ExternalID: "1000000002", Handle: "bob"}},
+		{name: "unlinked legacy user", profile: identity.Profile{Provider: "x", ExternalID: "1000000003", Handle: "legacy_user"}, wantConflict: true},
+		{name: "unlinked legacy user in other case", profile: identity.Profile{Provider: "x", ExternalID: "1000000003", Handle: "Legacy_User"}, wantConflict: true},
+		// 既存ユーザーは X で登録されているため、他のプロバイダーのハンドルとは照合しない
+		{name: "other provider with legacy handle", profile: identity.Profile{Provider: "github", ExternalID: "42", Handle: "legacy_user"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			user, err := ou.findRegisteredUser(&tt.profile)
+			if tt.wantConflict {
+				if !errors.Is(err, apperror.ErrConflict) {
+					t.Fatalf("error = %v, want conflict", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("findRegisteredUser: %v", err)
+			}
+
+			var userID uint
+			if user != nil {
+				userID = user.ID
+			}
+			if userID != tt.wantUserID {
+				t.Errorf("user id = %d, want %d", userID, tt.wantUserID)
+			}
+		})
+	}
+}
@@ -0,0 +1,122 @@
+package usecase
+
+import (
+	"errors"
+	"net/http"
+	"proto-pulse-plat/app/presentation/http/web/validation"
+	"proto-pulse-plat/auth"
+	"proto-pulse-plat/domain/apperror"
+	"proto-pulse-plat/domain/repository"
+	"proto-pulse-plat/helper"
+	"proto-pulse-plat/infrastructure/mapper"
+	"proto-pulse-plat/infrastructure/response"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type PersonalAccessTokenUsecase interface {
+	List(r *http.Request) (response.PersonalAccessTokenList, error)
+	Create(r *http.Request) (*response.CreatedPersonalAccessToken, error)
+	Revoke(r *http.Request) error
+}
+
+type personalAccessTokenUsecase struct {
+	tokenRepo repository.PersonalAccessTokensRepository
+}
+
+func NewPersonalAccessTokenUsecase(tokenRepo repository.PersonalAccessTokensRepository) PersonalAccessTokenUsecase {
+	return &personalAccessTokenUsecase{
+		tokenRepo: tokenRepo,
+	}
+}
+
+type RevokePersonalAccessTokenRequest struct {
+	TokenID uint `json:"token_id"`
+}
+
+func (u *personalAccessTokenUsecase) List(r *http.Request) (response.PersonalAccessTokenList, error) {
+	principal, err := currentPrincipal(r)
+	if err != nil {
+		return response.PersonalAccessTokenList{}, err
+	}
+
+	tokens, err := u.tokenRepo.FindActiveByUserID(principal.UserID)
+	if err != nil {
+		return response.PersonalAccessTokenList{}, err
+	}
+
+	return helper.BuildPersonalAccessTokenListResponse(tokens), nil
+}
+
+// トークンを発行する。平文はこのレスポンスでのみ返し、DB にはハッシュを保存する
+func (u *personalAccessTokenUsecase) Create(r *http.Request) (*response.CreatedPersonalAccessToken, error) {
+	err := helper.ValidateMethod(r, http.MethodPost)
+	if err != nil {
+		return nil, err
+	}
+
+	principal, err := currentPrincipal(r)
+	if err != nil {
+		return nil, err
+	}
+
+	input, err := validation.ValidatePersonalAccessTokenInputs(r)
+	if err != nil {
+		return nil, err
+	}
+
+	token, tokenPrefix, tokenHash, err := auth.NewPersonalAccessToken()
+	if err != nil {
+		return nil, err
+	}
+
+	var expiresAt *time.Time
+	if input.ExpiresInDays > 0 {
+		t := time.Now().AddDate(0, 0, input.ExpiresInDays)
+		expiresAt = &t
+	}
+
+	saved, err := u.tokenRepo.Save(mapper.ToModelPersonalAccessToken(
+		principal.UserID,
+		input.Name,
+		tokenPrefix,
+		tokenHash,
+		input.Scopes,
+		expiresAt,
+	))
+	if err != nil {
+		return nil, err
+	}
+
+	return &response.CreatedPersonalAccessToken{
+		PersonalAccessToken: helper.BuildPersonalAccessTokenResponse(*saved),
+		Token:               token,
+	}, nil
+}
+
+func (u *personalAccessTokenUsecase) Revoke(r *http.Request) error {
+	err := helper.ValidateMethod(r, http.MethodPost)
+	if err != nil {
+		return err
+	}
+
+	principal, err := currentPrincipal(r)
+	if err != nil {
+		return err
+	}
+
+	var req RevokePersonalAccessTokenRequest
+	if err := helper.DecodeJSONBody(r, &req); err != nil {
+		return err
+	}
+
+	if err := u.tokenRepo.Revoke(principal.UserID, req.TokenID); err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return apperror.NotFound("personal access token %d was not found", req.TokenID)
+		}
+		return err
+	}
+
+	return nil
+}
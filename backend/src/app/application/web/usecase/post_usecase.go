@@ -1,29 +1,34 @@
 package usecase
 
 import (
-	"encoding/json"
 	"errors"
 	"fmt"
 	"io"
 	"log"
+	"mime/multipart"
 	"net/http"
-	"os"
+	"proto-pulse-plat/app/application/web/authorization"
 	"proto-pulse-plat/app/presentation/http/web/validation"
+	"proto-pulse-plat/auth"
+	"proto-pulse-plat/domain/apperror"
 	"proto-pulse-plat/domain/entity"
 	"proto-pulse-plat/domain/repository"
+	"proto-pulse-plat/domain/storage"
 	"proto-pulse-plat/helper"
+	"proto-pulse-plat/infrastructure/blobstore"
+	"proto-pulse-plat/infrastructure/imaging"
 	"proto-pulse-plat/infrastructure/mapper"
-	"proto-pulse-plat/infrastructure/model"
 	"proto-pulse-plat/infrastructure/response"
 	"strconv"
 
-	"github.com/golang-jwt/jwt/v4"
+	"gorm.io/gorm"
 )
 
 type PostUsecase interface {
 	List(r *http.Request) (response.PostList, error)
 	Delete(r *http.Request) error
 	Add(r *http.Request) error
+	Update(r *http.Request) error
 	GetPost(r *http.Request) (response.PostDetail, error)
 }
 
@@ -31,17 +36,38 @@ type postUsecase struct {
 	postRepo      repository.PostRepository
 	postImageRepo repository.PostImagesRepository
 	userRepo      repository.UsersRepository
+	settingsRepo  repository.AppSettingsRepository
+	variantRepo   repository.PostImageVariantsRepository
+	blobLocks     repository.BlobLocksRepository
+	transactor    repository.Transactor
+	authorizer    authorization.PostAuthorizer
+	blobStore     storage.BlobStore
+	imageVariants ImageVariantUsecase
 }
 
 func NewPostUsecase(
 	postRepo repository.PostRepository,
 	postImageRepo repository.PostImagesRepository,
 	userRepo repository.UsersRepository,
+	settingsRepo repository.AppSettingsRepository,
+	variantRepo repository.PostImageVariantsRepository,
+	blobLocks repository.BlobLocksRepository,
+	transactor repository.Transactor,
+	authorizer authorization.PostAuthorizer,
+	blobStore storage.BlobStore,
+	imageVariants ImageVariantUsecase,
 ) PostUsecase {
 	return &postUsecase{
 		postRepo:      postRepo,
 		postImageRepo: postImageRepo,
 		userRepo:      userRepo,
+		settingsRepo:  settingsRepo,
+		variantRepo:   variantRepo,
+		blobLocks:     blobLocks,
+		transactor:    transactor,
+		authorizer:    authorizer,
+		blobStore:     blobStore,
+		imageVariants: imageVariants,
 	}
 }
 
@@ -50,45 +76,8 @@ type DeletePostRequest struct {
 }
 
 func (u *postUsecase) List(r *http.Request) (response.PostList, error) {
-	var profile *model.UserProfile
-
-	// JWT Cookie を取得
-	cookie, err := r.Cookie("auth_token")
-	if err != nil {
-		if err == http.ErrNoCookie {
-			// JWT がない場合でも profile を nil に設定
-			profile = nil
-		} else {
-			return response.PostList{}, fmt.Errorf("failed to get cookie: %v", err)
-		}
-	} else {
-		// JWT を解析
-		tokenStr := cookie.Value
-		claims := &jwt.MapClaims{}
-		secretKeyStr := os.Getenv("JWT_SECRET_KEY")
-		secretKey := []byte(secretKeyStr)
-
-		token, err := jwt.ParseWithClaims(tokenStr, claims, func(t *jwt.Token) (interface{}, error) {
-			if _, ok := t.Method.(*jwt.SigningMethodHMAC); !ok {
-				return nil, fmt.Errorf("unexpected signing method: %v", t.Header["alg"])
-			}
-			return secretKey, nil
-		})
-		if err == nil && token != nil && token.Valid {
-			// 必要なクレームを取得
-			id, _ := (*claims)["id"].(float64)
-			name, _ := (*claims)["name"].(string)
-			screenName, _ := (*claims)["screen_name"].(string)
-			profileImageUrl, _ := (*claims)["profile_image_url"].(string)
-
-			profile = &model.UserProfile{
-				ID:              id,
-				Name:            name,
-				ScreenName:      screenName,
-				ProfileImageUrl: profileImageUrl,
-			}
-		}
-	}
+	// 未ログインの場合は loginUser が nil になる
+	loginUser, _ := auth.PrincipalFromContext(r.Context())
 
 	// ページング情報を取得
 	pageStr, perPageStr := helper.PostListQueryParams(r)
@@ -131,6 +120,7 @@ func (u *postUsecase) List(r *http.Request) (response.PostList, error) {
 	}
 
 	postImagesMap := make(map[uint][]entity.PostImage)
+	var coverImageIDs []uint
 	for _, post := range posts {
 		postImages, err := u.postImageRepo.FindByPostID(post.ID)
 		if err != nil {
@@ -138,37 +128,76 @@ func (u *postUsecase) List(r *http.Request) (response.PostList, error) {
 			continue
 		}
 		postImagesMap[post.ID] = postImages
+		if coverImage, ok := helper.CoverImage(postImages); ok {
+			coverImageIDs = append(coverImageIDs, coverImage.ID)
+		}
+	}
+
+	// 一覧ではカバー画像のみ表示するため、その派生画像のみ取得する
+	variants, err := u.variantRepo.FindByPostImageIDs(coverImageIDs)
+	if err != nil {
+		fmt.Println("Error fetching post image variants:", err)
 	}
 
 	// レスポンスを作成
-	return helper.BuildPostListResponse(posts, users, postImagesMap, totalCount, page, perPage, profile), nil
+	return helper.BuildPostListResponse(
+		posts, users, postImagesMap, groupVariantsByPostImage(variants), totalCount, page, perPage, loginUser,
+	), nil
 }
 
 func (u *postUsecase) Delete(r *http.Request) error {
 	err := helper.ValidateMethod(r, http.MethodPost)
 	if err != nil {
-		return errors.New(err.Error())
+		return err
 	}
 
-	body, err := io.ReadAll(r.Body)
+	principal, err := currentPrincipal(r)
 	if err != nil {
-		return errors.New(err.Error())
+		return err
 	}
-	defer r.Body.Close()
 
 	var req DeletePostRequest
-	if err := json.Unmarshal(body, &req); err != nil {
-		return errors.New(err.Error())
+	if err := helper.DecodeJSONBody(r, &req); err != nil {
+		return err
 	}
 
 	if req.PostID <= 0 {
-		return errors.New(err.Error())
+		return apperror.NotFound("post %d was not found", req.PostID)
 	}
 
-	if err := u.postRepo.Delete(req.PostID); err != nil {
-		return errors.New(err.Error())
+	post, err := u.authorizer.AuthorizeDeletion(principal, req.PostID)
+	if err != nil {
+		return err
 	}
 
+	var postImages []entity.PostImage
+	var variants []entity.PostImageVariant
+	err = u.transactor.Transaction(func(repos repository.TxRepositories) error {
+		var err error
+		postImages, err = repos.PostImages.FindByPostID(post.ID)
+		if err != nil {
+			return err
+		}
+
+		imageIDs := postImageIDs(postImages)
+		variants, err = repos.PostImageVariants.FindByPostImageIDs(imageIDs)
+		if err != nil {
+			return err
+		}
+
+		// 派生画像の行は画像の行と一緒に削除される
+		if err := repos.PostImages.DeleteByIDs(post.ID, imageIDs); err != nil {
+			return err
+		}
+		return repos.Posts.Delete(req.PostID)
+	})
+	if err != nil {
+		return err
+	}
+
+	// コミットしてから参照数を数え、参照されなくなった内容を削除する
+	u.deleteUnreferencedImages(postImageStorageKeys(postImages), variantStorageKeys(variants))
+
 	return nil
 }
 
@@ -178,94 +207,173 @@ func (u *postUsecase) Add(r *http.Request) error {
 			log.Printf("panic recovered in Add: %v", rec)
 		}
 	}()
-	
+
 	err := helper.ValidateMethod(r, http.MethodPost)
 	if err != nil {
-		return errors.New(err.Error())
+		return err
 	}
 
-	var profile *model.UserProfile
+	principal, err := currentPrincipal(r)
+	if err != nil {
+		return err
+	}
+
+	err = helper.ParseMultipart(r)
+	if err != nil {
+		return err
+	}
 
-	// JWT Cookie を取得
-	cookie, err := r.Cookie("auth_token")
+	input, err := validation.ValidateFormInputs(r)
 	if err != nil {
-		return errors.New(err.Error())
+		return err
 	}
 
-	// JWT を解析
-	tokenStr := cookie.Value
-	claims := &jwt.MapClaims{}
-	secretKeyStr := os.Getenv("JWT_SECRET_KEY")
-	secretKey := []byte(secretKeyStr)
+	images, err := u.readUploadedImages(input.Images)
+	if err != nil {
+		return err
+	}
 
-	token, err := jwt.ParseWithClaims(tokenStr, claims, func(t *jwt.Token) (interface{}, error) {
-		if _, ok := t.Method.(*jwt.SigningMethodHMAC); !ok {
-			return nil, fmt.Errorf("unexpected signing method: %v", t.Header["alg"])
-		}
-		return secretKey, nil
+	// 内容はトランザクションの前に保存し、行はまとめて保存する
+	err = withStoredBlobs(u.blobLocks, u.blobStore, uploadedImageContents(images), func() error {
+		return u.transactor.Transaction(func(repos repository.TxRepositories) error {
+			savedPost, err := repos.Posts.Save(
+				mapper.ToModelPost(input.Title, input.Content, input.ContentTitle, input.Location, principal.UserID),
+			)
+			if err != nil {
+				return err
+			}
+			return saveImages(repos, savedPost.ID, 0, images)
+		})
 	})
-	if err == nil && token != nil && token.Valid {
-		// 必要なクレームを取得
-		id, _ := (*claims)["id"].(float64)
-		name, _ := (*claims)["name"].(string)
-		screenName, _ := (*claims)["screen_name"].(string)
-		profileImageUrl, _ := (*claims)["profile_image_url"].(string)
-
-		profile = &model.UserProfile{
-			ID:              id,
-			Name:            name,
-			ScreenName:      screenName,
-			ProfileImageUrl: profileImageUrl,
-		}
+	if err != nil {
+		u.deleteUnsavedImages(images)
+		return err
+	}
+	return nil
+}
+
+func (u *postUsecase) Update(r *http.Request) error {
+	err := helper.ValidateMethod(r, http.MethodPost)
+	if err != nil {
+		return err
+	}
+
+	principal, err := currentPrincipal(r)
+	if err != nil {
+		return err
 	}
 
 	err = helper.ParseMultipart(r)
 	if err != nil {
-		return errors.New(err.Error())
+		return err
 	}
 
-	title, content, contentTitle, location, files, err := validation.ValidateFormInputs(r)
+	input, err := validation.ValidateUpdateFormInputs(r)
 	if err != nil {
-		return errors.New(err.Error())
+		return err
 	}
 
-	savedPost, err := u.postRepo.Save(mapper.ToModelPost(title, content, contentTitle, location, uint(profile.ID)))
+	post, err := u.authorizer.AuthorizeMutation(principal.UserID, input.PostID)
 	if err != nil {
-		return errors.New(err.Error())
+		return err
 	}
 
-	for _, fileHeader := range files {
-		file, err := fileHeader.Open()
-		if err != nil {
-			return errors.New(err.Error())
-		}
-		defer file.Close()
+	postImages, err := u.postImageRepo.FindByPostID(post.ID)
+	if err != nil {
+		return err
+	}
 
-		fileData, err := io.ReadAll(file)
-		if err != nil {
-			return errors.New(err.Error())
-		}
+	imageOrder, err := helper.ResolveImageOrder(postImages, input.DeleteImageIDs, input.ImageOrder)
+	if err != nil {
+		return err
+	}
+
+	if err := checkEditableImages(imageOrder, input.Descriptions, input.CoverImageID); err != nil {
+		return err
+	}
+
+	if len(imageOrder)+len(input.Images) == 0 {
+		return apperror.InvalidFields(apperror.Field("files[]", "a post must have at least one image"))
+	}
+	if len(imageOrder)+len(input.Images) > validation.MaxPostImages {
+		return apperror.InvalidFields(
+			apperror.Field("files[]", fmt.Sprintf("a post can have at most %d images", validation.MaxPostImages)),
+		)
+	}
 
-		err = u.postImageRepo.Save(mapper.ToModelPostImage(fileHeader.Filename, savedPost.ID, fileData))
-		fmt.Println(err)
+	images, err := u.readUploadedImages(input.Images)
+	if err != nil {
+		return err
+	}
+
+	// 内容はトランザクションの前に保存し、不要になった内容はコミットしてから削除する
+	var deletedVariants []entity.PostImageVariant
+	err = withStoredBlobs(u.blobLocks, u.blobStore, uploadedImageContents(images), func() error {
+		return u.transactor.Transaction(func(repos repository.TxRepositories) error {
+			updatedPost := mapper.ToModelPost(input.Title, input.Content, input.ContentTitle, input.Location, post.UserID)
+			updatedPost.ID = int(post.ID)
+			if err := repos.Posts.Update(updatedPost); err != nil {
+				return err
+			}
+
+			var err error
+			deletedVariants, err = repos.PostImageVariants.FindByPostImageIDs(input.DeleteImageIDs)
+			if err != nil {
+				return err
+			}
+
+			if err := repos.PostImages.DeleteByIDs(post.ID, input.DeleteImageIDs); err != nil {
+				return err
+			}
+
+			if err := repos.PostImages.UpdateSortOrders(post.ID, imageOrder); err != nil {
+				return err
+			}
+
+			for _, description := range input.Descriptions {
+				err := repos.PostImages.UpdateDescription(post.ID, description.ImageID, description.AltText, description.Caption)
+				if err != nil {
+					return err
+				}
+			}
+
+			if input.CoverImageID != 0 {
+				if err := repos.PostImages.SetCover(post.ID, input.CoverImageID); err != nil {
+					return err
+				}
+			}
+
+			// 追加画像は既存画像の後ろに並べる
+			return saveImages(repos, post.ID, len(imageOrder), images)
+		})
+	})
+	if err != nil {
+		u.deleteUnsavedImages(images)
+		return err
 	}
 
+	u.deleteUnreferencedImages(
+		postImageStorageKeys(deletedPostImages(postImages, input.DeleteImageIDs)), variantStorageKeys(deletedVariants),
+	)
 	return nil
 }
 
 func (uc *postUsecase) GetPost(r *http.Request) (response.PostDetail, error) {
 	postIDStr := r.URL.Query().Get("post_id")
 	if postIDStr == "" {
-		return response.PostDetail{}, errors.New("postIDStr is blank")
+		return response.PostDetail{}, apperror.InvalidFields(apperror.Field("post_id", "post_id is required"))
 	}
 
 	postID, err := strconv.Atoi(postIDStr)
-	if err != nil {
-		return response.PostDetail{}, errors.New("postIDStr is invalid")
+	if err != nil || postID <= 0 {
+		return response.PostDetail{}, apperror.InvalidFields(apperror.Field("post_id", "post_id is invalid"))
 	}
 
 	post, err := uc.postRepo.FindByID(postID)
 	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return response.PostDetail{}, apperror.NotFound("post %d was not found", postID)
+		}
 		return response.PostDetail{}, errors.New("GetByID occured error")
 	}
 
@@ -274,7 +382,235 @@ func (uc *postUsecase) GetPost(r *http.Request) (response.PostDetail, error) {
 		return response.PostDetail{}, errors.New("FindByPostID occured error")
 	}
 
-	postDetail := helper.BuildPostResponse(post, postImages)
+	variants, err := uc.variantRepo.FindByPostImageIDs(postImageIDs(postImages))
+	if err != nil {
+		return response.PostDetail{}, errors.New("FindByPostImageIDs occured error")
+	}
+
+	// 未ログインの場合は loginUser が nil になる
+	loginUser, _ := auth.PrincipalFromContext(r.Context())
+	postDetail := helper.BuildPostResponse(post, postImages, groupVariantsByPostImage(variants), loginUser)
 
 	return postDetail, nil
 }
+
+func readFormFile(fileHeader *multipart.FileHeader) ([]byte, error) {
+	file, err := fileHeader.Open()
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	return io.ReadAll(file)
+}
+
+type uploadedImage struct {
+	fileName     string
+	altText      string
+	caption      string
+	isCover      bool
+	data         []byte
+	mimeType     string
+	width        int
+	height       int
+	derived      *DerivedImages
+	gpsLatitude  *float64
+	gpsLongitude *float64
+}
+
+// 投稿を保存する前にすべての画像を読み込み、中身が対応している形式か確認してメタデータを取り除く
+func (u *postUsecase) readUploadedImages(inputs []validation.NewPostImageInput) ([]uploadedImage, error) {
+	keepGPS, err := boolSetting(u.settingsRepo, entity.AppSettingKeepUploadGPS)
+	if err != nil {
+		return nil, err
+	}
+
+	images := make([]uploadedImage, 0, len(inputs))
+	var violations []apperror.FieldViolation
+	for _, input := range inputs {
+		fileHeader := input.File
+		data, err := readFormFile(fileHeader)
+		if err != nil {
+			return nil, err
+		}
+
+		info, err := imaging.Inspect(fileHeader.Filename, data)
+		if err != nil {
+			log.Printf("rejected upload %q: %v", fileHeader.Filename, err)
+			violations = append(violations, apperror.Field("files[]", uploadedImageErrorMessage(fileHeader.Filename, err)))
+			continue
+		}
+
+		sanitized, err := imaging.Sanitize(data, info, keepGPS)
+		if err != nil {
+			log.Printf("failed to sanitize upload %q: %v", fileHeader.Filename, err)
+			violations = append(violations, apperror.Field("files[]", fmt.Sprintf("%q could not be processed", fileHeader.Filename)))
+			continue
+		}
+
+		derived, err := u.imageVariants.Generate(sanitized.Data)
+		if err != nil {
+			return nil, err
+		}
+
+		image := uploadedImage{
+			fileName: fileHeader.Filename,
+			altText:  input.AltText,
+			caption:  input.Caption,
+			isCover:  input.IsCover,
+			data:     sanitized.Data,
+			mimeType: sanitized.MimeType,
+			width:    sanitized.Width,
+			height:   sanitized.Height,
+			derived:  derived,
+		}
+		if sanitized.GPS != nil {
+			image.gpsLatitude = &sanitized.GPS.Latitude
+			image.gpsLongitude = &sanitized.GPS.Longitude
+		}
+		images = append(images, image)
+	}
+
+	if len(violations) > 0 {
+		return nil, apperror.InvalidFields(violations...)
+	}
+	return images, nil
+}
+
+func (image uploadedImage) content() blobContent {
+	return blobContent{
+		namespace: blobstore.PostImageNamespace,
+		data:      image.data,
+		mimeType:  image.mimeType,
+	}
+}
+
+// 画像と派生画像の内容
+func uploadedImageContents(images []uploadedImage) []blobContent {
+	var contents []blobContent
+	for _, image := range images {
+		contents = append(contents, image.content())
+		contents = append(contents, variantContents(image.derived.Variants)...)
+	}
+	return contents
+}
+
+// firstSortOrder から順に並び順を付けて画像と派生画像の行を保存し、カバー画像に指定された画像があれば設定する。
+// 内容は保存済みであること
+func saveImages(repos repository.TxRepositories, postID uint, firstSortOrder int, images []uploadedImage) error {
+	var coverImageID uint
+	for i, image := range images {
+		content := image.content()
+		savedImage, err := repos.PostImages.Save(
+			mapper.ToModelPostImage(
+				image.fileName, postID, content.key(), int64(len(image.data)), content.checksum(),
+				image.mimeType, image.width, image.height,
+				image.derived.Placeholder.BlurHash, image.derived.Placeholder.DominantColor,
+				firstSortOrder+i, image.altText, image.caption, image.gpsLatitude, image.gpsLongitude,
+			),
+		)
+		if err != nil {
+			return err
+		}
+
+		if err := saveVariantRows(repos.PostImageVariants, savedImage.ID, image.derived.Variants); err != nil {
+			return err
+		}
+		if image.isCover {
+			coverImageID = savedImage.ID
+		}
+	}
+
+	if coverImageID != 0 {
+		return repos.PostImages.SetCover(postID, coverImageID)
+	}
+	return nil
+}
+
+// 削除した画像と派生画像の内容のうち、他から参照されていないものを BlobStore から削除する
+func (u *postUsecase) deleteUnreferencedImages(imageKeys []string, variantKeys []string) {
+	deleteUnreferencedBlobs(u.blobLocks, u.blobStore, imageKeys, u.postImageRepo.CountByStorageKey)
+	deleteUnreferencedBlobs(u.blobLocks, u.blobStore, variantKeys, u.variantRepo.CountByStorageKey)
+}
+
+// ロールバックした場合、保存した内容はどの行からも参照されないため削除する
+func (u *postUsecase) deleteUnsavedImages(images []uploadedImage) {
+	var imageKeys, variantKeys []string
+	for _, image := range images {
+		imageKeys = append(imageKeys, image.content().key())
+		variantKeys = append(variantKeys, blobContentKeys(variantContents(image.derived.Variants))...)
+	}
+	u.deleteUnreferencedImages(imageKeys, variantKeys)
+}
+
+func postImageStorageKeys(postImages []entity.PostImage) []string {
+	keys := make([]string, 0, len(postImages))
+	for _, postImage := range postImages {
+		keys = append(keys, postImage.StorageKey)
+	}
+	return keys
+}
+
+func postImageIDs(postImages []entity.PostImage) []uint {
+	ids := make([]uint, 0, len(postImages))
+	for _, postImage := range postImages {
+		ids = append(ids, postImage.ID)
+	}
+	return ids
+}
+
+// 説明の変更とカバー画像の指定は、削除しない既存の画像のみ対象にできる
+func checkEditableImages(imageOrder []uint, descriptions []validation.PostImageDescriptionInput, coverImageID uint) error {
+	remaining := make(map[uint]bool, len(imageOrder))
+	for _, id := range imageOrder {
+		remaining[id] = true
+	}
+
+	var violations []apperror.FieldViolation
+	for _, description := range descriptions {
+		if !remaining[description.ImageID] {
+			violations = append(violations, apperror.Field(
+				"image_ids[]", fmt.Sprintf("image %d cannot be edited", description.ImageID),
+			))
+		}
+	}
+	if coverImageID != 0 && !remaining[coverImageID] {
+		violations = append(violations, apperror.Field(
+			"cover_image_id", fmt.Sprintf("image %d cannot be the cover", coverImageID),
+		))
+	}
+
+	if len(violations) > 0 {
+		return apperror.InvalidFields(violations...)
+	}
+	return nil
+}
+
+func deletedPostImages(postImages []entity.PostImage, deleteImageIDs []uint) []entity.PostImage {
+	deleteIDs := make(map[uint]bool, len(deleteImageIDs))
+	for _, id := range deleteImageIDs {
+		deleteIDs[id] = true
+	}
+
+	var deleted []entity.PostImage
+	for _, postImage := range postImages {
+		if deleteIDs[postImage.ID] {
+			deleted = append(deleted, postImage)
+		}
+	}
+	return deleted
+}
+
+func uploadedImageErrorMessage(fileName string, err error) string {
+	switch {
+	case errors.Is(err, imaging.ErrTooLarge):
+		return fmt.Sprintf(
+			"%q must be at most %d pixels wide and high and %d megapixels",
+			fileName, imaging.MaxDimension, imaging.MaxPixels/1_000_000,
+		)
+	case errors.Is(err, imaging.ErrTypeMismatch):
+		return fmt.Sprintf("%q content does not match its file type", fileName)
+	default:
+		return fmt.Sprintf("%q is not a JPEG, PNG, GIF or WebP image", fileName)
+	}
+}
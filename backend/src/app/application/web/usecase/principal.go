@@ -0,0 +1,16 @@
+package usecase
+
+import (
+	"net/http"
+	"proto-pulse-plat/auth"
+	"proto-pulse-plat/domain/apperror"
+)
+
+// 認証ミドルウェアが context に格納したログインユーザーを取得する
+func currentPrincipal(r *http.Request) (*auth.Principal, error) {
+	principal, ok := auth.PrincipalFromContext(r.Context())
+	if !ok {
+		return nil, apperror.Unauthorized("authentication is required")
+	}
+	return principal, nil
+}
@@ -0,0 +1,135 @@
+package usecase
+
+import (
+	"errors"
+	"net/http"
+	"proto-pulse-plat/auth"
+	"proto-pulse-plat/domain/apperror"
+	"proto-pulse-plat/domain/repository"
+	"proto-pulse-plat/helper"
+	"proto-pulse-plat/infrastructure/response"
+
+	"gorm.io/gorm"
+)
+
+type SessionUsecase interface {
+	List(r *http.Request) (response.SessionList, error)
+	Logout(r *http.Request) error
+	LogoutAll(r *http.Request) error
+	Revoke(r *http.Request) error
+}
+
+type sessionUsecase struct {
+	sessionRepo      repository.SessionsRepository
+	refreshTokenRepo repository.RefreshTokensRepository
+}
+
+func NewSessionUsecase(
+	sessionRepo repository.SessionsRepository,
+	refreshTokenRepo repository.RefreshTokensRepository,
+) SessionUsecase {
+	return &sessionUsecase{
+		sessionRepo:      sessionRepo,
+		refreshTokenRepo: refreshTokenRepo,
+	}
+}
+
+type RevokeSessionRequest struct {
+	SessionID string `json:"session_id"`
+}
+
+func (u *sessionUsecase) List(r *http.Request) (response.SessionList, error) {
+	principal, err := currentPrincipal(r)
+	if err != nil {
+		return response.SessionList{}, err
+	}
+
+	sessions, err := u.sessionRepo.FindActiveByUserID(principal.UserID)
+	if err != nil {
+		return response.SessionList{}, err
+	}
+
+	return helper.BuildSessionListResponse(sessions, principal.SessionID), nil
+}
+
+// 現在のセッションを失効させる。アクセストークンが期限切れの場合はリフレッシュトークンからセッションを特定する
+func (u *sessionUsecase) Logout(r *http.Request) error {
+	userID, sessionID, err := u.currentSession(r)
+	if err != nil {
+		return err
+	}
+
+	err = u.sessionRepo.Revoke(userID, sessionID)
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		return err
+	}
+
+	return nil
+}
+
+func (u *sessionUsecase) currentSession(r *http.Request) (uint, string, error) {
+	if principal, err := currentPrincipal(r); err == nil {
+		return principal.UserID, principal.SessionID, nil
+	}
+
+	cookie, err := r.Cookie(helper.RefreshCookieName)
+	if err != nil || cookie.Value == "" {
+		return 0, "", apperror.Unauthorized("authentication is required")
+	}
+
+	refreshToken, err := u.refreshTokenRepo.FindByHash(auth.HashRefreshToken(cookie.Value))
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return 0, "", apperror.Unauthorized("authentication is required")
+		}
+		return 0, "", err
+	}
+
+	return refreshToken.UserID, refreshToken.SessionID, nil
+}
+
+// 全端末のセッションを失効させる
+func (u *sessionUsecase) LogoutAll(r *http.Request) error {
+	err := helper.ValidateMethod(r, http.MethodPost)
+	if err != nil {
+		return err
+	}
+
+	principal, err := currentPrincipal(r)
+	if err != nil {
+		return err
+	}
+
+	if err := u.sessionRepo.RevokeAllByUserID(principal.UserID); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// 指定したセッションを失効させる
+func (u *sessionUsecase) Revoke(r *http.Request) error {
+	err := helper.ValidateMethod(r, http.MethodPost)
+	if err != nil {
+		return err
+	}
+
+	principal, err := currentPrincipal(r)
+	if err != nil {
+		return err
+	}
+
+	var req RevokeSessionRequest
+	if err := helper.DecodeJSONBody(r, &req); err != nil {
+		return err
+	}
+
+	if err := u.sessionRepo.Revoke(principal.UserID, req.SessionID); err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return apperror.NotFound("session %q was not found", req.SessionID)
+		}
+		return err
+	}
+
+	return nil
+}
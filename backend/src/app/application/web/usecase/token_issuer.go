@@ -0,0 +1,83 @@
+package usecase
+
+import (
+	"fmt"
+	"proto-pulse-plat/auth"
+	"proto-pulse-plat/config"
+	"proto-pulse-plat/domain/entity"
+	"proto-pulse-plat/domain/repository"
+	"proto-pulse-plat/helper"
+	"proto-pulse-plat/infrastructure/mapper"
+	"time"
+)
+
+// アクセストークンとリフレッシュトークンを発行する
+type TokenIssuer interface {
+	// ログイン時に新しいセッション (トークンファミリー) を作成して発行する
+	Issue(user entity.User, userAgent string) (*auth.TokenPair, error)
+	// 既存セッションのトークンを再発行する
+	Rotate(user entity.User, session entity.Session) (*auth.TokenPair, error)
+}
+
+type tokenIssuer struct {
+	keyRing          *config.KeyRing
+	sessionRepo      repository.SessionsRepository
+	refreshTokenRepo repository.RefreshTokensRepository
+}
+
+func NewTokenIssuer(
+	keyRing *config.KeyRing,
+	sessionRepo repository.SessionsRepository,
+	refreshTokenRepo repository.RefreshTokensRepository,
+) TokenIssuer {
+	return &tokenIssuer{
+		keyRing:          keyRing,
+		sessionRepo:      sessionRepo,
+		refreshTokenRepo: refreshTokenRepo,
+	}
+}
+
+func (i *tokenIssuer) Issue(user entity.User, userAgent string) (*auth.TokenPair, error) {
+	sessionID, err := helper.GenerateNonce(32)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	session, err := i.sessionRepo.Save(
+		mapper.ToModelSession(sessionID, user.ID, userAgent, now, now.Add(auth.SessionTTL)),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to save session: %w", err)
+	}
+
+	return i.Rotate(user, *session)
+}
+
+func (i *tokenIssuer) Rotate(user entity.User, session entity.Session) (*auth.TokenPair, error) {
+	accessTokenExpiresAt := time.Now().Add(auth.AccessTokenTTL)
+	accessToken, err := helper.GenerateJWT(i.keyRing, user, session, accessTokenExpiresAt)
+	if err != nil {
+		return nil, err
+	}
+
+	refreshToken, refreshTokenHash, err := auth.NewRefreshToken()
+	if err != nil {
+		return nil, err
+	}
+
+	// リフレッシュトークンはセッションの有効期限を超えない
+	_, err = i.refreshTokenRepo.Save(
+		mapper.ToModelRefreshToken(session.ID, user.ID, refreshTokenHash, session.ExpiresAt),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return &auth.TokenPair{
+		AccessToken:           accessToken,
+		AccessTokenExpiresAt:  accessTokenExpiresAt,
+		RefreshToken:          refreshToken,
+		RefreshTokenExpiresAt: session.ExpiresAt,
+	}, nil
+}
@@ -3,10 +3,13 @@ package usecase
 import (
 	"errors"
 	"net/http"
+	"proto-pulse-plat/domain/apperror"
 	"proto-pulse-plat/domain/repository"
 	"proto-pulse-plat/helper"
 	"proto-pulse-plat/infrastructure/response"
 	"strconv"
+
+	"gorm.io/gorm"
 )
 
 type UserUsecase interface {
@@ -28,16 +31,19 @@ func NewUserUsecase(
 func (u *userUsecase) Find(r *http.Request) (*response.User, error) {
 	userIDStr := r.URL.Query().Get("user_id")
 	if userIDStr == "" {
-		return nil, errors.New("userIDStr is blank")
+		return nil, apperror.InvalidFields(apperror.Field("user_id", "user_id is required"))
 	}
 
 	userID, err := strconv.Atoi(userIDStr)
-	if err != nil {
-		return nil, errors.New("userID is invalid")
+	if err != nil || userID <= 0 {
+		return nil, apperror.InvalidFields(apperror.Field("user_id", "user_id is invalid"))
 	}
 
 	user, err := u.userRepo.Find(uint(userID))
 	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, apperror.NotFound("user %d was not found", userID)
+		}
 		return nil, errors.New("Find occured error")
 	}
 
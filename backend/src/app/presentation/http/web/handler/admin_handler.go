@@ -0,0 +1,81 @@
+package handler
+
+import (
+	"fmt"
+	"net/http"
+	"proto-pulse-plat/app/application/web/usecase"
+	"proto-pulse-plat/helper"
+)
+
+type AdminHandler struct {
+	AdminUsecase usecase.AdminUsecase
+}
+
+func NewAdminHandler(
+	adminUsecase usecase.AdminUsecase,
+) *AdminHandler {
+	return &AdminHandler{
+		AdminUsecase: adminUsecase,
+	}
+}
+
+func (h *AdminHandler) UpdateRole(w http.ResponseWriter, r *http.Request) {
+	err := h.AdminUsecase.UpdateRole(r)
+	if err != nil {
+		fmt.Println(err)
+		helper.WriteError(w, err, "Failed to update role")
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+}
+
+func (h *AdminHandler) Suspend(w http.ResponseWriter, r *http.Request) {
+	err := h.AdminUsecase.Suspend(r)
+	if err != nil {
+		fmt.Println(err)
+		helper.WriteError(w, err, "Failed to suspend user")
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+}
+
+func (h *AdminHandler) Unsuspend(w http.ResponseWriter, r *http.Request) {
+	err := h.AdminUsecase.Unsuspend(r)
+	if err != nil {
+		fmt.Println(err)
+		helper.WriteError(w, err, "Failed to unsuspend user")
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+}
+
+func (h *AdminHandler) GetSettings(w http.ResponseWriter, r *http.Request) {
+	settings, err := h.AdminUsecase.GetSettings(r)
+	if err != nil {
+		fmt.Println(err)
+		helper.WriteError(w, err, "Failed to get settings")
+		return
+	}
+
+	err = helper.WriteResponse(w, settings)
+	if err != nil {
+		helper.WriteError(w, err, "Failed WriteResponse")
+	}
+}
+
+func (h *AdminHandler) UpdateSettings(w http.ResponseWriter, r *http.Request) {
+	settings, err := h.AdminUsecase.UpdateSettings(r)
+	if err != nil {
+		fmt.Println(err)
+		helper.WriteError(w, err, "Failed to update settings")
+		return
+	}
+
+	err = helper.WriteResponse(w, settings)
+	if err != nil {
+		helper.WriteError(w, err, "Failed WriteResponse")
+	}
+}
@@ -0,0 +1,32 @@
+package handler
+
+import (
+	"fmt"
+	"net/http"
+	"proto-pulse-plat/app/application/web/usecase"
+	"proto-pulse-plat/helper"
+)
+
+type AuthHandler struct {
+	AuthUsecase usecase.AuthUsecase
+}
+
+func NewAuthHandler(authUsecase usecase.AuthUsecase) *AuthHandler {
+	return &AuthHandler{
+		AuthUsecase: authUsecase,
+	}
+}
+
+func (h *AuthHandler) Refresh(w http.ResponseWriter, r *http.Request) {
+	tokenPair, err := h.AuthUsecase.Refresh(r)
+	if err != nil {
+		fmt.Println(err)
+		helper.ClearAuthCookies(w)
+		helper.WriteError(w, err, "Failed to refresh token")
+		return
+	}
+
+	helper.SetAuthCookies(w, tokenPair)
+
+	w.WriteHeader(http.StatusOK)
+}
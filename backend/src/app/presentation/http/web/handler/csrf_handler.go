@@ -0,0 +1,38 @@
+package handler
+
+import (
+	"fmt"
+	"net/http"
+	"proto-pulse-plat/auth"
+	"proto-pulse-plat/config"
+	"proto-pulse-plat/helper"
+	"proto-pulse-plat/infrastructure/response"
+)
+
+type CSRFHandler struct {
+	cookieConfig *config.CookieConfig
+}
+
+func NewCSRFHandler(cookieConfig *config.CookieConfig) *CSRFHandler {
+	return &CSRFHandler{
+		cookieConfig: cookieConfig,
+	}
+}
+
+// フロントエンドは返されたトークンを X-Csrf-Token ヘッダーに付けて送信する。
+// トークンはログイン中のセッションに結び付くため、ログイン・ログアウト後は取得し直す
+func (h *CSRFHandler) Token(w http.ResponseWriter, r *http.Request) {
+	principal, _ := auth.PrincipalFromContext(r.Context())
+	token, err := helper.IssueCSRFToken(w, r, h.cookieConfig.StoreKey, helper.CSRFSubject(principal))
+	if err != nil {
+		fmt.Println(err)
+		helper.WriteError(w, err, "Failed to issue CSRF token")
+		return
+	}
+
+	w.Header().Set("Cache-Control", "no-store")
+	err = helper.WriteResponse(w, response.CSRFToken{Token: token})
+	if err != nil {
+		helper.WriteError(w, err, "Failed WriteResponse")
+	}
+}
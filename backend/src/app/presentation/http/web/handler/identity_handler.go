@@ -0,0 +1,45 @@
+package handler
+
+import (
+	"fmt"
+	"net/http"
+	"proto-pulse-plat/app/application/web/usecase"
+	"proto-pulse-plat/helper"
+)
+
+type IdentityHandler struct {
+	IdentityUsecase usecase.IdentityUsecase
+}
+
+func NewIdentityHandler(
+	identityUsecase usecase.IdentityUsecase,
+) *IdentityHandler {
+	return &IdentityHandler{
+		IdentityUsecase: identityUsecase,
+	}
+}
+
+func (h *IdentityHandler) List(w http.ResponseWriter, r *http.Request) {
+	identities, err := h.IdentityUsecase.List(r)
+	if err != nil {
+		fmt.Println(err)
+		helper.WriteError(w, err, "Failed to list identities")
+		return
+	}
+
+	err = helper.WriteResponse(w, identities)
+	if err != nil {
+		helper.WriteError(w, err, "Failed WriteResponse")
+	}
+}
+
+func (h *IdentityHandler) Unlink(w http.ResponseWriter, r *http.Request) {
+	err := h.IdentityUsecase.Unlink(r)
+	if err != nil {
+		fmt.Println(err)
+		helper.WriteError(w, err, "Failed to unlink identity")
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+}
@@ -0,0 +1,47 @@
+package handler
+
+import (
+	"net/http"
+	"proto-pulse-plat/app/application/web/usecase"
+	"proto-pulse-plat/helper"
+)
+
+type ImageHandler struct {
+	ImageUsecase usecase.ImageUsecase
+}
+
+func NewImageHandler(
+	imageUsecase usecase.ImageUsecase,
+) *ImageHandler {
+	return &ImageHandler{
+		ImageUsecase: imageUsecase,
+	}
+}
+
+func (h *ImageHandler) GetPostImage(w http.ResponseWriter, r *http.Request) {
+	image, err := h.ImageUsecase.GetPostImage(r)
+	if err != nil {
+		helper.WriteError(w, err, "Failed to get image")
+		return
+	}
+
+	if image.Content != nil {
+		defer image.Content.Close()
+	}
+
+	helper.ServeImage(w, r, image.Content, image.MimeType, image.Checksum)
+}
+
+func (h *ImageHandler) GetUserIcon(w http.ResponseWriter, r *http.Request) {
+	image, err := h.ImageUsecase.GetUserIcon(r)
+	if err != nil {
+		helper.WriteError(w, err, "Failed to get user icon")
+		return
+	}
+
+	if image.Content != nil {
+		defer image.Content.Close()
+	}
+
+	helper.ServeImage(w, r, image.Content, image.MimeType, image.Checksum)
+}
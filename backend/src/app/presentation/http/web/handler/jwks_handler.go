@@ -0,0 +1,27 @@
+package handler
+
+import (
+	"net/http"
+	"proto-pulse-plat/config"
+	"proto-pulse-plat/helper"
+)
+
+type JWKSHandler struct {
+	keyRing *config.KeyRing
+}
+
+func NewJWKSHandler(keyRing *config.KeyRing) *JWKSHandler {
+	return &JWKSHandler{
+		keyRing: keyRing,
+	}
+}
+
+// 他サービスがトークンを検証するための公開鍵を返す
+func (h *JWKSHandler) JWKS(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Cache-Control", "public, max-age=300")
+
+	err := helper.WriteResponse(w, helper.BuildJWKSResponse(h.keyRing))
+	if err != nil {
+		helper.WriteError(w, err, "Failed WriteResponse")
+	}
+}
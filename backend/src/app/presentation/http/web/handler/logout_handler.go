@@ -1,27 +1,41 @@
 package handler
 
 import (
+	"fmt"
 	"net/http"
-	"time"
+	"proto-pulse-plat/app/application/web/usecase"
+	"proto-pulse-plat/helper"
 )
 
 type LogoutHandler struct {
+	SessionUsecase usecase.SessionUsecase
 }
 
-func NewLogoutHandler() *LogoutHandler {
-	return &LogoutHandler{}
+func NewLogoutHandler(sessionUsecase usecase.SessionUsecase) *LogoutHandler {
+	return &LogoutHandler{
+		SessionUsecase: sessionUsecase,
+	}
 }
+
 func (oc *LogoutHandler) Logout(w http.ResponseWriter, r *http.Request) {
-	http.SetCookie(w, &http.Cookie{
-		Name:     "auth_token",         // 削除したいクッキーの名前
-		Value:    "",                   // 値を空にする
-		Path:     "/",                  // クッキーが有効なパス
-		Expires:  time.Unix(0, 0),      // 過去の日付を設定
-		MaxAge:   -1,                   // 最大期限を負に設定
-		HttpOnly: true,                // HttpOnly属性がついている場合
-		Secure:   true,                // 本番環境では true にする
-		SameSite: http.SameSiteLaxMode, // SameSite属性を設定することでセキュリティ強化
-	})
+	// セッションが既に無効でも Cookie は削除する
+	if err := oc.SessionUsecase.Logout(r); err != nil {
+		fmt.Println(err)
+	}
+
+	helper.ClearAuthCookies(w)
+
+	w.WriteHeader(http.StatusOK)
+}
+
+func (oc *LogoutHandler) LogoutAll(w http.ResponseWriter, r *http.Request) {
+	if err := oc.SessionUsecase.LogoutAll(r); err != nil {
+		fmt.Println(err)
+		helper.WriteError(w, err, "Failed to logout all sessions")
+		return
+	}
+
+	helper.ClearAuthCookies(w)
 
 	w.WriteHeader(http.StatusOK)
 }
@@ -8,60 +8,108 @@ import (
 	"os"
 	"proto-pulse-plat/app/application/web/usecase"
 	"proto-pulse-plat/config"
+	"proto-pulse-plat/domain/apperror"
 	"proto-pulse-plat/helper"
-	"time"
+	"proto-pulse-plat/infrastructure/identityprovider"
+
+	"github.com/gorilla/mux"
 )
 
 type OAuthClient struct {
 	OauthUsecase usecase.OAuthUsecase
+	cookieConfig *config.CookieConfig
 }
 
-func NewOAuthClient(oauthUsecase usecase.OAuthUsecase, xConfig *config.Xconfig) *OAuthClient {
+func NewOAuthClient(oauthUsecase usecase.OAuthUsecase, cookieConfig *config.CookieConfig) *OAuthClient {
 	return &OAuthClient{
 		OauthUsecase: oauthUsecase,
+		cookieConfig: cookieConfig,
 	}
 }
 
 func (oc *OAuthClient) OauthCertificate(w http.ResponseWriter, r *http.Request) {
-	req, err := oc.OauthUsecase.MakeOAuthRequest()
+	oauthRequest, err := oc.OauthUsecase.MakeOAuthRequest(r, providerName(r))
+	oc.startAuthorization(w, oauthRequest, err)
+}
+
+// ログイン中のユーザーに別のログイン方法を紐付ける認可を開始する
+func (oc *OAuthClient) OauthLink(w http.ResponseWriter, r *http.Request) {
+	oauthRequest, err := oc.OauthUsecase.MakeLinkRequest(r, providerName(r))
+	oc.startAuthorization(w, oauthRequest, err)
+}
+
+func (oc *OAuthClient) startAuthorization(w http.ResponseWriter, oauthRequest *usecase.OAuthRequest, err error) {
 	if err != nil {
 		fmt.Println("Error creating request:", err)
+		// 種類の無いエラーはプロバイダーとの通信の失敗とみなす
+		if _, ok := apperror.As(err); ok {
+			helper.WriteError(w, err, "Failed MakeOAuthRequest")
+		} else {
+			helper.WriteError(w, apperror.BadGateway("Failed MakeOAuthRequest").Wrap(err), "")
+		}
 		return
 	}
 
-	client := &http.Client{}
-	resp, err := client.Do(req)
+	// リクエストトークンと secret を認可を開始したブラウザに紐付ける
+	err = helper.SetOAuthRequestCookie(w, oc.cookieConfig.StoreKey, oauthRequest)
 	if err != nil {
-		fmt.Println("Error sending request:", err)
+		fmt.Println("Error setting oauth request cookie:", err)
+		helper.WriteError(w, err, "Failed MakeOAuthRequest")
 		return
 	}
-	defer resp.Body.Close()
 
-	response := map[string]string{"redirectURL": resp.Request.URL.String()}
+	response := map[string]string{"redirectURL": oauthRequest.AuthorizationURL}
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(http.StatusOK)
 	err = json.NewEncoder(w).Encode(response)
 	if err != nil {
-		http.Error(w, "Failed to send JSON response", http.StatusInternalServerError)
 		log.Println("Error encoding JSON response:", err)
 	}
 }
 
 func (oc *OAuthClient) OauthCallback(w http.ResponseWriter, r *http.Request) {
-	tokenString, err := oc.OauthUsecase.GetOAuthResponse(r)
+	var oauthRequest usecase.OAuthRequest
+	err := helper.ConsumeOAuthRequestCookie(w, r, oc.cookieConfig.StoreKey, &oauthRequest)
+	if err != nil {
+		fmt.Println(err)
+		helper.WriteError(w, apperror.InvalidArgument("OAuth request is missing or expired"), "")
+		return
+	}
+
+	// 認可を開始したプロバイダー以外のコールバックは受け付けない
+	if oauthRequest.Provider != providerName(r) {
+		helper.WriteError(w, apperror.InvalidArgument("OAuth provider does not match the pending request"), "")
+		return
+	}
+
+	if oauthRequest.IsLink() {
+		err = oc.OauthUsecase.LinkIdentity(r, &oauthRequest)
+		if err != nil {
+			fmt.Println(err)
+			helper.WriteError(w, err, "Failed LinkIdentity")
+			return
+		}
+
+		http.Redirect(w, r, os.Getenv("BASE_HTTPS_URL"), http.StatusSeeOther)
+		return
+	}
+
+	tokenPair, err := oc.OauthUsecase.GetOAuthResponse(r, &oauthRequest)
 	if err != nil {
-		helper.WriteErrorResponse(w, "Failed GetOAuthResponse", http.StatusInternalServerError)
+		fmt.Println(err)
+		helper.WriteError(w, err, "Failed GetOAuthResponse")
+		return
 	}
 
-	http.SetCookie(w, &http.Cookie{
-		Name:     "auth_token",
-		Value:    tokenString,
-		Path:     "/",
-		Expires:  time.Now().Add(24 * time.Hour),
-		HttpOnly: true,
-		Secure:   true, // 本番環境では true にする
-		SameSite: http.SameSiteNoneMode,
-	})
+	helper.SetAuthCookies(w, tokenPair)
 
 	http.Redirect(w, r, fmt.Sprintf("%s", os.Getenv("BASE_HTTPS_URL")), http.StatusSeeOther)
 }
+
+// ルートの {provider}。従来の /api/oauth, /api/oauth2callback は X とみなす
+func providerName(r *http.Request) string {
+	if provider, ok := mux.Vars(r)["provider"]; ok {
+		return provider
+	}
+	return identityprovider.XProviderName
+}
@@ -0,0 +1,62 @@
+package handler
+
+import (
+	"fmt"
+	"net/http"
+	"proto-pulse-plat/app/application/web/usecase"
+	"proto-pulse-plat/helper"
+)
+
+type PersonalAccessTokenHandler struct {
+	PersonalAccessTokenUsecase usecase.PersonalAccessTokenUsecase
+}
+
+func NewPersonalAccessTokenHandler(
+	personalAccessTokenUsecase usecase.PersonalAccessTokenUsecase,
+) *PersonalAccessTokenHandler {
+	return &PersonalAccessTokenHandler{
+		PersonalAccessTokenUsecase: personalAccessTokenUsecase,
+	}
+}
+
+func (h *PersonalAccessTokenHandler) List(w http.ResponseWriter, r *http.Request) {
+	tokens, err := h.PersonalAccessTokenUsecase.List(r)
+	if err != nil {
+		fmt.Println(err)
+		helper.WriteError(w, err, "Failed to list tokens")
+		return
+	}
+
+	err = helper.WriteResponse(w, tokens)
+	if err != nil {
+		helper.WriteError(w, err, "Failed WriteResponse")
+	}
+}
+
+func (h *PersonalAccessTokenHandler) Create(w http.ResponseWriter, r *http.Request) {
+	token, err := h.PersonalAccessTokenUsecase.Create(r)
+	if err != nil {
+		fmt.Println(err)
+		helper.WriteError(w, err, "Failed to create token")
+		return
+	}
+
+	w.Header().Set("Cache-Control", "no-store")
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	err = helper.WriteResponse(w, token)
+	if err != nil {
+		fmt.Println(err)
+	}
+}
+
+func (h *PersonalAccessTokenHandler) Revoke(w http.ResponseWriter, r *http.Request) {
+	err := h.PersonalAccessTokenUsecase.Revoke(r)
+	if err != nil {
+		fmt.Println(err)
+		helper.WriteError(w, err, "Failed to revoke token")
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+}
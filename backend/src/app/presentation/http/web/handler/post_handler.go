@@ -22,19 +22,21 @@ func NewPostHandler(
 func (oc *PostHandler) GetPostList(w http.ResponseWriter, r *http.Request) {
 	postList, err := oc.PostUsecase.List(r)
 	if err != nil {
-		helper.WriteErrorResponse(w, "Failed GetPostList", http.StatusInternalServerError)
+		helper.WriteError(w, err, "Failed GetPostList")
+		return
 	}
 
 	err = helper.WriteResponse(w, postList)
 	if err != nil {
-		helper.WriteErrorResponse(w, "Failed WriteResponse", http.StatusInternalServerError)
+		helper.WriteError(w, err, "Failed WriteResponse")
 	}
 }
 
 func (oc *PostHandler) DeletePost(w http.ResponseWriter, r *http.Request) {
 	err := oc.PostUsecase.Delete(r)
 	if err != nil {
-		http.Error(w, "Failed to delete post", http.StatusInternalServerError)
+		fmt.Println(err)
+		helper.WriteError(w, err, "Failed to delete post")
 		return
 	}
 
@@ -45,7 +47,18 @@ func (oc *PostHandler) AddPost(w http.ResponseWriter, r *http.Request) {
 	err := oc.PostUsecase.Add(r)
 	if err != nil {
 		fmt.Println(err)
-		helper.WriteErrorResponse(w, "Failed to add post", http.StatusInternalServerError)
+		helper.WriteError(w, err, "Failed to add post")
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+}
+
+func (oc *PostHandler) UpdatePost(w http.ResponseWriter, r *http.Request) {
+	err := oc.PostUsecase.Update(r)
+	if err != nil {
+		fmt.Println(err)
+		helper.WriteError(w, err, "Failed to update post")
 		return
 	}
 
@@ -55,11 +68,12 @@ func (oc *PostHandler) AddPost(w http.ResponseWriter, r *http.Request) {
 func (oc *PostHandler) GetPost(w http.ResponseWriter, r *http.Request) {
 	postDetail, err := oc.PostUsecase.GetPost(r)
 	if err != nil {
-		helper.WriteErrorResponse(w, "Failed GetPost", http.StatusInternalServerError)
+		helper.WriteError(w, err, "Failed GetPost")
+		return
 	}
 
 	err = helper.WriteResponse(w, postDetail)
 	if err != nil {
-		helper.WriteErrorResponse(w, "Failed WriteResponse", http.StatusInternalServerError)
+		helper.WriteError(w, err, "Failed WriteResponse")
 	}
 }
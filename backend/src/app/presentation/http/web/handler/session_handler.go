@@ -0,0 +1,45 @@
+package handler
+
+import (
+	"fmt"
+	"net/http"
+	"proto-pulse-plat/app/application/web/usecase"
+	"proto-pulse-plat/helper"
+)
+
+type SessionHandler struct {
+	SessionUsecase usecase.SessionUsecase
+}
+
+func NewSessionHandler(
+	sessionUsecase usecase.SessionUsecase,
+) *SessionHandler {
+	return &SessionHandler{
+		SessionUsecase: sessionUsecase,
+	}
+}
+
+func (h *SessionHandler) List(w http.ResponseWriter, r *http.Request) {
+	sessions, err := h.SessionUsecase.List(r)
+	if err != nil {
+		fmt.Println(err)
+		helper.WriteError(w, err, "Failed to list sessions")
+		return
+	}
+
+	err = helper.WriteResponse(w, sessions)
+	if err != nil {
+		helper.WriteError(w, err, "Failed WriteResponse")
+	}
+}
+
+func (h *SessionHandler) Revoke(w http.ResponseWriter, r *http.Request) {
+	err := h.SessionUsecase.Revoke(r)
+	if err != nil {
+		fmt.Println(err)
+		helper.WriteError(w, err, "Failed to revoke session")
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+}
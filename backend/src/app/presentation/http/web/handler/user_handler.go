@@ -21,11 +21,12 @@ func NewUserHandler(
 func (h *UserHandler) Find(w http.ResponseWriter, r *http.Request) {
 	user, err := h.UserUsecase.Find(r)
 	if err != nil {
-		helper.WriteErrorResponse(w, "Failed GetPost", http.StatusInternalServerError)
+		helper.WriteError(w, err, "Failed to find user")
+		return
 	}
 
 	err = helper.WriteResponse(w, user)
 	if err != nil {
-		helper.WriteErrorResponse(w, "Failed WriteResponse", http.StatusInternalServerError)
+		helper.WriteError(w, err, "Failed WriteResponse")
 	}
 }
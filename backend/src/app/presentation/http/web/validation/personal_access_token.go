@@ -0,0 +1,57 @@
+package validation
+
+import (
+	"fmt"
+	"net/http"
+	"proto-pulse-plat/auth"
+	"proto-pulse-plat/domain/apperror"
+	"proto-pulse-plat/helper"
+	"strings"
+)
+
+const (
+	maxPersonalAccessTokenNameLength = 100
+	maxPersonalAccessTokenDays       = 365
+)
+
+type PersonalAccessTokenInput struct {
+	Name   string   `json:"name"`
+	Scopes []string `json:"scopes"`
+	// 0 の場合は無期限
+	ExpiresInDays int `json:"expires_in_days"`
+}
+
+func ValidatePersonalAccessTokenInputs(r *http.Request) (*PersonalAccessTokenInput, error) {
+	var input PersonalAccessTokenInput
+	if err := helper.DecodeJSONBody(r, &input); err != nil {
+		return nil, err
+	}
+
+	input.Name = strings.TrimSpace(input.Name)
+	if input.Name == "" {
+		return nil, apperror.InvalidFields(apperror.Field("name", "name is required"))
+	}
+	if len([]rune(input.Name)) > maxPersonalAccessTokenNameLength {
+		return nil, apperror.InvalidFields(apperror.Field("name", fmt.Sprintf("name must be at most %d characters", maxPersonalAccessTokenNameLength)))
+	}
+
+	if len(input.Scopes) == 0 {
+		return nil, apperror.InvalidFields(apperror.Field("scopes", "scopes is required"))
+	}
+	seen := make(map[string]bool, len(input.Scopes))
+	for _, scope := range input.Scopes {
+		if !auth.IsValidScope(scope) {
+			return nil, apperror.InvalidFields(apperror.Field("scopes", fmt.Sprintf("scope %q is not supported", scope)))
+		}
+		if seen[scope] {
+			return nil, apperror.InvalidFields(apperror.Field("scopes", fmt.Sprintf("scope %q is duplicated", scope)))
+		}
+		seen[scope] = true
+	}
+
+	if input.ExpiresInDays < 0 || input.ExpiresInDays > maxPersonalAccessTokenDays {
+		return nil, apperror.InvalidFields(apperror.Field("expires_in_days", fmt.Sprintf("expires_in_days must be between 0 and %d", maxPersonalAccessTokenDays)))
+	}
+
+	return &input, nil
+}
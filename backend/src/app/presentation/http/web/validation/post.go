@@ -4,33 +4,235 @@ import (
 	"fmt"
 	"mime/multipart"
 	"net/http"
+	"sort"
+	"strconv"
 )
 
-func ValidateFormInputs(r *http.Request) (string, string, string, string, []*multipart.FileHeader, error) {
-	title := r.FormValue("title")
-	if title == "" {
-		return "", "", "", "", nil, fmt.Errorf("Title field is required")
+const (
+	// posts の title, content_title, location と post_images の file_name は varchar(255)
+	MaxPostTitleLength        = 255
+	MaxPostContentLength      = 10000
+	MaxPostContentTitleLength = 255
+	MaxPostLocationLength     = 255
+	MaxPostImageFileNameSize  = 255
+	MaxPostImages             = 10
+	MaxPostImageSize          = 10 << 20 // 10 MB
+	MaxPostImageAltTextLength = 255
+	MaxPostImageCaptionLength = 1000
+)
+
+type PostInput struct {
+	Title        string
+	Content      string
+	ContentTitle string
+	Location     string
+	Images       []NewPostImageInput
+}
+
+// 追加する画像。alt_texts[], captions[], sort_orders[] は files[] と同じ順で1件ずつ送る
+type NewPostImageInput struct {
+	File      *multipart.FileHeader
+	AltText   string
+	Caption   string
+	SortOrder int
+	// cover_index で指定された画像
+	IsCover bool
+}
+
+// 既存の画像の説明の変更。image_alt_texts[], image_captions[] は image_ids[] と同じ順で送る。
+// 送られなかった項目は nil で、既存の値を変更しない
+type PostImageDescriptionInput struct {
+	ImageID uint
+	AltText *string
+	Caption *string
+}
+
+func postImageRules(minCount int) []FilesRule {
+	return []FilesRule{
+		MinCount(minCount),
+		MaxCount(MaxPostImages),
+		MaxFileSize(MaxPostImageSize),
+		MaxFileNameLength(MaxPostImageFileNameSize),
+	}
+}
+
+func ValidateFormInputs(r *http.Request) (*PostInput, error) {
+	v := NewValidator()
+
+	input := &PostInput{
+		Title:        v.String("title", r.FormValue("title"), Required(), MaxLength(MaxPostTitleLength), PrintableText(false)),
+		Content:      v.String("content", r.FormValue("content"), Required(), MaxLength(MaxPostContentLength), PrintableText(true)),
+		ContentTitle: v.String("content_title", r.FormValue("content_title"), Required(), MaxLength(MaxPostContentTitleLength), PrintableText(false)),
+		Location:     v.String("location", r.FormValue("location"), Required(), MaxLength(MaxPostLocationLength), PrintableText(false)),
 	}
+	files := r.MultipartForm.File["files[]"]
+	v.Files("files[]", files, postImageRules(1)...)
+	input.Images = newPostImages(v, r, files)
 
-	content := r.FormValue("content")
-	if content == "" {
-		return "", "", "", "", nil, fmt.Errorf("Content field is required")
+	if err := v.Err(); err != nil {
+		return nil, err
 	}
+	return input, nil
+}
+
+type PostUpdateInput struct {
+	PostID         int
+	Title          string
+	Content        string
+	ContentTitle   string
+	Location       string
+	Images         []NewPostImageInput
+	DeleteImageIDs []uint
+	ImageOrder     []uint
+	Descriptions   []PostImageDescriptionInput
+	// 既存の画像をカバー画像にする場合のみ 0 以外
+	CoverImageID uint
+}
+
+// 空のテキスト項目は更新しない。画像の合計枚数は既存の画像と合わせて usecase で確認する
+func ValidateUpdateFormInputs(r *http.Request) (*PostUpdateInput, error) {
+	v := NewValidator()
 
+	postID, err := strconv.Atoi(r.FormValue("post_id"))
+	if err != nil || postID <= 0 {
+		v.Add("post_id", "post_id is invalid")
+	}
+
+	input := &PostUpdateInput{
+		PostID:       postID,
+		Title:        v.String("title", r.FormValue("title"), MaxLength(MaxPostTitleLength), PrintableText(false)),
+		Content:      v.String("content", r.FormValue("content"), MaxLength(MaxPostContentLength), PrintableText(true)),
+		ContentTitle: v.String("content_title", r.FormValue("content_title"), MaxLength(MaxPostContentTitleLength), PrintableText(false)),
+		Location:     v.String("location", r.FormValue("location"), MaxLength(MaxPostLocationLength), PrintableText(false)),
+	}
 	files := r.MultipartForm.File["files[]"]
-	if len(files) == 0 {
-		return "", "", "", "", nil, fmt.Errorf("Files[] field is required")
+	v.Files("files[]", files, postImageRules(0)...)
+	input.Images = newPostImages(v, r, files)
+
+	input.DeleteImageIDs, err = parseIDs(r.MultipartForm.Value["delete_image_ids[]"])
+	if err != nil {
+		v.Add("delete_image_ids[]", err.Error())
+	}
+
+	input.ImageOrder, err = parseIDs(r.MultipartForm.Value["image_order[]"])
+	if err != nil {
+		v.Add("image_order[]", err.Error())
+	}
+
+	input.Descriptions = postImageDescriptions(v, r)
+
+	if coverImageID := r.FormValue("cover_image_id"); coverImageID != "" {
+		id, err := strconv.ParseUint(coverImageID, 10, 64)
+		if err != nil || id == 0 {
+			v.Add("cover_image_id", "cover_image_id is invalid")
+		}
+		if r.FormValue("cover_index") != "" {
+			v.Add("cover_image_id", "cover_image_id and cover_index cannot be specified together")
+		}
+		input.CoverImageID = uint(id)
+	}
+
+	if err := v.Err(); err != nil {
+		return nil, err
+	}
+	return input, nil
+}
+
+func parseIDs(values []string) ([]uint, error) {
+	ids := make([]uint, 0, len(values))
+	seen := make(map[uint]bool, len(values))
+	for _, value := range values {
+		id, err := strconv.ParseUint(value, 10, 64)
+		if err != nil || id == 0 {
+			return nil, fmt.Errorf("%q is not a valid id", value)
+		}
+		if seen[uint(id)] {
+			return nil, fmt.Errorf("id %d is duplicated", id)
+		}
+		seen[uint(id)] = true
+		ids = append(ids, uint(id))
+	}
+	return ids, nil
+}
+
+// files[] に説明、並び順、カバー画像の指定を付け、sort_orders[] の順 (同じ値はアップロード順) に並べて返す
+func newPostImages(v *Validator, r *http.Request, files []*multipart.FileHeader) []NewPostImageInput {
+	altTexts := parallelValues(v, r, "alt_texts[]", "files[]", len(files))
+	captions := parallelValues(v, r, "captions[]", "files[]", len(files))
+	sortOrders := parallelValues(v, r, "sort_orders[]", "files[]", len(files))
+
+	images := make([]NewPostImageInput, 0, len(files))
+	for i, file := range files {
+		image := NewPostImageInput{File: file, SortOrder: i}
+		if altTexts != nil {
+			image.AltText = v.String("alt_texts[]", altTexts[i], MaxLength(MaxPostImageAltTextLength), PrintableText(false))
+		}
+		if captions != nil {
+			image.Caption = v.String("captions[]", captions[i], MaxLength(MaxPostImageCaptionLength), PrintableText(true))
+		}
+		if sortOrders != nil {
+			sortOrder, err := strconv.Atoi(sortOrders[i])
+			if err != nil || sortOrder < 0 {
+				v.Add("sort_orders[]", fmt.Sprintf("%q is not a valid sort order", sortOrders[i]))
+			}
+			image.SortOrder = sortOrder
+		}
+		images = append(images, image)
+	}
+
+	if coverIndex := r.FormValue("cover_index"); coverIndex != "" {
+		index, err := strconv.Atoi(coverIndex)
+		if err != nil || index < 0 || index >= len(images) {
+			v.Add("cover_index", "cover_index must point to one of files[]")
+		} else {
+			images[index].IsCover = true
+		}
 	}
 
-	contentTitle := r.FormValue("content_title")
-	if content == "" {
-		return "", "", "", "", nil, fmt.Errorf("content_title field is required")
+	sort.SliceStable(images, func(i, j int) bool {
+		return images[i].SortOrder < images[j].SortOrder
+	})
+	return images
+}
+
+func postImageDescriptions(v *Validator, r *http.Request) []PostImageDescriptionInput {
+	imageIDs, err := parseIDs(r.MultipartForm.Value["image_ids[]"])
+	if err != nil {
+		v.Add("image_ids[]", err.Error())
+		return nil
 	}
 
-	location := r.FormValue("location")
-	if location == "" {
-		return "", "", "", "", nil, fmt.Errorf("location field is required")
+	altTexts := parallelValues(v, r, "image_alt_texts[]", "image_ids[]", len(imageIDs))
+	captions := parallelValues(v, r, "image_captions[]", "image_ids[]", len(imageIDs))
+	if altTexts == nil && captions == nil {
+		return nil
 	}
 
-	return title, content, contentTitle, location, files, nil
+	descriptions := make([]PostImageDescriptionInput, 0, len(imageIDs))
+	for i, imageID := range imageIDs {
+		description := PostImageDescriptionInput{ImageID: imageID}
+		if altTexts != nil {
+			altText := v.String("image_alt_texts[]", altTexts[i], MaxLength(MaxPostImageAltTextLength), PrintableText(false))
+			description.AltText = &altText
+		}
+		if captions != nil {
+			caption := v.String("image_captions[]", captions[i], MaxLength(MaxPostImageCaptionLength), PrintableText(true))
+			description.Caption = &caption
+		}
+		descriptions = append(descriptions, description)
+	}
+	return descriptions
+}
+
+// 省略された場合は nil を返す。送る場合は base と同じ件数が必要
+func parallelValues(v *Validator, r *http.Request, field, base string, count int) []string {
+	values := r.MultipartForm.Value[field]
+	if len(values) == 0 {
+		return nil
+	}
+	if len(values) != count {
+		v.Add(field, fmt.Sprintf("%s must have one value for each of %s", field, base))
+		return nil
+	}
+	return values
 }
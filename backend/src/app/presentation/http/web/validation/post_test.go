@@ -0,0 +1,265 @@
+package validation
+
+import (
+	"bytes"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+type testFile struct {
+	name string
+	size int
+}
+
+// values と files[] を multipart で送るリクエストを作り、ハンドラーと同じく解析しておく
+func multipartRequest(t *testing.T, values map[string][]string, files ...testFile) *http.Request {
+	t.Helper()
+
+	var body bytes.Buffer
+	writer := multipart.NewWriter(&body)
+	for field, fieldValues := range values {
+		for _, value := range fieldValues {
+			if err := writer.WriteField(field, value); err != nil {
+				t.Fatalf("WriteField: %v", err)
+			}
+		}
+	}
+	for _, file := range files {
+		part, err := writer.CreateFormFile("files[]", file.name)
+		if err != nil {
+			t.Fatalf("CreateFormFile: %v", err)
+		}
+		if _, err := part.Write(make([]byte, file.size)); err != nil {
+			t.Fatalf("write file: %v", err)
+		}
+	}
+	if err := writer.Close(); err != nil {
+		t.Fatalf("Close: %v", err)
+	}
+
+	r := httptest.NewRequest(http.MethodPost, "/", &body)
+	r.Header.Set("Content-Type", writer.FormDataContentType())
+	if err := r.ParseMultipartForm(32 << 20); err != nil {
+		t.Fatalf("ParseMultipartForm: %v", err)
+	}
+	return r
+}
+
+func repeatFiles(file testFile, count int) []testFile {
+	files := make([]testFile, count)
+	for i := range files {
+		files[i] = file
+	}
+	return files
+}
+
+func optionalString(value *string) string {
+	if value == nil {
+		return "<nil>"
+	}
+	return "\"" + *value + "\""
+}
+
+func validPostValues() map[string][]string {
+	return map[string][]string{
+		"title":         {"title"},
+		"content":       {"content"},
+		"content_title": {"content title"},
+		"location":      {"location"},
+	}
+}
+
+func TestValidateFormInputs(t *testing.T) {
+	image := testFile{name: "image.png", size: 1}
+
+	tests := []struct {
+		name       string
+		values     map[string][]string
+		files      []testFile
+		wantFields []string
+	}{
+		{name: "valid", files: []testFile{image}},
+		{
+			name:       "all violations at once",
+			values:     map[string][]string{"title": {" "}, "content": {""}, "content_title": {"a\x00"}, "location": {strings.Repeat("a", 256)}},
+			wantFields: []string{"title", "content", "content_title", "location", "files[]"},
+		},
+		// content が入っていても content_title は content_title として検証する
+		{name: "content title checked on its own", values: map[string][]string{"content_title": {""}}, files: []testFile{image}, wantFields: []string{"content_title"}},
+		{name: "title at varchar limit", values: map[string][]string{"title": {strings.Repeat("あ", MaxPostTitleLength)}}, files: []testFile{image}},
+		{name: "title over varchar limit", values: map[string][]string{"title": {strings.Repeat("あ", MaxPostTitleLength+1)}}, files: []testFile{image}, wantFields: []string{"title"}},
+		{name: "content title over varchar limit", values: map[string][]string{"content_title": {strings.Repeat("a", MaxPostContentTitleLength+1)}}, files: []testFile{image}, wantFields: []string{"content_title"}},
+		{name: "content over limit", values: map[string][]string{"content": {strings.Repeat("a", MaxPostContentLength+1)}}, files: []testFile{image}, wantFields: []string{"content"}},
+		{name: "multiline content", values: map[string][]string{"content": {"line 1\nline 2"}}, files: []testFile{image}},
+		{name: "newline in title", values: map[string][]string{"title": {"a\nb"}}, files: []testFile{image}, wantFields: []string{"title"}},
+		{name: "max images", files: repeatFiles(image, MaxPostImages)},
+		{name: "too many images", files: repeatFiles(image, MaxPostImages+1), wantFields: []string{"files[]"}},
+		{
+			name:       "file over size limit",
+			files:      []testFile{image, {name: "large.png", size: MaxPostImageSize + 1}},
+			wantFields: []string{"files[]"},
+		},
+		{name: "file name over limit", files: []testFile{{name: strings.Repeat("a", MaxPostImageFileNameSize) + ".png", size: 1}}, wantFields: []string{"files[]"}},
+		{name: "parallel arrays", values: map[string][]string{"alt_texts[]": {"a", "b"}, "captions[]": {"c", "d"}, "sort_orders[]": {"1", "0"}}, files: repeatFiles(image, 2)},
+		{name: "mismatched alt texts", values: map[string][]string{"alt_texts[]": {"a"}}, files: repeatFiles(image, 2), wantFields: []string{"alt_texts[]"}},
+		{
+			name:       "mismatched captions and sort orders",
+			values:     map[string][]string{"captions[]": {"a", "b", "c"}, "sort_orders[]": {"0"}},
+			files:      repeatFiles(image, 2),
+			wantFields: []string{"captions[]", "sort_orders[]"},
+		},
+		{name: "invalid sort order", values: map[string][]string{"sort_orders[]": {"-1"}}, files: []testFile{image}, wantFields: []string{"sort_orders[]"}},
+		{name: "cover index out of range", values: map[string][]string{"cover_index": {"1"}}, files: []testFile{image}, wantFields: []string{"cover_index"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			values := validPostValues()
+			for field, fieldValues := range tt.values {
+				values[field] = fieldValues
+			}
+
+			_, err := ValidateFormInputs(multipartRequest(t, values, tt.files...))
+			if got := violatedFields(t, err); !reflect.DeepEqual(got, tt.wantFields) {
+				t.Errorf("violated fields = %v, want %v", got, tt.wantFields)
+			}
+		})
+	}
+}
+
+func TestValidateFormInputsNormalizesText(t *testing.T) {
+	values := validPostValues()
+	values["title"] = []string{"  Cafe\u0301  "}
+	values["alt_texts[]"] = []string{" a\u0301 "}
+
+	input, err := ValidateFormInputs(multipartRequest(t, values, testFile{name: "image.png", size: 1}))
+	if err != nil {
+		t.Fatalf("ValidateFormInputs: %v", err)
+	}
+	if input.Title != "Caf\u00e9" {
+		t.Errorf("Title = %q, want %q", input.Title, "Caf\u00e9")
+	}
+	if input.Images[0].AltText != "\u00e1" {
+		t.Errorf("AltText = %q, want %q", input.Images[0].AltText, "\u00e1")
+	}
+}
+
+func TestValidateFormInputsOrdersImages(t *testing.T) {
+	values := validPostValues()
+	values["sort_orders[]"] = []string{"2", "0", "1"}
+	values["cover_index"] = []string{"0"}
+
+	input, err := ValidateFormInputs(multipartRequest(t, values,
+		testFile{name: "a.png", size: 1}, testFile{name: "b.png", size: 1}, testFile{name: "c.png", size: 1}))
+	if err != nil {
+		t.Fatalf("ValidateFormInputs: %v", err)
+	}
+
+	var names []string
+	for _, image := range input.Images {
+		names = append(names, image.File.Filename)
+	}
+	if want := []string{"b.png", "c.png", "a.png"}; !reflect.DeepEqual(names, want) {
+		t.Errorf("images = %v, want %v", names, want)
+	}
+	if !input.Images[2].IsCover {
+		t.Error("cover_index should follow its file after sorting")
+	}
+}
+
+func TestValidateUpdateFormInputs(t *testing.T) {
+	tests := []struct {
+		name       string
+		values     map[string][]string
+		wantFields []string
+	}{
+		// 更新では空のテキスト項目は変更しない
+		{name: "empty text fields", values: map[string][]string{"post_id": {"1"}}},
+		{name: "missing post id", values: map[string][]string{}, wantFields: []string{"post_id"}},
+		{
+			name:       "all violations at once",
+			values:     map[string][]string{"post_id": {"x"}, "title": {strings.Repeat("a", 256)}, "delete_image_ids[]": {"0"}, "image_order[]": {"1", "1"}},
+			wantFields: []string{"post_id", "title", "delete_image_ids[]", "image_order[]"},
+		},
+		{name: "mismatched alt texts", values: map[string][]string{"post_id": {"1"}, "image_ids[]": {"1", "2"}, "image_alt_texts[]": {"a"}}, wantFields: []string{"image_alt_texts[]"}},
+		{name: "mismatched captions", values: map[string][]string{"post_id": {"1"}, "image_ids[]": {"1"}, "image_captions[]": {"a", "b"}}, wantFields: []string{"image_captions[]"}},
+		{name: "alt text over limit", values: map[string][]string{"post_id": {"1"}, "image_ids[]": {"1"}, "image_alt_texts[]": {strings.Repeat("a", MaxPostImageAltTextLength+1)}}, wantFields: []string{"image_alt_texts[]"}},
+		{name: "cover image and cover index", values: map[string][]string{"post_id": {"1"}, "cover_image_id": {"1"}, "cover_index": {"0"}}, wantFields: []string{"cover_index", "cover_image_id"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := ValidateUpdateFormInputs(multipartRequest(t, tt.values))
+			if got := violatedFields(t, err); !reflect.DeepEqual(got, tt.wantFields) {
+				t.Errorf("violated fields = %v, want %v", got, tt.wantFields)
+			}
+		})
+	}
+}
+
+func TestValidateUpdateFormInputsPartialDescriptions(t *testing.T) {
+	tests := []struct {
+		name        string
+		values      map[string][]string
+		wantAlt     []string
+		wantCaption []string
+	}{
+		{
+			name:        "alt texts only",
+			values:      map[string][]string{"image_alt_texts[]": {"a cat", ""}},
+			wantAlt:     []string{`"a cat"`, `""`},
+			wantCaption: []string{"<nil>", "<nil>"},
+		},
+		{
+			name:        "captions only",
+			values:      map[string][]string{"image_captions[]": {"first", "second"}},
+			wantAlt:     []string{"<nil>", "<nil>"},
+			wantCaption: []string{`"first"`, `"second"`},
+		},
+		{
+			name:        "both",
+			values:      map[string][]string{"image_alt_texts[]": {"a", "b"}, "image_captions[]": {"c", "d"}},
+			wantAlt:     []string{`"a"`, `"b"`},
+			wantCaption: []string{`"c"`, `"d"`},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			values := map[string][]string{"post_id": {"1"}, "image_ids[]": {"10", "11"}}
+			for field, fieldValues := range tt.values {
+				values[field] = fieldValues
+			}
+
+			input, err := ValidateUpdateFormInputs(multipartRequest(t, values))
+			if err != nil {
+				t.Fatalf("ValidateUpdateFormInputs: %v", err)
+			}
+			if len(input.Descriptions) != 2 {
+				t.Fatalf("got %d descriptions, want 2", len(input.Descriptions))
+			}
+			for i, description := range input.Descriptions {
+				if got := optionalString(description.AltText); got != tt.wantAlt[i] {
+					t.Errorf("Descriptions[%d].AltText = %s, want %s", i, got, tt.wantAlt[i])
+				}
+				if got := optionalString(description.Caption); got != tt.wantCaption[i] {
+					t.Errorf("Descriptions[%d].Caption = %s, want %s", i, got, tt.wantCaption[i])
+				}
+			}
+		})
+	}
+}
+
+func TestValidateUpdateFormInputsWithoutDescriptions(t *testing.T) {
+	input, err := ValidateUpdateFormInputs(multipartRequest(t, map[string][]string{
+		"post_id":     {"1"},
+		"image_ids[]": {"10"},
+	}))
+	if err != nil {
+		t.Fatalf("ValidateUpdateFormInputs: %v", err)
+	}
+	if len(input.Descriptions) != 0 {
+		t.Errorf("got %d descriptions, want none", len(input.Descriptions))
+	}
+}
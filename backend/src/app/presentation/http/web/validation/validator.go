@@ -0,0 +1,143 @@
+package validation
+
+import (
+	"fmt"
+	"mime/multipart"
+	"proto-pulse-plat/domain/apperror"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"golang.org/x/text/unicode/norm"
+)
+
+// 文字列の検証ルール。違反している場合はメッセージを返す
+type StringRule func(value string) string
+
+// アップロードファイルの検証ルール
+type FilesRule func(files []*multipart.FileHeader) []string
+
+// 項目ごとにルールを適用し、すべての違反をまとめて返す
+type Validator struct {
+	violations []apperror.FieldViolation
+}
+
+func NewValidator() *Validator {
+	return &Validator{}
+}
+
+// 前後の空白を除いて NFC に正規化した値にルールを適用し、正規化した値を返す
+func (v *Validator) String(field, value string, rules ...StringRule) string {
+	normalized := NormalizeText(value)
+	for _, rule := range rules {
+		if message := rule(normalized); message != "" {
+			v.Add(field, field+" "+message)
+			// 必須違反の場合などに同じ項目のエラーを重ねない
+			break
+		}
+	}
+	return normalized
+}
+
+func (v *Validator) Files(field string, files []*multipart.FileHeader, rules ...FilesRule) {
+	for _, rule := range rules {
+		for _, message := range rule(files) {
+			v.Add(field, field+" "+message)
+		}
+	}
+}
+
+func (v *Validator) Add(field, message string) {
+	v.violations = append(v.violations, apperror.Field(field, message))
+}
+
+func (v *Validator) Err() error {
+	if len(v.violations) == 0 {
+		return nil
+	}
+	return apperror.InvalidFields(v.violations...)
+}
+
+func NormalizeText(value string) string {
+	return norm.NFC.String(strings.TrimSpace(value))
+}
+
+func Required() StringRule {
+	return func(value string) string {
+		if value == "" {
+			return "is required"
+		}
+		return ""
+	}
+}
+
+// 文字数 (rune 数) の上限
+func MaxLength(max int) StringRule {
+	return func(value string) string {
+		if utf8.RuneCountInString(value) > max {
+			return fmt.Sprintf("must be at most %d characters", max)
+		}
+		return ""
+	}
+}
+
+// 改行とタブ以外の制御文字を拒否する。multiline が false の場合は改行も拒否する
+func PrintableText(multiline bool) StringRule {
+	return func(value string) string {
+		if !utf8.ValidString(value) {
+			return "must be valid UTF-8"
+		}
+		for _, r := range value {
+			if multiline && (r == '\n' || r == '\r' || r == '\t') {
+				continue
+			}
+			if unicode.IsControl(r) {
+				return "must not contain control characters"
+			}
+		}
+		return ""
+	}
+}
+
+func MinCount(min int) FilesRule {
+	return func(files []*multipart.FileHeader) []string {
+		if len(files) < min {
+			return []string{fmt.Sprintf("must contain at least %d files", min)}
+		}
+		return nil
+	}
+}
+
+func MaxCount(max int) FilesRule {
+	return func(files []*multipart.FileHeader) []string {
+		if len(files) > max {
+			return []string{fmt.Sprintf("must contain at most %d files", max)}
+		}
+		return nil
+	}
+}
+
+// 1ファイルあたりのサイズ上限 (バイト)
+func MaxFileSize(max int64) FilesRule {
+	return func(files []*multipart.FileHeader) []string {
+		var messages []string
+		for _, file := range files {
+			if file.Size > max {
+				messages = append(messages, fmt.Sprintf("%q must be at most %d MB", file.Filename, max>>20))
+			}
+		}
+		return messages
+	}
+}
+
+func MaxFileNameLength(max int) FilesRule {
+	return func(files []*multipart.FileHeader) []string {
+		var messages []string
+		for _, file := range files {
+			if utf8.RuneCountInString(file.Filename) > max {
+				messages = append(messages, fmt.Sprintf("file names must be at most %d characters", max))
+			}
+		}
+		return messages
+	}
+}
@@ -0,0 +1,188 @@
+package validation
+
+import (
+	"mime/multipart"
+	"reflect"
+	"strings"
+	"testing"
+
+	"proto-pulse-plat/domain/apperror"
+)
+
+// err の項目ごとのエラーを項目名の一覧にする
+func violatedFields(t *testing.T, err error) []string {
+	t.Helper()
+
+	if err == nil {
+		return nil
+	}
+	appErr, ok := apperror.As(err)
+	if !ok || appErr.Code != apperror.CodeInvalidArgument {
+		t.Fatalf("error = %v, want invalid_argument", err)
+	}
+	fields := make([]string, 0, len(appErr.Details))
+	for _, violation := range appErr.Details {
+		fields = append(fields, violation.Field)
+	}
+	return fields
+}
+
+func TestNormalizeText(t *testing.T) {
+	tests := []struct {
+		name  string
+		value string
+		want  string
+	}{
+		{name: "trims spaces", value: "  title \t\n", want: "title"},
+		{name: "trims full-width spaces", value: "　タイトル　", want: "タイトル"},
+		{name: "composes combining marks", value: "Cafe\u0301", want: "Caf\u00e9"},
+		{name: "composes dakuten", value: "\u304b\u3099", want: "\u304c"},
+		{name: "keeps inner spaces", value: " a  b ", want: "a  b"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := NormalizeText(tt.value); got != tt.want {
+				t.Errorf("NormalizeText(%q) = %q, want %q", tt.value, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestValidatorString(t *testing.T) {
+	tests := []struct {
+		name      string
+		value     string
+		rules     []StringRule
+		want      string
+		wantValid bool
+	}{
+		{name: "normalized value is returned", value: " Cafe\u0301 ", rules: []StringRule{Required()}, want: "Caf\u00e9", wantValid: true},
+		{name: "blank is missing", value: " \t ", rules: []StringRule{Required()}, want: "", wantValid: false},
+		{name: "length after trimming", value: "  abc  ", rules: []StringRule{MaxLength(3)}, want: "abc", wantValid: true},
+		// 結合文字は正規化してから数える
+		{name: "length after normalization", value: "e\u0301e\u0301e\u0301", rules: []StringRule{MaxLength(3)}, want: "\u00e9\u00e9\u00e9", wantValid: true},
+		{name: "too long", value: "abcd", rules: []StringRule{MaxLength(3)}, want: "abcd", wantValid: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			v := NewValidator()
+			if got := v.String("field", tt.value, tt.rules...); got != tt.want {
+				t.Errorf("String() = %q, want %q", got, tt.want)
+			}
+			if err := v.Err(); (err == nil) != tt.wantValid {
+				t.Errorf("Err() = %v, want valid %v", err, tt.wantValid)
+			}
+		})
+	}
+}
+
+func TestValidatorStringStopsAtFirstViolation(t *testing.T) {
+	v := NewValidator()
+	v.String("title", "", Required(), MaxLength(0), PrintableText(false))
+
+	if got := violatedFields(t, v.Err()); !reflect.DeepEqual(got, []string{"title"}) {
+		t.Errorf("violated fields = %v, want [title]", got)
+	}
+}
+
+func TestValidatorCollectsAllViolations(t *testing.T) {
+	v := NewValidator()
+	v.String("title", "", Required())
+	v.String("content", "ok", Required())
+	v.String("location", strings.Repeat("a", 4), MaxLength(3))
+	v.Files("files[]", []*multipart.FileHeader{{Filename: "a.png", Size: 2}, {Filename: "b.png", Size: 3}}, MaxFileSize(1))
+
+	err := v.Err()
+	want := []string{"title", "location", "files[]", "files[]"}
+	if got := violatedFields(t, err); !reflect.DeepEqual(got, want) {
+		t.Errorf("violated fields = %v, want %v", got, want)
+	}
+
+	appErr, _ := apperror.As(err)
+	if message := appErr.Details[0].Message; message != "title is required" {
+		t.Errorf("message = %q, want the field name in it", message)
+	}
+}
+
+func TestMaxLength(t *testing.T) {
+	tests := []struct {
+		name      string
+		value     string
+		max       int
+		wantValid bool
+	}{
+		{name: "ascii at limit", value: strings.Repeat("a", 255), max: 255, wantValid: true},
+		{name: "ascii over limit", value: strings.Repeat("a", 256), max: 255, wantValid: false},
+		// varchar(255) は文字数で数えるため、マルチバイト文字も 255 文字まで入る
+		{name: "multibyte at limit", value: strings.Repeat("あ", 255), max: 255, wantValid: true},
+		{name: "multibyte over limit", value: strings.Repeat("あ", 256), max: 255, wantValid: false},
+		{name: "empty", value: "", max: 255, wantValid: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if message := MaxLength(tt.max)(tt.value); (message == "") != tt.wantValid {
+				t.Errorf("MaxLength(%d) message = %q, want valid %v", tt.max, message, tt.wantValid)
+			}
+		})
+	}
+}
+
+func TestPrintableText(t *testing.T) {
+	tests := []struct {
+		name      string
+		value     string
+		multiline bool
+		wantValid bool
+	}{
+		{name: "plain text", value: "Hello, 世界 🌏", wantValid: true},
+		{name: "newline in single line", value: "a\nb", wantValid: false},
+		{name: "tab in single line", value: "a\tb", wantValid: false},
+		{name: "newline in multiline", value: "a\r\nb", multiline: true, wantValid: true},
+		{name: "tab in multiline", value: "a\tb", multiline: true, wantValid: true},
+		{name: "null byte", value: "a\x00b", multiline: true, wantValid: false},
+		{name: "escape", value: "a\x1b[31mb", multiline: true, wantValid: false},
+		{name: "delete", value: "a\x7fb", multiline: true, wantValid: false},
+		{name: "c1 control", value: "a\u0085b", multiline: true, wantValid: false},
+		{name: "invalid utf-8", value: "a\xffb", multiline: true, wantValid: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if message := PrintableText(tt.multiline)(tt.value); (message == "") != tt.wantValid {
+				t.Errorf("PrintableText(%v)(%q) message = %q, want valid %v", tt.multiline, tt.value, message, tt.wantValid)
+			}
+		})
+	}
+}
+
+func TestFilesRules(t *testing.T) {
+	files := func(sizes ...int64) []*multipart.FileHeader {
+		headers := make([]*multipart.FileHeader, 0, len(sizes))
+		for _, size := range sizes {
+			headers = append(headers, &multipart.FileHeader{Filename: "image.png", Size: size})
+		}
+		return headers
+	}
+
+	tests := []struct {
+		name         string
+		rule         FilesRule
+		files        []*multipart.FileHeader
+		wantMessages int
+	}{
+		{name: "min count met", rule: MinCount(1), files: files(1), wantMessages: 0},
+		{name: "min count not met", rule: MinCount(1), files: nil, wantMessages: 1},
+		{name: "max count met", rule: MaxCount(2), files: files(1, 1), wantMessages: 0},
+		{name: "max count exceeded", rule: MaxCount(2), files: files(1, 1, 1), wantMessages: 1},
+		{name: "file size at limit", rule: MaxFileSize(10), files: files(10), wantMessages: 0},
+		// 上限を超えたファイルごとにエラーを返す
+		{name: "file sizes over limit", rule: MaxFileSize(10), files: files(11, 10, 12), wantMessages: 2},
+		{name: "file name too long", rule: MaxFileNameLength(5), files: files(1), wantMessages: 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if messages := tt.rule(tt.files); len(messages) != tt.wantMessages {
+				t.Errorf("got messages %q, want %d", messages, tt.wantMessages)
+			}
+		})
+	}
+}
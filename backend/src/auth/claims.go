@@ -0,0 +1,91 @@
+package auth
+
+import (
+	"fmt"
+	"proto-pulse-plat/config"
+
+	"github.com/golang-jwt/jwt/v4"
+)
+
+// auth_token に含めるクレーム。jti にはセッションIDが入る
+type Claims struct {
+	UserID     uint   `json:"id"`
+	Name       string `json:"name"`
+	ScreenName string `json:"screen_name"`
+	jwt.RegisteredClaims
+}
+
+func (c *Claims) Principal() *Principal {
+	return &Principal{
+		UserID:     c.UserID,
+		SessionID:  c.ID,
+		Name:       c.Name,
+		ScreenName: c.ScreenName,
+		AuthMethod: AuthMethodSession,
+	}
+}
+
+// アクティブな鍵でクレームに署名し、kid ヘッダー付きのトークン文字列を返す
+func SignToken(keyRing *config.KeyRing, claims *Claims) (string, error) {
+	key := keyRing.Active()
+
+	method, err := signingMethod(key.Algorithm)
+	if err != nil {
+		return "", err
+	}
+
+	token := jwt.NewWithClaims(method, claims)
+	token.Header["kid"] = key.Kid
+
+	return token.SignedString(key.PrivateKey)
+}
+
+// kid に対応する鍵で署名と有効期限を検証してクレームを返す
+func ParseToken(keyRing *config.KeyRing, tokenString string) (*Claims, error) {
+	claims := &Claims{}
+	token, err := jwt.ParseWithClaims(tokenString, claims, func(t *jwt.Token) (interface{}, error) {
+		// kid の無いトークンは JWT_SECRET_KEY で署名されたものとして扱う
+		kid, _ := t.Header["kid"].(string)
+		if kid == "" {
+			kid = config.DefaultKid
+		}
+
+		key, ok := keyRing.Lookup(kid)
+		if !ok {
+			return nil, fmt.Errorf("unknown kid: %q", kid)
+		}
+
+		// 鍵に登録されたアルゴリズム以外は受け付けない
+		if t.Method.Alg() != key.Algorithm {
+			return nil, fmt.Errorf("unexpected signing method: %v", t.Header["alg"])
+		}
+		return key.PublicKey, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	if !token.Valid {
+		return nil, fmt.Errorf("invalid token")
+	}
+	if claims.UserID == 0 {
+		return nil, fmt.Errorf("token has no user id")
+	}
+	if claims.ID == "" {
+		return nil, fmt.Errorf("token has no session id")
+	}
+
+	return claims, nil
+}
+
+func signingMethod(algorithm string) (jwt.SigningMethod, error) {
+	switch algorithm {
+	case config.AlgorithmHS256:
+		return jwt.SigningMethodHS256, nil
+	case config.AlgorithmRS256:
+		return jwt.SigningMethodRS256, nil
+	case config.AlgorithmEdDSA:
+		return jwt.SigningMethodEdDSA, nil
+	default:
+		return nil, fmt.Errorf("unsupported signing algorithm: %s", algorithm)
+	}
+}
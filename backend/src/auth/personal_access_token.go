@@ -0,0 +1,32 @@
+package auth
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"strings"
+)
+
+const (
+	// パーソナルアクセストークンの接頭辞。漏洩時にシークレットスキャナーで検出しやすくする
+	PersonalAccessTokenPrefix = "ppp_"
+	// 一覧で見分けられるよう保存する、平文の先頭の長さ
+	personalAccessTokenDisplayLength = len(PersonalAccessTokenPrefix) + 6
+)
+
+// パーソナルアクセストークンを生成し、平文・表示用の先頭部分・DB保存用のハッシュを返す
+func NewPersonalAccessToken() (string, string, string, error) {
+	bytes := make([]byte, 32)
+	if _, err := rand.Read(bytes); err != nil {
+		return "", "", "", err
+	}
+	token := PersonalAccessTokenPrefix + base64.RawURLEncoding.EncodeToString(bytes)
+	return token, token[:personalAccessTokenDisplayLength], HashPersonalAccessToken(token), nil
+}
+
+func HashPersonalAccessToken(token string) string {
+	return HashRefreshToken(token)
+}
+
+func IsPersonalAccessToken(token string) bool {
+	return strings.HasPrefix(token, PersonalAccessTokenPrefix)
+}
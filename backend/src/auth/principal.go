@@ -0,0 +1,51 @@
+package auth
+
+import "context"
+
+const (
+	AuthMethodSession             = "session"
+	AuthMethodPersonalAccessToken = "personal_access_token"
+)
+
+// 検証済みトークンから得たログインユーザー
+type Principal struct {
+	UserID     uint
+	SessionID  string
+	Name       string
+	ScreenName string
+	AuthMethod string
+	// 認証ミドルウェアがリクエストごとに DB から読み込む
+	Role string
+	// パーソナルアクセストークンの場合のみ設定する
+	TokenID uint
+	Scopes  []string
+}
+
+// ブラウザのセッションは全スコープを持つ。パーソナルアクセストークンは付与されたスコープのみ
+func (p *Principal) HasScope(scope string) bool {
+	if p.AuthMethod != AuthMethodPersonalAccessToken {
+		return true
+	}
+	for _, s := range p.Scopes {
+		if s == scope {
+			return true
+		}
+	}
+	return false
+}
+
+func (p *Principal) HasRole(required string) bool {
+	return HasRole(p.Role, required)
+}
+
+type principalKey struct{}
+
+func WithPrincipal(ctx context.Context, principal *Principal) context.Context {
+	return context.WithValue(ctx, principalKey{}, principal)
+}
+
+// 未ログインの場合は false を返す
+func PrincipalFromContext(ctx context.Context) (*Principal, bool) {
+	principal, ok := ctx.Value(principalKey{}).(*Principal)
+	return principal, ok && principal != nil
+}
@@ -0,0 +1,30 @@
+package auth
+
+const (
+	RoleUser      = "user"
+	RoleModerator = "moderator"
+	RoleAdmin     = "admin"
+)
+
+// 上位のロールは下位のロールの権限をすべて持つ
+var roleRanks = map[string]int{
+	RoleUser:      1,
+	RoleModerator: 2,
+	RoleAdmin:     3,
+}
+
+func IsValidRole(role string) bool {
+	_, ok := roleRanks[role]
+	return ok
+}
+
+// role が required 以上の権限を持つか
+func HasRole(role, required string) bool {
+	rank, ok := roleRanks[role]
+	return ok && rank >= roleRanks[required]
+}
+
+// role が other より上位か
+func OutranksRole(role, other string) bool {
+	return roleRanks[role] > roleRanks[other]
+}
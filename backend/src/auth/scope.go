@@ -0,0 +1,21 @@
+package auth
+
+const (
+	ScopePostsRead  = "posts:read"
+	ScopePostsWrite = "posts:write"
+)
+
+// パーソナルアクセストークンに付与できるスコープ
+var Scopes = []string{
+	ScopePostsRead,
+	ScopePostsWrite,
+}
+
+func IsValidScope(scope string) bool {
+	for _, s := range Scopes {
+		if s == scope {
+			return true
+		}
+	}
+	return false
+}
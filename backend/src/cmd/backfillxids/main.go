@@ -0,0 +1,199 @@
+// 外部ID (id_str) 導入前に X で登録され、ログイン方法が紐付いていないユーザーに X の id_str を紐付ける。
+// スクリーンネームは変更・再利用されるため、ログイン時には照合しない。20261018000004 を適用した後に1回だけ実行する。
+// X で見つからないユーザーや、id_str が既に他のユーザーに紐付いているユーザーは紐付けずに残す。
+// 紐付いていないユーザーと同じスクリーンネームでのログインは、重複したアカウントを作らないよう拒否される
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/joho/godotenv"
+	postgres_driver "gorm.io/driver/postgres"
+	"gorm.io/gorm"
+
+	"proto-pulse-plat/config"
+	"proto-pulse-plat/infrastructure/identityprovider"
+	"proto-pulse-plat/infrastructure/mapper"
+	"proto-pulse-plat/infrastructure/persistence/postgres"
+	"proto-pulse-plat/infrastructure/response"
+)
+
+// users/lookup で一度に指定できるユーザー数の上限
+const maxLookupUsers = 100
+
+// users/lookup のレスポンスの上限
+const maxLookupResponseSize = 10 << 20
+
+type legacyUser struct {
+	ID        uint
+	AccountID string
+}
+
+func main() {
+	batchSize := flag.Int("batch", maxLookupUsers, "number of users to look up per request (at most 100)")
+	dryRun := flag.Bool("dry-run", false, "look up users without linking them")
+	flag.Parse()
+
+	if *batchSize <= 0 || *batchSize > maxLookupUsers {
+		log.Fatalf("-batch must be between 1 and %d", maxLookupUsers)
+	}
+
+	env := os.Getenv("APP_ENV")
+	if env == "" {
+		env = "local"
+	}
+
+	if env == "local" {
+		godotenv.Load(".env")
+	} else if env == "production" {
+		godotenv.Load("/etc/secrets/.env")
+	}
+
+	db, err := gorm.Open(postgres_driver.Open(config.GetDSN()), &gorm.Config{})
+	if err != nil {
+		log.Fatalf("failed to connect to database: %v", err)
+	}
+
+	lookupConfig, err := config.LoadXLookupConfig()
+	if err != nil {
+		log.Fatalf("failed to load X lookup config: %v", err)
+	}
+
+	identitiesRepository := postgres.NewGormUserIdentitiesRepository(db)
+	client := &http.Client{Timeout: 30 * time.Second}
+
+	linked, unresolved := 0, 0
+	var lastID uint
+	for {
+		var users []legacyUser
+		result := db.Table("users").
+			Select("id, account_id").
+			Where("id > ?", lastID).
+			Where("NOT EXISTS (SELECT 1 FROM user_identities WHERE user_identities.user_id = users.id)").
+			Order("id").
+			Limit(*batchSize).
+			Find(&users)
+		if result.Error != nil {
+			log.Fatalf("failed to load users without identities: %v", result.Error)
+		}
+		if len(users) == 0 {
+			break
+		}
+		lastID = users[len(users)-1].ID
+
+		profiles, err := lookupUsers(client, lookupConfig, users)
+		if err != nil {
+			log.Fatalf("failed to look up users after id %d: %v", lastID, err)
+		}
+
+		for _, user := range users {
+			profile, ok := profiles[strings.ToLower(user.AccountID)]
+			if !ok {
+				log.Printf("user %d: @%s was not found on X, left unlinked", user.ID, user.AccountID)
+				unresolved++
+				continue
+			}
+
+			_, err := identitiesRepository.FindByExternalID(identityprovider.XProviderName, profile.IDStr)
+			if err == nil {
+				log.Printf("user %d: X id %s (@%s) is already linked to another user, left unlinked", user.ID, profile.IDStr, profile.ScreenName)
+				unresolved++
+				continue
+			}
+			if !errors.Is(err, gorm.ErrRecordNotFound) {
+				log.Fatalf("failed to check identity of user %d: %v", user.ID, err)
+			}
+
+			linked++
+			if *dryRun {
+				continue
+			}
+
+			_, err = identitiesRepository.Save(
+				mapper.ToModelUserIdentity(user.ID, identityprovider.XProviderName, profile.IDStr, profile.ScreenName),
+			)
+			if err != nil {
+				log.Fatalf("failed to link user %d to X id %s: %v", user.ID, profile.IDStr, err)
+			}
+		}
+		log.Printf("processed users up to id %d", lastID)
+	}
+
+	if *dryRun {
+		log.Printf("dry run: %d users would be linked, %d left unlinked", linked, unresolved)
+		return
+	}
+	log.Printf("linked %d users to their X id, %d left unlinked", linked, unresolved)
+}
+
+// スクリーンネーム (小文字) をキーにしたプロフィールを返す。見つからないユーザーは含まれない
+func lookupUsers(client *http.Client, lookupConfig *config.XLookupConfig, users []legacyUser) (map[string]response.UserProfile, error) {
+	screenNames := make([]string, 0, len(users))
+	for _, user := range users {
+		if user.AccountID != "" {
+			screenNames = append(screenNames, user.AccountID)
+		}
+	}
+
+	profiles := make(map[string]response.UserProfile)
+	if len(screenNames) == 0 {
+		return profiles, nil
+	}
+
+	lookupURL, err := url.Parse(lookupConfig.UsersLookupURL)
+	if err != nil {
+		return nil, err
+	}
+	query := lookupURL.Query()
+	query.Set("screen_name", strings.Join(screenNames, ","))
+	query.Set("include_entities", "false")
+	lookupURL.RawQuery = query.Encode()
+
+	req, err := http.NewRequest(http.MethodGet, lookupURL.String(), nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Authorization", "Bearer "+lookupConfig.BearerToken)
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	// 指定したユーザーが1人も見つからない場合は 404 が返る
+	if resp.StatusCode == http.StatusNotFound {
+		return profiles, nil
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("users/lookup returned status %d", resp.StatusCode)
+	}
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, maxLookupResponseSize))
+	if err != nil {
+		return nil, err
+	}
+
+	var found []response.UserProfile
+	if err := json.Unmarshal(body, &found); err != nil {
+		return nil, err
+	}
+
+	for _, profile := range found {
+		if profile.IDStr == "" || profile.ScreenName == "" {
+			continue
+		}
+		profiles[strings.ToLower(profile.ScreenName)] = profile
+	}
+	return profiles, nil
+}
@@ -0,0 +1,203 @@
+// post_images.data と users.icon_data に残っている画像を BlobStore にコピーし、DB の列を空にする。
+// アップロード時と同じく、位置情報などのメタデータを取り除いてからコピーする。
+// 20261018000013 まで適用した後に実行し、完了してから 20261018000014 を適用する
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"os"
+
+	"github.com/joho/godotenv"
+	postgres_driver "gorm.io/driver/postgres"
+	"gorm.io/gorm"
+
+	"proto-pulse-plat/config"
+	"proto-pulse-plat/domain/storage"
+	"proto-pulse-plat/helper"
+	"proto-pulse-plat/infrastructure/blobstore"
+	"proto-pulse-plat/infrastructure/imaging"
+)
+
+type legacyPostImage struct {
+	ID       uint
+	FileName string
+	Data     []byte
+	MimeType string
+}
+
+type legacyUserIcon struct {
+	ID           uint
+	IconFileName string
+	IconData     []byte
+}
+
+type migrator struct {
+	db        *gorm.DB
+	blobStore storage.BlobStore
+	batchSize int
+	dryRun    bool
+}
+
+func main() {
+	batchSize := flag.Int("batch", 100, "number of rows to copy per query")
+	dryRun := flag.Bool("dry-run", false, "count rows to copy without writing")
+	flag.Parse()
+
+	env := os.Getenv("APP_ENV")
+	if env == "" {
+		env = "local"
+	}
+
+	if env == "local" {
+		godotenv.Load(".env")
+	} else if env == "production" {
+		godotenv.Load("/etc/secrets/.env")
+	}
+
+	db, err := gorm.Open(postgres_driver.Open(config.GetDSN()), &gorm.Config{})
+	if err != nil {
+		log.Fatalf("failed to connect to database: %v", err)
+	}
+
+	blobStoreConfig, err := config.LoadBlobStoreConfig()
+	if err != nil {
+		log.Fatalf("failed to load blob store config: %v", err)
+	}
+
+	blobStore, err := blobstore.New(blobStoreConfig)
+	if err != nil {
+		log.Fatalf("failed to create blob store: %v", err)
+	}
+
+	m := &migrator{db: db, blobStore: blobStore, batchSize: *batchSize, dryRun: *dryRun}
+
+	postImages, err := m.migratePostImages()
+	if err != nil {
+		log.Fatalf("failed to migrate post images: %v", err)
+	}
+	userIcons, err := m.migrateUserIcons()
+	if err != nil {
+		log.Fatalf("failed to migrate user icons: %v", err)
+	}
+
+	if m.dryRun {
+		log.Printf("dry run: %d post images and %d user icons would be copied", postImages, userIcons)
+		return
+	}
+	log.Printf("copied %d post images and %d user icons to the %s blob store", postImages, userIcons, blobStoreConfig.Driver)
+}
+
+func (m *migrator) migratePostImages() (int, error) {
+	copied := 0
+	var lastID uint
+	for {
+		var rows []legacyPostImage
+		result := m.db.Table("post_images").
+			Select("id, file_name, data, mime_type").
+			Where("data IS NOT NULL AND storage_key IS NULL AND id > ?", lastID).
+			Order("id").
+			Limit(m.batchSize).
+			Find(&rows)
+		if result.Error != nil {
+			return copied, result.Error
+		}
+		if len(rows) == 0 {
+			return copied, nil
+		}
+
+		for _, row := range rows {
+			lastID = row.ID
+			copied++
+			if m.dryRun {
+				continue
+			}
+
+			data, mimeType := sanitize(fmt.Sprintf("post image %d (%q)", row.ID, row.FileName), row.Data, row.MimeType)
+			checksum := helper.ContentHash(data)
+			key := blobstore.ContentKey(blobstore.PostImageNamespace, checksum)
+			if err := m.blobStore.Put(key, data, mimeType); err != nil {
+				return copied, err
+			}
+
+			result := m.db.Table("post_images").Where("id = ?", row.ID).Updates(map[string]any{
+				"storage_key": key,
+				"size":        len(data),
+				"checksum":    checksum,
+				"mime_type":   mimeType,
+				"data":        nil,
+			})
+			if result.Error != nil {
+				return copied, result.Error
+			}
+		}
+		log.Printf("post images: %d rows processed", copied)
+	}
+}
+
+func (m *migrator) migrateUserIcons() (int, error) {
+	copied := 0
+	var lastID uint
+	for {
+		var rows []legacyUserIcon
+		result := m.db.Table("users").
+			Select("id, icon_file_name, icon_data").
+			Where("icon_data IS NOT NULL AND icon_storage_key IS NULL AND id > ?", lastID).
+			Order("id").
+			Limit(m.batchSize).
+			Find(&rows)
+		if result.Error != nil {
+			return copied, result.Error
+		}
+		if len(rows) == 0 {
+			return copied, nil
+		}
+
+		for _, row := range rows {
+			lastID = row.ID
+			copied++
+			if m.dryRun {
+				continue
+			}
+
+			data, mimeType := sanitize(fmt.Sprintf("icon of user %d (%q)", row.ID, row.IconFileName), row.IconData, "")
+			checksum := helper.ContentHash(data)
+			key := blobstore.ContentKey(blobstore.UserIconNamespace, checksum)
+			if err := m.blobStore.Put(key, data, mimeType); err != nil {
+				return copied, err
+			}
+
+			result := m.db.Table("users").Where("id = ?", row.ID).Updates(map[string]any{
+				"icon_storage_key": key,
+				"icon_mime_type":   mimeType,
+				"icon_hash":        checksum,
+				"icon_data":        nil,
+			})
+			if result.Error != nil {
+				return copied, result.Error
+			}
+		}
+		log.Printf("user icons: %d rows processed", copied)
+	}
+}
+
+// メタデータを取り除いた内容と MIME タイプを返す。位置情報は残さない。
+// 既存のデータは検証前に保存されたものがあるため、処理できない場合はそのままコピーして移行は続ける
+func sanitize(label string, data []byte, mimeType string) ([]byte, string) {
+	info, err := imaging.Inspect("", data)
+	if err != nil {
+		log.Printf("could not detect image type of %s; copying it unchanged: %v", label, err)
+		if mimeType == "" {
+			mimeType = "application/octet-stream"
+		}
+		return data, mimeType
+	}
+
+	sanitized, err := imaging.Sanitize(data, info, false)
+	if err != nil {
+		log.Printf("could not remove metadata from %s; copying it unchanged: %v", label, err)
+		return data, info.MimeType
+	}
+	return sanitized.Data, sanitized.MimeType
+}
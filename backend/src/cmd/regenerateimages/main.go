@@ -0,0 +1,93 @@
+// すべての投稿画像の縮小画像とプレースホルダーを現在の IMAGE_* の設定で作り直す。
+// 既存の画像に縮小画像を追加する場合や、大きさ・品質の設定を変えた場合に実行する
+package main
+
+import (
+	"flag"
+	"log"
+	"os"
+
+	"github.com/joho/godotenv"
+	postgres_driver "gorm.io/driver/postgres"
+	"gorm.io/gorm"
+
+	"proto-pulse-plat/app/application/web/usecase"
+	"proto-pulse-plat/config"
+	"proto-pulse-plat/infrastructure/blobstore"
+	"proto-pulse-plat/infrastructure/persistence/postgres"
+)
+
+func main() {
+	batchSize := flag.Int("batch", 100, "number of images to load per query")
+	afterID := flag.Uint("after-id", 0, "resume after this post image id")
+	flag.Parse()
+
+	env := os.Getenv("APP_ENV")
+	if env == "" {
+		env = "local"
+	}
+
+	if env == "local" {
+		godotenv.Load(".env")
+	} else if env == "production" {
+		godotenv.Load("/etc/secrets/.env")
+	}
+
+	db, err := gorm.Open(postgres_driver.Open(config.GetDSN()), &gorm.Config{})
+	if err != nil {
+		log.Fatalf("failed to connect to database: %v", err)
+	}
+
+	blobStoreConfig, err := config.LoadBlobStoreConfig()
+	if err != nil {
+		log.Fatalf("failed to load blob store config: %v", err)
+	}
+
+	blobStore, err := blobstore.New(blobStoreConfig)
+	if err != nil {
+		log.Fatalf("failed to create blob store: %v", err)
+	}
+
+	imageVariantConfig, err := config.LoadImageVariantConfig()
+	if err != nil {
+		log.Fatalf("failed to load image variant config: %v", err)
+	}
+
+	postImagesRepository := postgres.NewGormPostImagesRepository(db)
+	imageVariantUsecase := usecase.NewImageVariantUsecase(
+		postImagesRepository,
+		postgres.NewGormPostImageVariantsRepository(db),
+		postgres.NewGormBlobLocksRepository(db),
+		blobStore,
+		imageVariantConfig,
+	)
+
+	regenerated, failed := 0, 0
+	lastID := *afterID
+	for {
+		postImages, err := postImagesRepository.FindAfterID(lastID, *batchSize)
+		if err != nil {
+			log.Fatalf("failed to load post images: %v", err)
+		}
+		if len(postImages) == 0 {
+			break
+		}
+
+		// 1枚の失敗で止めず、残りの画像を処理する
+		for _, postImage := range postImages {
+			lastID = postImage.ID
+			if err := imageVariantUsecase.Regenerate(postImage); err != nil {
+				log.Printf("skipped post image %d: %v", postImage.ID, err)
+				failed++
+				continue
+			}
+			regenerated++
+		}
+		log.Printf("processed post images up to id %d", lastID)
+	}
+
+	log.Printf("regenerated %d post images, %d failed", regenerated, failed)
+	if failed > 0 {
+		os.Exit(1)
+	}
+}
@@ -0,0 +1,64 @@
+package config
+
+import (
+	"fmt"
+	"strconv"
+)
+
+const (
+	BlobStoreLocal = "local"
+	BlobStoreS3    = "s3"
+)
+
+type BlobStoreConfig struct {
+	Driver string
+	// Driver が local の場合の保存先
+	LocalDir string
+	S3       S3Config
+}
+
+// S3 互換のストレージ。Endpoint を指定すると MinIO などに接続する
+type S3Config struct {
+	Bucket          string
+	Region          string
+	Endpoint        string
+	AccessKeyID     string
+	SecretAccessKey string
+	ForcePathStyle  bool
+}
+
+func LoadBlobStoreConfig() (*BlobStoreConfig, error) {
+	blobStoreConfig := &BlobStoreConfig{
+		Driver:   nonEmptyEnv("BLOB_STORE", BlobStoreLocal),
+		LocalDir: nonEmptyEnv("BLOB_STORE_LOCAL_DIR", "./data/blobs"),
+	}
+
+	switch blobStoreConfig.Driver {
+	case BlobStoreLocal:
+		return blobStoreConfig, nil
+	case BlobStoreS3:
+	default:
+		return nil, fmt.Errorf("BLOB_STORE %q is not supported", blobStoreConfig.Driver)
+	}
+
+	endpoint := GetEnv("S3_ENDPOINT", "")
+	// LocalStack や MinIO はパス形式でのみ接続できるため、Endpoint を指定した場合の既定値を true とする
+	forcePathStyle, err := strconv.ParseBool(nonEmptyEnv("S3_FORCE_PATH_STYLE", strconv.FormatBool(endpoint != "")))
+	if err != nil {
+		return nil, fmt.Errorf("S3_FORCE_PATH_STYLE is invalid: %w", err)
+	}
+
+	blobStoreConfig.S3 = S3Config{
+		Bucket:          GetEnv("S3_BUCKET_NAME", ""),
+		Region:          nonEmptyEnv("S3_REGION", "ap-northeast-1"),
+		Endpoint:        endpoint,
+		AccessKeyID:     GetEnv("S3_ACCESS_KEY_ID", ""),
+		SecretAccessKey: GetEnv("S3_SECRET_ACCESS_KEY", ""),
+		ForcePathStyle:  forcePathStyle,
+	}
+	if blobStoreConfig.S3.Bucket == "" {
+		return nil, fmt.Errorf("S3_BUCKET_NAME is not set in environment variables")
+	}
+
+	return blobStoreConfig, nil
+}
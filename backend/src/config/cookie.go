@@ -0,0 +1,19 @@
+package config
+
+import "fmt"
+
+type CookieConfig struct {
+	// 署名・暗号化した Cookie に使う鍵
+	StoreKey string
+}
+
+func LoadCookieConfig() (*CookieConfig, error) {
+	storeKey := GetEnv("COOKIE_STORE_KEY", "")
+	if storeKey == "" {
+		return nil, fmt.Errorf("COOKIE_STORE_KEY is not set in environment variables")
+	}
+
+	return &CookieConfig{
+		StoreKey: storeKey,
+	}, nil
+}
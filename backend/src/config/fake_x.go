@@ -0,0 +1,79 @@
+package config
+
+import (
+	"fmt"
+	"strings"
+)
+
+const (
+	FakeXPathPrefix      = "/fake-x"
+	FakeXConsumerKey     = "fake-x-consumer"
+	FakeXConsumerSecret  = "fake-x-consumer-secret"
+	defaultFakeXPersonas = "1000000001:alice:Alice,1000000002:bob:Bob"
+)
+
+// ローカル開発用の偽 X で選択できるテストユーザー
+type FakeXPersona struct {
+	IDStr      string
+	ScreenName string
+	Name       string
+}
+
+type FakeXConfig struct {
+	// バックエンド自身と、ブラウザの両方から到達できる URL
+	BaseURL  string
+	Personas []FakeXPersona
+}
+
+// APP_ENV が明示的に local で X_CONSUMER が未設定の場合のみ偽 X を使う。
+// APP_ENV が未設定の環境 (設定漏れの本番など) では有効にしない
+func FakeXEnabled() bool {
+	return GetEnv("APP_ENV", "") == "local" && GetEnv("X_CONSUMER", "") == ""
+}
+
+// FAKE_X_PERSONAS に id_str:screen_name:name をカンマ区切りで指定する
+func LoadFakeXConfig() (*FakeXConfig, error) {
+	var personas []FakeXPersona
+	for _, entry := range strings.Split(GetEnv("FAKE_X_PERSONAS", defaultFakeXPersonas), ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+
+		parts := strings.SplitN(entry, ":", 3)
+		if len(parts) != 3 || parts[0] == "" || parts[1] == "" {
+			return nil, fmt.Errorf("invalid FAKE_X_PERSONAS entry %q, want id_str:screen_name:name", entry)
+		}
+
+		personas = append(personas, FakeXPersona{
+			IDStr:      parts[0],
+			ScreenName: parts[1],
+			Name:       parts[2],
+		})
+	}
+
+	if len(personas) == 0 {
+		return nil, fmt.Errorf("FAKE_X_PERSONAS has no personas")
+	}
+
+	return &FakeXConfig{
+		BaseURL:  fakeXBaseURL(),
+		Personas: personas,
+	}, nil
+}
+
+func fakeXBaseURL() string {
+	return nonEmptyEnv("FAKE_X_URL", localBaseURL()) + FakeXPathPrefix
+}
+
+func localBaseURL() string {
+	return "http://localhost:" + nonEmptyEnv("PORT", "8080")
+}
+
+// .env に空で定義されている場合も既定値を使う
+func nonEmptyEnv(key, defaultValue string) string {
+	if value := GetEnv(key, ""); value != "" {
+		return value
+	}
+	return defaultValue
+}
@@ -0,0 +1,37 @@
+package config
+
+import (
+	"fmt"
+	"strconv"
+)
+
+// アップロード時に生成する派生画像の設定。大きさは長辺のピクセル数
+type ImageVariantConfig struct {
+	ThumbnailSize int
+	MediumSize    int
+	// JPEG で保存する場合の品質 (1 から 100)
+	JPEGQuality int
+}
+
+func LoadImageVariantConfig() (*ImageVariantConfig, error) {
+	thumbnailSize, err := strconv.Atoi(nonEmptyEnv("IMAGE_THUMBNAIL_SIZE", "480"))
+	if err != nil || thumbnailSize <= 0 {
+		return nil, fmt.Errorf("IMAGE_THUMBNAIL_SIZE must be a positive integer")
+	}
+
+	mediumSize, err := strconv.Atoi(nonEmptyEnv("IMAGE_MEDIUM_SIZE", "1600"))
+	if err != nil || mediumSize <= thumbnailSize {
+		return nil, fmt.Errorf("IMAGE_MEDIUM_SIZE must be an integer larger than IMAGE_THUMBNAIL_SIZE")
+	}
+
+	jpegQuality, err := strconv.Atoi(nonEmptyEnv("IMAGE_JPEG_QUALITY", "82"))
+	if err != nil || jpegQuality < 1 || jpegQuality > 100 {
+		return nil, fmt.Errorf("IMAGE_JPEG_QUALITY must be an integer from 1 to 100")
+	}
+
+	return &ImageVariantConfig{
+		ThumbnailSize: thumbnailSize,
+		MediumSize:    mediumSize,
+		JPEGQuality:   jpegQuality,
+	}, nil
+}
@@ -0,0 +1,174 @@
+package config
+
+import (
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+)
+
+const (
+	AlgorithmHS256 = "HS256"
+	AlgorithmRS256 = "RS256"
+	AlgorithmEdDSA = "EdDSA"
+
+	// JWT_SECRET_KEY から読み込む HS256 鍵の kid
+	DefaultKid = "default"
+)
+
+type SigningKey struct {
+	Kid       string
+	Algorithm string
+	// 署名用の鍵。検証専用の鍵 (ローテーション済み) の場合は nil
+	PrivateKey crypto.PrivateKey
+	// HS256 の場合は共有鍵 ([]byte)
+	PublicKey crypto.PublicKey
+}
+
+// 署名用の鍵を kid で管理する
+type KeyRing struct {
+	activeKid string
+	keys      map[string]*SigningKey
+}
+
+// JWT_KEYS に "kid:alg:PEMファイルパス" をカンマ区切りで指定する。
+// 例: JWT_KEYS=2026-10:EdDSA:/etc/secrets/jwt-2026-10.pem,2026-04:RS256:/etc/secrets/jwt-2026-04.pub.pem
+// 公開鍵のみの PEM は検証専用の鍵として扱う。JWT_SECRET_KEY が設定されている場合は kid "default" の HS256 鍵になる
+func LoadKeyRing() (*KeyRing, error) {
+	keyRing := &KeyRing{
+		keys: make(map[string]*SigningKey),
+	}
+
+	if secretKey := os.Getenv("JWT_SECRET_KEY"); secretKey != "" {
+		keyRing.keys[DefaultKid] = &SigningKey{
+			Kid:        DefaultKid,
+			Algorithm:  AlgorithmHS256,
+			PrivateKey: []byte(secretKey),
+			PublicKey:  []byte(secretKey),
+		}
+	}
+
+	for _, entry := range strings.Split(os.Getenv("JWT_KEYS"), ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+
+		parts := strings.SplitN(entry, ":", 3)
+		if len(parts) != 3 {
+			return nil, fmt.Errorf("invalid JWT_KEYS entry %q", entry)
+		}
+
+		key, err := loadSigningKey(parts[0], parts[1], parts[2])
+		if err != nil {
+			return nil, err
+		}
+		if _, exists := keyRing.keys[key.Kid]; exists {
+			return nil, fmt.Errorf("duplicate kid %q in JWT_KEYS", key.Kid)
+		}
+		keyRing.keys[key.Kid] = key
+	}
+
+	if len(keyRing.keys) == 0 {
+		return nil, fmt.Errorf("neither JWT_SECRET_KEY nor JWT_KEYS is set in environment variables")
+	}
+
+	keyRing.activeKid = os.Getenv("JWT_ACTIVE_KID")
+	if keyRing.activeKid == "" {
+		if len(keyRing.keys) != 1 {
+			return nil, fmt.Errorf("JWT_ACTIVE_KID is required when multiple keys are configured")
+		}
+		for kid := range keyRing.keys {
+			keyRing.activeKid = kid
+		}
+	}
+
+	active, ok := keyRing.keys[keyRing.activeKid]
+	if !ok {
+		return nil, fmt.Errorf("JWT_ACTIVE_KID %q is not in the key ring", keyRing.activeKid)
+	}
+	if active.PrivateKey == nil {
+		return nil, fmt.Errorf("active key %q has no private key", keyRing.activeKid)
+	}
+
+	return keyRing, nil
+}
+
+// 新しいトークンの署名に使う鍵
+func (k *KeyRing) Active() *SigningKey {
+	return k.keys[k.activeKid]
+}
+
+func (k *KeyRing) Lookup(kid string) (*SigningKey, bool) {
+	key, ok := k.keys[kid]
+	return key, ok
+}
+
+// JWKS として公開できる非対称鍵を kid 順で返す
+func (k *KeyRing) PublicKeys() []*SigningKey {
+	var keys []*SigningKey
+	for _, key := range k.keys {
+		if key.Algorithm != AlgorithmHS256 {
+			keys = append(keys, key)
+		}
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		return keys[i].Kid < keys[j].Kid
+	})
+	return keys
+}
+
+func loadSigningKey(kid, algorithm, path string) (*SigningKey, error) {
+	pemBytes, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read key %q: %w", kid, err)
+	}
+
+	block, _ := pem.Decode(pemBytes)
+	if block == nil {
+		return nil, fmt.Errorf("key %q is not PEM encoded", kid)
+	}
+
+	key := &SigningKey{
+		Kid:       kid,
+		Algorithm: algorithm,
+	}
+
+	switch block.Type {
+	case "PUBLIC KEY":
+		key.PublicKey, err = x509.ParsePKIXPublicKey(block.Bytes)
+	case "RSA PRIVATE KEY":
+		key.PrivateKey, err = x509.ParsePKCS1PrivateKey(block.Bytes)
+	case "PRIVATE KEY":
+		key.PrivateKey, err = x509.ParsePKCS8PrivateKey(block.Bytes)
+	default:
+		return nil, fmt.Errorf("key %q has unsupported PEM type %q", kid, block.Type)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse key %q: %w", kid, err)
+	}
+
+	if signer, ok := key.PrivateKey.(crypto.Signer); ok {
+		key.PublicKey = signer.Public()
+	}
+
+	switch algorithm {
+	case AlgorithmRS256:
+		if _, ok := key.PublicKey.(*rsa.PublicKey); !ok {
+			return nil, fmt.Errorf("key %q is not an RSA key", kid)
+		}
+	case AlgorithmEdDSA:
+		if _, ok := key.PublicKey.(ed25519.PublicKey); !ok {
+			return nil, fmt.Errorf("key %q is not an Ed25519 key", kid)
+		}
+	default:
+		return nil, fmt.Errorf("key %q has unsupported algorithm %q", kid, algorithm)
+	}
+
+	return key, nil
+}
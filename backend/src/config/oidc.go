@@ -0,0 +1,73 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"strings"
+)
+
+// OAuth2 / OpenID Connect プロバイダーの設定
+type OIDCProviderConfig struct {
+	Name         string
+	ClientID     string
+	ClientSecret string
+	// 設定されている場合、未指定のエンドポイントは /.well-known/openid-configuration から取得する
+	IssuerURL   string
+	AuthURL     string
+	TokenURL    string
+	UserInfoURL string
+	RedirectURL string
+	Scopes      []string
+	// userinfo レスポンスのどの項目をプロフィールに使うか。
+	// IDClaim は IssuerURL を指定しない OAuth2 プロバイダーのみ使い、OpenID Connect では ID トークンの sub を使う
+	IDClaim     string
+	HandleClaim string
+	NameClaim   string
+	AvatarClaim string
+}
+
+// OIDC_PROVIDERS にカンマ区切りでプロバイダー名を指定し、
+// プロバイダーごとの設定を OIDC_<NAME>_CLIENT_ID のように指定する。
+// 例: OIDC_PROVIDERS=github,google
+func LoadOIDCProviderConfigs() ([]OIDCProviderConfig, error) {
+	var configs []OIDCProviderConfig
+
+	for _, name := range strings.Split(os.Getenv("OIDC_PROVIDERS"), ",") {
+		name = strings.ToLower(strings.TrimSpace(name))
+		if name == "" {
+			continue
+		}
+
+		prefix := "OIDC_" + strings.ToUpper(name) + "_"
+		providerConfig := OIDCProviderConfig{
+			Name:         name,
+			ClientID:     GetEnv(prefix+"CLIENT_ID", ""),
+			ClientSecret: GetEnv(prefix+"CLIENT_SECRET", ""),
+			IssuerURL:    GetEnv(prefix+"ISSUER_URL", ""),
+			AuthURL:      GetEnv(prefix+"AUTH_URL", ""),
+			TokenURL:     GetEnv(prefix+"TOKEN_URL", ""),
+			UserInfoURL:  GetEnv(prefix+"USERINFO_URL", ""),
+			RedirectURL: GetEnv(
+				prefix+"REDIRECT_URL",
+				fmt.Sprintf("%s/api/oauth/%s/callback", os.Getenv("BASE_HTTPS_URL"), name),
+			),
+			Scopes:      strings.Fields(GetEnv(prefix+"SCOPES", "openid profile")),
+			IDClaim:     GetEnv(prefix+"ID_CLAIM", "sub"),
+			HandleClaim: GetEnv(prefix+"HANDLE_CLAIM", "preferred_username"),
+			NameClaim:   GetEnv(prefix+"NAME_CLAIM", "name"),
+			AvatarClaim: GetEnv(prefix+"AVATAR_CLAIM", "picture"),
+		}
+
+		if providerConfig.ClientID == "" {
+			return nil, fmt.Errorf("%sCLIENT_ID is not set in environment variables", prefix)
+		}
+		if providerConfig.IssuerURL == "" &&
+			(providerConfig.AuthURL == "" || providerConfig.TokenURL == "" || providerConfig.UserInfoURL == "") {
+			return nil, fmt.Errorf("%sISSUER_URL or AUTH_URL, TOKEN_URL and USERINFO_URL must be set", prefix)
+		}
+
+		configs = append(configs, providerConfig)
+	}
+
+	return configs, nil
+}
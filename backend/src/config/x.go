@@ -1,5 +1,7 @@
 package config
 
+import "fmt"
+
 type Xconfig struct {
 	RequestTokenURL   string
 	AccessTokenURL    string
@@ -11,6 +13,10 @@ type Xconfig struct {
 }
 
 func LoadXconfig() *Xconfig {
+	if FakeXEnabled() {
+		return fakeXconfig(fakeXBaseURL())
+	}
+
 	return &Xconfig{
 		RequestTokenURL:   GetEnv("X_REQUEST_TOKEN_URL", "x_request_token_example"),
 		AccessTokenURL:    GetEnv("X_ACCESS_TOKEN_URL", "x_access_token_url_example"),
@@ -21,3 +27,34 @@ func LoadXconfig() *Xconfig {
 		CallBackURL:       GetEnv("X_CALL_BACK_URL", "x_call_back_url_example"),
 	}
 }
+
+// ローカル開発用の偽 X に向けた設定
+func fakeXconfig(baseURL string) *Xconfig {
+	return &Xconfig{
+		RequestTokenURL:   baseURL + "/oauth/request_token",
+		AccessTokenURL:    baseURL + "/oauth/access_token",
+		ConsumerKey:       FakeXConsumerKey,
+		ConsumerSecret:    FakeXConsumerSecret,
+		AuthorizeURL:      baseURL + "/oauth/authorize",
+		VerifyCredentials: baseURL + "/1.1/account/verify_credentials.json",
+		CallBackURL:       nonEmptyEnv("X_CALL_BACK_URL", localBaseURL()+"/api/oauth2callback"),
+	}
+}
+
+// 外部ID導入前のユーザーの id_str を埋める cmd/backfillxids で使う。users/lookup はアプリ認証のベアラートークンで呼び出す
+type XLookupConfig struct {
+	UsersLookupURL string
+	BearerToken    string
+}
+
+func LoadXLookupConfig() (*XLookupConfig, error) {
+	bearerToken := GetEnv("X_BEARER_TOKEN", "")
+	if bearerToken == "" {
+		return nil, fmt.Errorf("X_BEARER_TOKEN is not set in environment variables")
+	}
+
+	return &XLookupConfig{
+		UsersLookupURL: nonEmptyEnv("X_USERS_LOOKUP_URL", "https://api.twitter.com/1.1/users/lookup.json"),
+		BearerToken:    bearerToken,
+	}, nil
+}
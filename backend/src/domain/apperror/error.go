@@ -0,0 +1,127 @@
+package apperror
+
+import (
+	"errors"
+	"fmt"
+)
+
+type Code string
+
+const (
+	CodeInvalidArgument  Code = "invalid_argument"
+	CodeUnauthorized     Code = "unauthorized"
+	CodeForbidden        Code = "forbidden"
+	CodeInvalidCSRFToken Code = "invalid_csrf_token"
+	CodeAccountSuspended Code = "account_suspended"
+	CodeNotFound         Code = "not_found"
+	CodeMethodNotAllowed Code = "method_not_allowed"
+	CodeConflict         Code = "conflict"
+	CodeBadGateway       Code = "bad_gateway"
+	CodeInternal         Code = "internal"
+)
+
+// errors.Is(err, apperror.ErrNotFound) のように種類だけで判定するための値
+var (
+	ErrInvalidArgument  = &Error{Code: CodeInvalidArgument}
+	ErrUnauthorized     = &Error{Code: CodeUnauthorized}
+	ErrForbidden        = &Error{Code: CodeForbidden}
+	ErrAccountSuspended = &Error{Code: CodeAccountSuspended}
+	ErrNotFound         = &Error{Code: CodeNotFound}
+	ErrConflict         = &Error{Code: CodeConflict}
+)
+
+// 入力項目ごとのエラー
+type FieldViolation struct {
+	Field   string
+	Message string
+}
+
+// 種類とクライアントに返すメッセージを持つエラー。原因のエラーはログにのみ出す
+type Error struct {
+	Code    Code
+	Message string
+	Details []FieldViolation
+	cause   error
+}
+
+func (e *Error) Error() string {
+	if e.cause != nil {
+		return fmt.Sprintf("%s: %s: %v", e.Code, e.Message, e.cause)
+	}
+	return fmt.Sprintf("%s: %s", e.Code, e.Message)
+}
+
+func (e *Error) Unwrap() error {
+	return e.cause
+}
+
+func (e *Error) Is(target error) bool {
+	t, ok := target.(*Error)
+	return ok && t.Code == e.Code
+}
+
+// 原因のエラーを付けたコピーを返す
+func (e *Error) Wrap(cause error) *Error {
+	wrapped := *e
+	wrapped.cause = cause
+	return &wrapped
+}
+
+func New(code Code, format string, args ...any) *Error {
+	return &Error{Code: code, Message: fmt.Sprintf(format, args...)}
+}
+
+func InvalidArgument(format string, args ...any) *Error {
+	return New(CodeInvalidArgument, format, args...)
+}
+
+// 項目ごとのエラーを持つ入力エラー
+func InvalidFields(violations ...FieldViolation) *Error {
+	return &Error{Code: CodeInvalidArgument, Message: "request has invalid fields", Details: violations}
+}
+
+func Unauthorized(format string, args ...any) *Error {
+	return New(CodeUnauthorized, format, args...)
+}
+
+func Forbidden(format string, args ...any) *Error {
+	return New(CodeForbidden, format, args...)
+}
+
+func InvalidCSRFToken() *Error {
+	return New(CodeInvalidCSRFToken, "CSRF token is missing or invalid")
+}
+
+func AccountSuspended() *Error {
+	return New(CodeAccountSuspended, "account is suspended")
+}
+
+func NotFound(format string, args ...any) *Error {
+	return New(CodeNotFound, format, args...)
+}
+
+func MethodNotAllowed(method string) *Error {
+	return New(CodeMethodNotAllowed, "method %s is not allowed", method)
+}
+
+func Conflict(format string, args ...any) *Error {
+	return New(CodeConflict, format, args...)
+}
+
+// 外部サービスとの通信に失敗したときのエラー
+func BadGateway(format string, args ...any) *Error {
+	return New(CodeBadGateway, format, args...)
+}
+
+func Field(field, message string) FieldViolation {
+	return FieldViolation{Field: field, Message: message}
+}
+
+// err が *Error を含む場合はそれを返す
+func As(err error) (*Error, bool) {
+	var appErr *Error
+	if errors.As(err, &appErr) {
+		return appErr, true
+	}
+	return nil, false
+}
@@ -0,0 +1,18 @@
+package entity
+
+import (
+	"time"
+)
+
+const (
+	// "true" の場合、アップロードされた写真の撮影位置を投稿者本人向けに保存する
+	AppSettingKeepUploadGPS = "keep_upload_gps"
+)
+
+type AppSetting struct {
+	Key       string `gorm:"primaryKey;size:64"`
+	Value     string `gorm:"type:text;not null"`
+	UpdatedBy *uint
+	CreatedAt time.Time
+	UpdatedAt time.Time
+}
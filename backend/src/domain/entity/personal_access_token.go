@@ -0,0 +1,20 @@
+package entity
+
+import (
+	"time"
+)
+
+// スクリプトなどから Authorization: Bearer で使うトークン。平文は発行時にのみ返す
+type PersonalAccessToken struct {
+	ID          uint   `gorm:"primaryKey"`
+	UserID      uint   `gorm:"not null;index"`
+	Name        string `gorm:"size:100;not null"`
+	TokenPrefix string `gorm:"size:16;not null"`
+	TokenHash   string `gorm:"size:64;not null;unique"`
+	Scopes      []string
+	ExpiresAt   *time.Time
+	LastUsedAt  *time.Time
+	RevokedAt   *time.Time
+	CreatedAt   time.Time
+	UpdatedAt   time.Time
+}
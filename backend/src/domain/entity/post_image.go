@@ -5,10 +5,28 @@ import (
 )
 
 type PostImage struct {
-	ID        uint   `gorm:"primaryKey"`
-	FileName  string `gorm:"type:varchar(255)"`
-	PostID    uint   `gorm:"not null"`
-	Data      []byte
-	CreatedAt time.Time
-	UpdatedAt time.Time
+	ID       uint   `gorm:"primaryKey"`
+	FileName string `gorm:"type:varchar(255)"`
+	PostID   uint   `gorm:"not null"`
+	// 内容は BlobStore に StorageKey で保存する
+	StorageKey string `gorm:"type:varchar(255)"`
+	Size       int64  `gorm:"not null;default:0"`
+	Checksum   string `gorm:"type:varchar(64);not null;default:''"`
+	MimeType   string `gorm:"type:varchar(64);not null;default:''"`
+	// 0 は未計測 (cmd/regenerateimages の実行前に保存した画像)
+	Width  int `gorm:"not null;default:0"`
+	Height int `gorm:"not null;default:0"`
+	// 読み込み中に表示するプレースホルダー。空は未計算
+	BlurHash      string `gorm:"type:varchar(64);not null;default:''"`
+	DominantColor string `gorm:"type:varchar(7);not null;default:''"`
+	SortOrder     int    `gorm:"not null;default:0"`
+	AltText       string `gorm:"type:varchar(255);not null;default:''"`
+	Caption       string `gorm:"type:varchar(1000);not null;default:''"`
+	// 一覧に表示する画像。投稿ごとに1枚まで
+	IsCover bool `gorm:"not null;default:false"`
+	// 撮影位置。keep_upload_gps が有効な場合のみ保存し、投稿者本人にのみ返す
+	GPSLatitude  *float64 `gorm:"column:gps_latitude"`
+	GPSLongitude *float64 `gorm:"column:gps_longitude"`
+	CreatedAt    time.Time
+	UpdatedAt    time.Time
 }
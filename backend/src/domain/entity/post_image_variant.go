@@ -0,0 +1,20 @@
+package entity
+
+import (
+	"time"
+)
+
+// 投稿画像から生成した縮小画像。内容は BlobStore に StorageKey で保存する
+type PostImageVariant struct {
+	ID          uint   `gorm:"primaryKey"`
+	PostImageID uint   `gorm:"not null"`
+	Name        string `gorm:"type:varchar(16);not null"`
+	StorageKey  string `gorm:"type:varchar(255);not null"`
+	MimeType    string `gorm:"type:varchar(64);not null"`
+	Width       int    `gorm:"not null"`
+	Height      int    `gorm:"not null"`
+	Size        int64  `gorm:"not null"`
+	Checksum    string `gorm:"type:varchar(64);not null"`
+	CreatedAt   time.Time
+	UpdatedAt   time.Time
+}
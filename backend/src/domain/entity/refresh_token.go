@@ -0,0 +1,17 @@
+package entity
+
+import (
+	"time"
+)
+
+// SessionID が同じリフレッシュトークンは同じファミリーとして扱う
+type RefreshToken struct {
+	ID        uint   `gorm:"primaryKey"`
+	SessionID string `gorm:"size:64;not null;index"`
+	UserID    uint   `gorm:"not null"`
+	TokenHash string `gorm:"size:64;not null;unique"`
+	ExpiresAt time.Time
+	UsedAt    *time.Time
+	CreatedAt time.Time
+	UpdatedAt time.Time
+}
@@ -0,0 +1,16 @@
+package entity
+
+import (
+	"time"
+)
+
+type Session struct {
+	ID         string `gorm:"primaryKey;size:64"`
+	UserID     uint   `gorm:"not null;index"`
+	UserAgent  string `gorm:"size:512"`
+	LastSeenAt time.Time
+	ExpiresAt  time.Time
+	RevokedAt  *time.Time
+	CreatedAt  time.Time
+	UpdatedAt  time.Time
+}
@@ -5,11 +5,15 @@ import (
 )
 
 type User struct {
-	ID           uint   `gorm:"primaryKey"     json:"id"`
-	UserName     string `gorm:"size:50"        json:"user_name"`
-	AccountID    string `gorm:"size:50;unique" json:"account_id"`
+	ID           uint   `gorm:"primaryKey"    json:"id"`
+	UserName     string `gorm:"size:50"       json:"user_name"`
+	AccountID    string `gorm:"size:50;index" json:"account_id"`
 	IconFileName string `gorm:"size:255"`
-	IconData     []byte
-	CreatedAt    time.Time
-	UpdatedAt    time.Time
+	// アイコンは BlobStore に IconStorageKey で保存する
+	IconStorageKey string `gorm:"size:255"`
+	IconMimeType   string `gorm:"size:64"`
+	IconHash       string `gorm:"size:64"`
+	Role           string `gorm:"size:16;not null;default:user"`
+	CreatedAt      time.Time
+	UpdatedAt      time.Time
 }
@@ -0,0 +1,16 @@
+package entity
+
+import (
+	"time"
+)
+
+// ユーザーに紐付いたログイン方法 (プロバイダーと、そのプロバイダーでのユーザーID)
+type UserIdentity struct {
+	ID         uint   `gorm:"primaryKey"                                                     json:"id"`
+	UserID     uint   `gorm:"not null;index"                                                 json:"user_id"`
+	Provider   string `gorm:"size:32;uniqueIndex:idx_user_identities_provider_external_id"  json:"provider"`
+	ExternalID string `gorm:"size:255;uniqueIndex:idx_user_identities_provider_external_id" json:"external_id"`
+	Handle     string `gorm:"size:255"                                                       json:"handle"`
+	CreatedAt  time.Time
+	UpdatedAt  time.Time
+}
@@ -0,0 +1,18 @@
+package entity
+
+import (
+	"time"
+)
+
+// ExpiresAt が nil の場合は解除されるまで無期限
+type UserSuspension struct {
+	ID        uint   `gorm:"primaryKey"`
+	UserID    uint   `gorm:"not null;index"`
+	Reason    string `gorm:"type:text;not null"`
+	CreatedBy *uint
+	ExpiresAt *time.Time
+	LiftedAt  *time.Time
+	LiftedBy  *uint
+	CreatedAt time.Time
+	UpdatedAt time.Time
+}
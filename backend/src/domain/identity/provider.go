@@ -0,0 +1,59 @@
+package identity
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"sort"
+)
+
+// プロバイダーから取得したプロフィールを共通の形にしたもの
+type Profile struct {
+	Provider    string
+	ExternalID  string
+	Handle      string
+	DisplayName string
+	AvatarURL   string
+}
+
+// 認可開始からコールバックまでプロバイダーが保持する値 (リクエストトークン、state、PKCE の verifier など)
+type FlowState map[string]string
+
+type Provider interface {
+	Name() string
+	// 認可URLと、コールバックの検証に必要な値を返す
+	Begin(ctx context.Context) (string, FlowState, error)
+	// コールバックのクエリを検証し、ログインしたユーザーのプロフィールを返す
+	Complete(ctx context.Context, callback url.Values, state FlowState) (*Profile, error)
+}
+
+type Registry struct {
+	providers map[string]Provider
+}
+
+func NewRegistry(providers ...Provider) (*Registry, error) {
+	registry := &Registry{
+		providers: make(map[string]Provider, len(providers)),
+	}
+	for _, provider := range providers {
+		if _, exists := registry.providers[provider.Name()]; exists {
+			return nil, fmt.Errorf("identity provider %q is registered twice", provider.Name())
+		}
+		registry.providers[provider.Name()] = provider
+	}
+	return registry, nil
+}
+
+func (r *Registry) Lookup(name string) (Provider, bool) {
+	provider, ok := r.providers[name]
+	return provider, ok
+}
+
+func (r *Registry) Names() []string {
+	names := make([]string, 0, len(r.providers))
+	for name := range r.providers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
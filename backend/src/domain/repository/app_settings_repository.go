@@ -0,0 +1,12 @@
+package repository
+
+import (
+	"proto-pulse-plat/domain/entity"
+	"proto-pulse-plat/infrastructure/model"
+)
+
+type AppSettingsRepository interface {
+	FindAll() ([]entity.AppSetting, error)
+	Find(key string) (*entity.AppSetting, error)
+	Save(model.AppSetting) (*entity.AppSetting, error)
+}
@@ -0,0 +1,9 @@
+package repository
+
+// 内容の保存と削除が同じキーで競合しないよう、キーごとのロックを取る
+type BlobLocksRepository interface {
+	// fn の実行中、同じキーの WithExclusiveLock を待たせる。内容を保存してから参照する行を保存し終えるまでの間に使う
+	WithSharedLock(keys []string, fn func() error) error
+	// fn の実行中、同じキーの WithSharedLock と WithExclusiveLock を待たせる。参照数を数えて内容を削除する間に使う
+	WithExclusiveLock(key string, fn func() error) error
+}
@@ -0,0 +1,15 @@
+package repository
+
+import (
+	"proto-pulse-plat/domain/entity"
+	"proto-pulse-plat/infrastructure/model"
+	"time"
+)
+
+type PersonalAccessTokensRepository interface {
+	Save(model.PersonalAccessToken) (*entity.PersonalAccessToken, error)
+	FindActiveByHash(tokenHash string) (*entity.PersonalAccessToken, error)
+	FindActiveByUserID(userID uint) ([]entity.PersonalAccessToken, error)
+	TouchLastUsed(id uint, lastUsedAt time.Time) error
+	Revoke(userID, id uint) error
+}
@@ -6,6 +6,14 @@ import (
 )
 
 type PostImagesRepository interface {
+	FindByID(imageID uint) (*entity.PostImage, error)
 	FindByPostID(postID uint) ([]entity.PostImage, error)
-	Save(model.PostImage) error
+	FindAfterID(lastID uint, limit int) ([]entity.PostImage, error)
+	Save(model.PostImage) (*entity.PostImage, error)
+	UpdateDerivedAttributes(imageID uint, width, height int, blurHash, dominantColor string) error
+	DeleteByIDs(postID uint, imageIDs []uint) error
+	CountByStorageKey(storageKey string) (int64, error)
+	UpdateSortOrders(postID uint, imageIDs []uint) error
+	UpdateDescription(postID, imageID uint, altText, caption *string) error
+	SetCover(postID, imageID uint) error
 }
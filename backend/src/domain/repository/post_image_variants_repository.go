@@ -0,0 +1,14 @@
+package repository
+
+import (
+	"proto-pulse-plat/domain/entity"
+	"proto-pulse-plat/infrastructure/model"
+)
+
+type PostImageVariantsRepository interface {
+	FindByPostImageIDs(postImageIDs []uint) ([]entity.PostImageVariant, error)
+	Find(postImageID uint, name string) (*entity.PostImageVariant, error)
+	Save(model.PostImageVariant) error
+	DeleteExcept(postImageID uint, names []string) error
+	CountByStorageKey(storageKey string) (int64, error)
+}
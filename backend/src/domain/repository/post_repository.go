@@ -10,5 +10,6 @@ type PostRepository interface {
 	FindAllWithPagination(limit int, offset int, title, contentTitle, location string) ([]entity.Post, int64, error)
 	Save(model.Post) (*entity.Post, error)
 	FindByID(postID int) (*entity.Post, error)
+	FindByIDIncludingSuspended(postID int) (*entity.Post, error)
 	Update(model.Post) error
 }
@@ -0,0 +1,13 @@
+package repository
+
+import (
+	"proto-pulse-plat/domain/entity"
+	"proto-pulse-plat/infrastructure/model"
+)
+
+type RefreshTokensRepository interface {
+	Save(model.RefreshToken) (*entity.RefreshToken, error)
+	FindByHash(tokenHash string) (*entity.RefreshToken, error)
+	// 未使用の場合のみ使用済みにする。既に使用済みなら false を返す
+	MarkUsed(id uint) (bool, error)
+}
@@ -0,0 +1,16 @@
+package repository
+
+import (
+	"proto-pulse-plat/domain/entity"
+	"proto-pulse-plat/infrastructure/model"
+	"time"
+)
+
+type SessionsRepository interface {
+	Save(model.Session) (*entity.Session, error)
+	FindActiveByID(id string) (*entity.Session, error)
+	FindActiveByUserID(userID uint) ([]entity.Session, error)
+	Touch(id string, lastSeenAt time.Time) error
+	Revoke(userID uint, id string) error
+	RevokeAllByUserID(userID uint) error
+}
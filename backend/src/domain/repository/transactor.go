@@ -0,0 +1,14 @@
+package repository
+
+// 複数のリポジトリへの変更を1つのトランザクションで行う
+type Transactor interface {
+	// fn がエラーを返した場合はすべての変更をロールバックする
+	Transaction(fn func(repos TxRepositories) error) error
+}
+
+// トランザクション内で使うリポジトリ
+type TxRepositories struct {
+	Posts             PostRepository
+	PostImages        PostImagesRepository
+	PostImageVariants PostImageVariantsRepository
+}
@@ -0,0 +1,14 @@
+package repository
+
+import (
+	"proto-pulse-plat/domain/entity"
+	"proto-pulse-plat/infrastructure/model"
+)
+
+type UserIdentitiesRepository interface {
+	Save(model.UserIdentity) (*entity.UserIdentity, error)
+	FindByExternalID(provider, externalID string) (*entity.UserIdentity, error)
+	FindByUserID(userID uint) ([]entity.UserIdentity, error)
+	UpdateHandle(id uint, handle string) error
+	Delete(userID, id uint) error
+}
@@ -0,0 +1,12 @@
+package repository
+
+import (
+	"proto-pulse-plat/domain/entity"
+	"proto-pulse-plat/infrastructure/model"
+)
+
+type UserSuspensionsRepository interface {
+	Save(model.UserSuspension) (*entity.UserSuspension, error)
+	FindActiveByUserID(userID uint) (*entity.UserSuspension, error)
+	LiftActiveByUserID(userID, liftedBy uint) error
+}
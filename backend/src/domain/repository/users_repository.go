@@ -7,6 +7,12 @@ import (
 
 type UsersRepository interface {
 	Find(id uint) (*entity.User, error)
-	Save(model.User) (*entity.User, error)
-	FindByUserName(userName string) (*entity.User, error)
+	FindForAuthentication(id uint) (*entity.User, error)
+	// 利用停止中のユーザーは見つからないものとして扱う
+	FindNotSuspended(id uint) (*entity.User, error)
+	Save(user model.User, identity model.UserIdentity) (*entity.User, error)
+	Update(model.User) error
+	UpdateRole(id uint, role string) error
+	// ログイン方法が紐付いていない (外部ID導入前に登録された) ユーザーのうち、スクリーンネームが一致するものがあるか
+	ExistsUnlinkedByAccountID(accountID string) (bool, error)
 }
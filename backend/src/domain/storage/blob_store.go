@@ -0,0 +1,17 @@
+package storage
+
+import (
+	"errors"
+	"io"
+)
+
+var ErrBlobNotFound = errors.New("blob not found")
+
+// 画像などのバイナリをキーで保存する。キーは "/" 区切りの相対パス
+type BlobStore interface {
+	Put(key string, data []byte, contentType string) error
+	// 存在しない場合は ErrBlobNotFound を返す。Range リクエストに応えられるよう、全体を読み込まずに返す
+	Get(key string) (io.ReadSeekCloser, error)
+	// 存在しない場合もエラーにしない
+	Delete(key string) error
+}
@@ -1,9 +1,11 @@
 package helper
 
 import (
+	"crypto/sha256"
+	"encoding/hex"
 	"encoding/json"
-	"fmt"
 	"net/http"
+	"proto-pulse-plat/domain/apperror"
 )
 
 const (
@@ -12,14 +14,23 @@ const (
 
 func ValidateMethod(r *http.Request, expectedMethod string) error {
 	if r.Method != expectedMethod {
-		return fmt.Errorf("method not allowed")
+		return apperror.MethodNotAllowed(r.Method)
 	}
 	return nil
 }
 
 func ParseMultipart(r *http.Request) error {
 	if err := r.ParseMultipartForm(MaxMultipartMemory); err != nil {
-		return fmt.Errorf("failed to parse multipart form: %w", err)
+		return apperror.InvalidArgument("request is not a valid multipart form").Wrap(err)
+	}
+	return nil
+}
+
+// JSON のリクエストボディを読み込む
+func DecodeJSONBody(r *http.Request, value any) error {
+	defer r.Body.Close()
+	if err := json.NewDecoder(r.Body).Decode(value); err != nil {
+		return apperror.InvalidArgument("request body is not valid JSON").Wrap(err)
 	}
 	return nil
 }
@@ -29,6 +40,8 @@ func WriteResponse(w http.ResponseWriter, response any) error {
 	return json.NewEncoder(w).Encode(response)
 }
 
-func WriteErrorResponse(w http.ResponseWriter, message string, statusCode int) {
-	http.Error(w, message, statusCode)
+// 内容が変わったかの判定に使う SHA-256 ハッシュ
+func ContentHash(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
 }
@@ -0,0 +1,144 @@
+package helper
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"proto-pulse-plat/auth"
+	"time"
+)
+
+const (
+	AuthCookieName    = "auth_token"
+	RefreshCookieName = "refresh_token"
+	// リフレッシュトークンは /api/auth 配下 (リフレッシュ・ログアウト) にのみ送信させる
+	refreshCookiePath = "/api/auth"
+
+	// OAuth 認可開始からコールバックまでの間だけ保持する Cookie
+	OAuthRequestCookieName = "oauth_request"
+	OAuthRequestCookieTTL  = 10 * time.Minute
+	oauthRequestCookiePath = "/api"
+)
+
+type sealedCookie struct {
+	Name      string          `json:"name"`
+	Value     json.RawMessage `json:"value"`
+	ExpiresAt int64           `json:"expires_at"`
+}
+
+func SetAuthCookies(w http.ResponseWriter, tokenPair *auth.TokenPair) {
+	http.SetCookie(w, &http.Cookie{
+		Name:     AuthCookieName,
+		Value:    tokenPair.AccessToken,
+		Path:     "/",
+		Expires:  tokenPair.AccessTokenExpiresAt,
+		HttpOnly: true,
+		Secure:   true, // 本番環境では true にする
+		SameSite: http.SameSiteNoneMode,
+	})
+	http.SetCookie(w, &http.Cookie{
+		Name:     RefreshCookieName,
+		Value:    tokenPair.RefreshToken,
+		Path:     refreshCookiePath,
+		Expires:  tokenPair.RefreshTokenExpiresAt,
+		HttpOnly: true,
+		Secure:   true,
+		SameSite: http.SameSiteNoneMode,
+	})
+}
+
+func ClearAuthCookies(w http.ResponseWriter) {
+	clearCookie(w, AuthCookieName, "/")
+	clearCookie(w, RefreshCookieName, refreshCookiePath)
+}
+
+func clearCookie(w http.ResponseWriter, name, path string) {
+	http.SetCookie(w, &http.Cookie{
+		Name:     name,                 // 削除したいクッキーの名前
+		Value:    "",                   // 値を空にする
+		Path:     path,                 // クッキーが有効なパス
+		Expires:  time.Unix(0, 0),      // 過去の日付を設定
+		MaxAge:   -1,                   // 最大期限を負に設定
+		HttpOnly: true,                 // HttpOnly属性がついている場合
+		Secure:   true,                 // 本番環境では true にする
+		SameSite: http.SameSiteLaxMode, // SameSite属性を設定することでセキュリティ強化
+	})
+}
+
+func SetOAuthRequestCookie(w http.ResponseWriter, storeKey string, value any) error {
+	return setSealedCookie(w, storeKey, OAuthRequestCookieName, oauthRequestCookiePath, value, OAuthRequestCookieTTL)
+}
+
+// Cookie を検証して value に復元する。使い回しを防ぐため読み出した Cookie は削除する
+func ConsumeOAuthRequestCookie(w http.ResponseWriter, r *http.Request, storeKey string, value any) error {
+	clearCookie(w, OAuthRequestCookieName, oauthRequestCookiePath)
+	return readSealedCookie(r, storeKey, OAuthRequestCookieName, value)
+}
+
+// 値を AES-GCM で暗号化して Cookie に保存する。改ざんされた Cookie は復号に失敗する
+func setSealedCookie(w http.ResponseWriter, storeKey, name, path string, value any, ttl time.Duration) error {
+	rawValue, err := json.Marshal(value)
+	if err != nil {
+		return err
+	}
+
+	expiresAt := time.Now().Add(ttl)
+	plainText, err := json.Marshal(sealedCookie{
+		Name:      name,
+		Value:     rawValue,
+		ExpiresAt: expiresAt.Unix(),
+	})
+	if err != nil {
+		return err
+	}
+
+	cipherText, err := encrypt(plainText, storeKey)
+	if err != nil {
+		return err
+	}
+
+	http.SetCookie(w, &http.Cookie{
+		Name:     name,
+		Value:    base64.RawURLEncoding.EncodeToString(cipherText),
+		Path:     path,
+		Expires:  expiresAt,
+		HttpOnly: true,
+		Secure:   true,
+		// 外部サイトからのリダイレクト (トップレベルの GET) でも送信させる
+		SameSite: http.SameSiteLaxMode,
+	})
+
+	return nil
+}
+
+func readSealedCookie(r *http.Request, storeKey, name string, value any) error {
+	cookie, err := r.Cookie(name)
+	if err != nil {
+		return err
+	}
+
+	cipherText, err := base64.RawURLEncoding.DecodeString(cookie.Value)
+	if err != nil {
+		return fmt.Errorf("cookie %s is malformed: %w", name, err)
+	}
+
+	plainText, err := decrypt(cipherText, storeKey)
+	if err != nil {
+		return fmt.Errorf("cookie %s is invalid: %w", name, err)
+	}
+
+	var sealed sealedCookie
+	if err := json.Unmarshal(plainText, &sealed); err != nil {
+		return err
+	}
+
+	if sealed.Name != name {
+		return fmt.Errorf("cookie %s was issued as %s", name, sealed.Name)
+	}
+	if time.Now().Unix() > sealed.ExpiresAt {
+		return fmt.Errorf("cookie %s is expired", name)
+	}
+
+	return json.Unmarshal(sealed.Value, value)
+}
@@ -0,0 +1,82 @@
+package helper
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"net/http"
+	"proto-pulse-plat/auth"
+	"strings"
+	"time"
+)
+
+const (
+	CSRFCookieName = "csrf_token"
+	CSRFHeaderName = "X-Csrf-Token"
+
+	anonymousCSRFSubject = "anonymous"
+)
+
+// CSRF トークンを結び付ける対象。ログイン中はセッション ID、未ログインの場合は anonymous
+func CSRFSubject(principal *auth.Principal) string {
+	if principal == nil || principal.AuthMethod != auth.AuthMethodSession || principal.SessionID == "" {
+		return anonymousCSRFSubject
+	}
+	return "session:" + principal.SessionID
+}
+
+// subject に結び付いた CSRF トークンを返す。有効な Cookie があればそれを使い、無ければ発行して Cookie に保存する
+func IssueCSRFToken(w http.ResponseWriter, r *http.Request, storeKey, subject string) (string, error) {
+	if cookie, err := r.Cookie(CSRFCookieName); err == nil && validCSRFToken(cookie.Value, storeKey, subject) {
+		return cookie.Value, nil
+	}
+
+	nonce, err := GenerateNonce(32)
+	if err != nil {
+		return "", err
+	}
+	token := nonce + "." + signCSRFNonce(nonce, storeKey, subject)
+
+	http.SetCookie(w, &http.Cookie{
+		Name:     CSRFCookieName,
+		Value:    token,
+		Path:     "/",
+		Expires:  time.Now().Add(auth.SessionTTL),
+		HttpOnly: true,
+		Secure:   true,
+		SameSite: http.SameSiteNoneMode,
+	})
+
+	return token, nil
+}
+
+// ヘッダーのトークンが Cookie と一致し、このサーバーが subject に対して発行したものか確認する (署名付き double-submit)。
+// subject にはリクエストの認証で確定したセッションを渡す
+func VerifyCSRFToken(r *http.Request, storeKey, subject string) bool {
+	cookie, err := r.Cookie(CSRFCookieName)
+	if err != nil {
+		return false
+	}
+
+	header := r.Header.Get(CSRFHeaderName)
+	if header == "" || !hmac.Equal([]byte(header), []byte(cookie.Value)) {
+		return false
+	}
+
+	return validCSRFToken(cookie.Value, storeKey, subject)
+}
+
+// 署名はセッションごとに異なるため、他人が取得したトークンを Cookie に書き込んでも通らない
+func validCSRFToken(token, storeKey, subject string) bool {
+	nonce, signature, ok := strings.Cut(token, ".")
+	if !ok || nonce == "" {
+		return false
+	}
+	return hmac.Equal([]byte(signature), []byte(signCSRFNonce(nonce, storeKey, subject)))
+}
+
+func signCSRFNonce(nonce, storeKey, subject string) string {
+	mac := hmac.New(sha256.New, []byte(storeKey))
+	mac.Write([]byte("csrf:" + subject + ":" + nonce))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
@@ -0,0 +1,66 @@
+package helper
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+	"proto-pulse-plat/domain/apperror"
+	"proto-pulse-plat/infrastructure/response"
+)
+
+var errorStatuses = map[apperror.Code]int{
+	apperror.CodeInvalidArgument:  http.StatusBadRequest,
+	apperror.CodeUnauthorized:     http.StatusUnauthorized,
+	apperror.CodeForbidden:        http.StatusForbidden,
+	apperror.CodeInvalidCSRFToken: http.StatusForbidden,
+	apperror.CodeAccountSuspended: http.StatusForbidden,
+	apperror.CodeNotFound:         http.StatusNotFound,
+	apperror.CodeMethodNotAllowed: http.StatusMethodNotAllowed,
+	apperror.CodeConflict:         http.StatusConflict,
+	apperror.CodeBadGateway:       http.StatusBadGateway,
+}
+
+// err の種類に応じたステータスでエラーを返す。種類の無いエラーは 500 とし、message を返す
+func WriteError(w http.ResponseWriter, err error, message string) {
+	appErr, ok := apperror.As(err)
+	if !ok {
+		writeErrorBody(w, http.StatusInternalServerError, apperror.CodeInternal, message, nil)
+		return
+	}
+
+	status, ok := errorStatuses[appErr.Code]
+	if !ok {
+		status = http.StatusInternalServerError
+	}
+	writeErrorBody(w, status, appErr.Code, appErr.Message, appErr.Details)
+}
+
+func writeErrorBody(
+	w http.ResponseWriter,
+	statusCode int,
+	code apperror.Code,
+	message string,
+	violations []apperror.FieldViolation,
+) {
+	details := make([]response.FieldError, 0, len(violations))
+	for _, violation := range violations {
+		details = append(details, response.FieldError{
+			Field:   violation.Field,
+			Message: violation.Message,
+		})
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.WriteHeader(statusCode)
+	err := json.NewEncoder(w).Encode(response.ErrorResponse{
+		Error: response.ErrorBody{
+			Code:    string(code),
+			Message: message,
+			Details: details,
+		},
+	})
+	if err != nil {
+		log.Println("Error encoding error response:", err)
+	}
+}
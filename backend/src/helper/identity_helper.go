@@ -0,0 +1,23 @@
+package helper
+
+import (
+	"proto-pulse-plat/domain/entity"
+	"proto-pulse-plat/infrastructure/response"
+	"time"
+)
+
+func BuildIdentityListResponse(identities []entity.UserIdentity) response.IdentityList {
+	responseIdentities := make([]response.Identity, 0, len(identities))
+	for _, identity := range identities {
+		responseIdentities = append(responseIdentities, response.Identity{
+			ID:        identity.ID,
+			Provider:  identity.Provider,
+			Handle:    identity.Handle,
+			CreatedAt: identity.CreatedAt.Format(time.RFC3339),
+		})
+	}
+
+	return response.IdentityList{
+		Identities: responseIdentities,
+	}
+}
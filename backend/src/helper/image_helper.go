@@ -0,0 +1,115 @@
+package helper
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"proto-pulse-plat/domain/entity"
+	"proto-pulse-plat/infrastructure/imaging"
+	"proto-pulse-plat/infrastructure/response"
+	"sort"
+	"strings"
+	"time"
+)
+
+const (
+	// URL の v が内容と一致する場合。内容が変わると URL も変わるため再検証しない。
+	// 投稿の削除や利用停止で非公開になった画像を共有キャッシュに残さないよう、ブラウザのキャッシュのみに保存させる
+	immutableCacheControl = "private, max-age=31536000, immutable"
+	// v が無い、または古い URL の場合は毎回 ETag で再検証させる。再検証のたびに公開状態を確認する
+	revalidateCacheControl = "public, no-cache"
+)
+
+// 画像 URL の v に付ける、内容のハッシュの先頭
+func ImageVersion(checksum string) string {
+	if len(checksum) > 16 {
+		return checksum[:16]
+	}
+	return checksum
+}
+
+func PostImageURL(postImage entity.PostImage) string {
+	return fmt.Sprintf("/api/images/%d?v=%s", postImage.ID, ImageVersion(postImage.Checksum))
+}
+
+func PostImageVariantURL(variant entity.PostImageVariant) string {
+	return fmt.Sprintf(
+		"/api/images/%d?variant=%s&v=%s", variant.PostImageID, variant.Name, ImageVersion(variant.Checksum),
+	)
+}
+
+// 指定した派生画像が無い場合 (元画像が十分に小さい場合など) は元画像の URL を返す
+func variantOrOriginalURL(postImage entity.PostImage, variants []entity.PostImageVariant, name string) string {
+	for _, variant := range variants {
+		if variant.Name == name {
+			return PostImageVariantURL(variant)
+		}
+	}
+	return PostImageURL(postImage)
+}
+
+func buildImageSources(postImage entity.PostImage, variants []entity.PostImageVariant) []response.ImageSource {
+	sources := make([]response.ImageSource, 0, len(variants)+1)
+	for _, variant := range variants {
+		sources = append(sources, response.ImageSource{
+			Variant: variant.Name,
+			URL:     PostImageVariantURL(variant),
+			Width:   variant.Width,
+			Height:  variant.Height,
+		})
+	}
+	sort.SliceStable(sources, func(i, j int) bool {
+		return sources[i].Width < sources[j].Width
+	})
+
+	return append(sources, response.ImageSource{
+		Variant: imaging.VariantOriginal,
+		URL:     PostImageURL(postImage),
+		Width:   postImage.Width,
+		Height:  postImage.Height,
+	})
+}
+
+// アイコンが無い場合は空文字を返す
+func UserIconURL(user entity.User) string {
+	if user.IconStorageKey == "" {
+		return ""
+	}
+	return fmt.Sprintf("/api/users/%d/icon?v=%s", user.ID, ImageVersion(user.IconHash))
+}
+
+// If-None-Match が内容のハッシュの ETag を含むか。If-None-Match は弱い比較で判定する
+func ImageNotModified(r *http.Request, checksum string) bool {
+	header := r.Header.Get("If-None-Match")
+	if header == "" {
+		return false
+	}
+
+	for _, tag := range strings.Split(header, ",") {
+		tag = strings.TrimPrefix(strings.TrimSpace(tag), "W/")
+		if tag == "*" || tag == `"`+checksum+`"` {
+			return true
+		}
+	}
+	return false
+}
+
+// 内容のハッシュを強い ETag にして画像を返す。If-None-Match と Range は http.ServeContent が処理する。
+// content が nil の場合は ImageNotModified で一致したものとして 304 を返す
+func ServeImage(w http.ResponseWriter, r *http.Request, content io.ReadSeeker, mimeType, checksum string) {
+	cacheControl := revalidateCacheControl
+	if version := r.URL.Query().Get("v"); version != "" && version == ImageVersion(checksum) {
+		cacheControl = immutableCacheControl
+	}
+
+	w.Header().Set("ETag", `"`+checksum+`"`)
+	w.Header().Set("Cache-Control", cacheControl)
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	if content == nil {
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+
+	w.Header().Set("Content-Type", mimeType)
+	http.ServeContent(w, r, "", time.Time{}, content)
+}
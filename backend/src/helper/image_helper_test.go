@@ -0,0 +1,53 @@
+package helper
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestServeImage(t *testing.T) {
+	const checksum = "0123456789abcdef0123456789abcdef"
+	content := []byte("image")
+
+	tests := []struct {
+		name             string
+		url              string
+		ifNoneMatch      string
+		wantStatus       int
+		wantCacheControl string
+	}{
+		// 非公開になった画像を共有キャッシュに残さない
+		{name: "current version", url: "/api/images/1?v=" + ImageVersion(checksum), wantStatus: http.StatusOK, wantCacheControl: "private, max-age=31536000, immutable"},
+		{name: "no version", url: "/api/images/1", wantStatus: http.StatusOK, wantCacheControl: "public, no-cache"},
+		{name: "old version", url: "/api/images/1?v=stale", wantStatus: http.StatusOK, wantCacheControl: "public, no-cache"},
+		{name: "not modified", url: "/api/images/1", ifNoneMatch: `W/"` + checksum + `"`, wantStatus: http.StatusNotModified, wantCacheControl: "public, no-cache"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodGet, tt.url, nil)
+			if tt.ifNoneMatch != "" {
+				r.Header.Set("If-None-Match", tt.ifNoneMatch)
+			}
+			w := httptest.NewRecorder()
+
+			// usecase と同じく、If-None-Match が一致する場合は内容を読み込まない
+			if ImageNotModified(r, checksum) {
+				ServeImage(w, r, nil, "image/png", checksum)
+			} else {
+				ServeImage(w, r, bytes.NewReader(content), "image/png", checksum)
+			}
+
+			if w.Code != tt.wantStatus {
+				t.Errorf("status = %d, want %d", w.Code, tt.wantStatus)
+			}
+			if got := w.Header().Get("Cache-Control"); got != tt.wantCacheControl {
+				t.Errorf("Cache-Control = %q, want %q", got, tt.wantCacheControl)
+			}
+			if got := w.Header().Get("ETag"); got != `"`+checksum+`"` {
+				t.Errorf("ETag = %s, want the checksum", got)
+			}
+		})
+	}
+}
@@ -0,0 +1,40 @@
+package helper
+
+import (
+	"crypto/ed25519"
+	"crypto/rsa"
+	"encoding/base64"
+	"math/big"
+	"proto-pulse-plat/config"
+	"proto-pulse-plat/infrastructure/response"
+)
+
+func BuildJWKSResponse(keyRing *config.KeyRing) response.JWKS {
+	keys := []response.JWK{}
+	for _, key := range keyRing.PublicKeys() {
+		jwk := response.JWK{
+			Kid: key.Kid,
+			Use: "sig",
+			Alg: key.Algorithm,
+		}
+
+		switch publicKey := key.PublicKey.(type) {
+		case *rsa.PublicKey:
+			jwk.Kty = "RSA"
+			jwk.N = base64.RawURLEncoding.EncodeToString(publicKey.N.Bytes())
+			jwk.E = base64.RawURLEncoding.EncodeToString(big.NewInt(int64(publicKey.E)).Bytes())
+		case ed25519.PublicKey:
+			jwk.Kty = "OKP"
+			jwk.Crv = "Ed25519"
+			jwk.X = base64.RawURLEncoding.EncodeToString(publicKey)
+		default:
+			continue
+		}
+
+		keys = append(keys, jwk)
+	}
+
+	return response.JWKS{
+		Keys: keys,
+	}
+}
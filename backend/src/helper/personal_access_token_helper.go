@@ -0,0 +1,38 @@
+package helper
+
+import (
+	"proto-pulse-plat/domain/entity"
+	"proto-pulse-plat/infrastructure/response"
+	"time"
+)
+
+func BuildPersonalAccessTokenResponse(token entity.PersonalAccessToken) response.PersonalAccessToken {
+	return response.PersonalAccessToken{
+		ID:          token.ID,
+		Name:        token.Name,
+		TokenPrefix: token.TokenPrefix,
+		Scopes:      token.Scopes,
+		ExpiresAt:   formatOptionalTime(token.ExpiresAt),
+		LastUsedAt:  formatOptionalTime(token.LastUsedAt),
+		CreatedAt:   token.CreatedAt.Format(time.RFC3339),
+	}
+}
+
+func BuildPersonalAccessTokenListResponse(tokens []entity.PersonalAccessToken) response.PersonalAccessTokenList {
+	responseTokens := make([]response.PersonalAccessToken, 0, len(tokens))
+	for _, token := range tokens {
+		responseTokens = append(responseTokens, BuildPersonalAccessTokenResponse(token))
+	}
+
+	return response.PersonalAccessTokenList{
+		Tokens: responseTokens,
+	}
+}
+
+func formatOptionalTime(t *time.Time) *string {
+	if t == nil {
+		return nil
+	}
+	formatted := t.Format(time.RFC3339)
+	return &formatted
+}
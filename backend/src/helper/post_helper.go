@@ -5,15 +5,14 @@ import (
 	"crypto/aes"
 	"crypto/cipher"
 	"crypto/rand"
-	"encoding/base64"
 	"fmt"
 	"io"
 	"net/http"
-	"path/filepath"
+	"proto-pulse-plat/auth"
+	"proto-pulse-plat/domain/apperror"
 	"proto-pulse-plat/domain/entity"
-	"proto-pulse-plat/infrastructure/model"
+	"proto-pulse-plat/infrastructure/imaging"
 	"proto-pulse-plat/infrastructure/response"
-	"strings"
 )
 
 func PostListQueryParams(r *http.Request) (string, string) {
@@ -27,9 +26,10 @@ func BuildPostListResponse(
 	posts []entity.Post,
 	users []entity.User,
 	postImagesMap map[uint][]entity.PostImage,
+	variantsMap map[uint][]entity.PostImageVariant,
 	totalCount int64,
 	page, perPage int,
-	loginUser *model.UserProfile,
+	loginUser *auth.Principal,
 ) response.PostList {
 	// ユーザーIDをキーにしたユーザーマップを作成
 	userMap := make(map[uint]entity.User)
@@ -46,33 +46,37 @@ func BuildPostListResponse(
 		// 投稿に関連付けられた画像を取得
 		postImages := postImagesMap[post.ID]
 
-		// ベース64エンコードされた画像を結合（例として最初の画像のみ）
-		var postImageBase64 string
-		if len(postImages) > 0 {
-			postImageBase64 = fmt.Sprintf(
-				"data:%s;base64,%s",
-				getImageBase64(postImages[0].FileName),
-				base64.StdEncoding.EncodeToString(postImages[0].Data),
-			) // PostImageにBase64フィールドがあると仮定
+		// 一覧ではカバー画像のみ表示する
+		var postImageURL, postImageAltText string
+		var postImageSrcSet []response.ImageSource
+		var postImageBlurHash, postImageDominantColor string
+		if coverImage, ok := CoverImage(postImages); ok {
+			variants := variantsMap[coverImage.ID]
+			postImageURL = variantOrOriginalURL(coverImage, variants, imaging.VariantThumbnail)
+			postImageSrcSet = buildImageSources(coverImage, variants)
+			postImageAltText = coverImage.AltText
+			postImageBlurHash = coverImage.BlurHash
+			postImageDominantColor = coverImage.DominantColor
 		}
 
 		// レスポンス用Post構造体に変換
 		responsePost := response.Post{
-			ID:              post.ID,
-			Title:           post.Title,
-			Content:         post.Content,
-			ContentTitle:    post.ContentTitle,
-			Location:        post.Location,
-			PostImageBase64: postImageBase64,
-			UserName:        user.UserName,
-			AccountID:       user.AccountID,
-			IconImageBase64: fmt.Sprintf(
-				"data:%s;base64,%s",
-				getImageBase64(user.IconFileName),
-				base64.StdEncoding.EncodeToString(user.IconData)),
-			IsOwnPost: loginUser != nil && user.UserName == loginUser.ScreenName,
-			UserID:    user.ID,
-			CreatedAt: post.CreatedAt.Format("2006年01月02日"),
+			ID:                     post.ID,
+			Title:                  post.Title,
+			Content:                post.Content,
+			ContentTitle:           post.ContentTitle,
+			Location:               post.Location,
+			PostImageURL:           postImageURL,
+			PostImageSrcSet:        postImageSrcSet,
+			PostImageAltText:       postImageAltText,
+			PostImageBlurHash:      postImageBlurHash,
+			PostImageDominantColor: postImageDominantColor,
+			UserName:               user.UserName,
+			AccountID:              user.AccountID,
+			IconImageURL:           UserIconURL(user),
+			IsOwnPost:              loginUser != nil && post.UserID == loginUser.UserID,
+			UserID:                 user.ID,
+			CreatedAt:              post.CreatedAt.Format("2006年01月02日"),
 		}
 		responsePosts = append(responsePosts, responsePost)
 	}
@@ -86,50 +90,75 @@ func BuildPostListResponse(
 	}
 }
 
-func BuildPostResponse(post *entity.Post, postImages []entity.PostImage) response.PostDetail {
-	var postImagesBase64 []string
+func BuildPostResponse(
+	post *entity.Post,
+	postImages []entity.PostImage,
+	variantsMap map[uint][]entity.PostImageVariant,
+	loginUser *auth.Principal,
+) response.PostDetail {
+	var postImageURLs []string
+	var postImageSrcSets [][]response.ImageSource
+	var postImageIDs []uint
+	var postImageAltTexts, postImageCaptions []string
+	// 撮影位置は投稿者本人にのみ返す
+	isOwnPost := loginUser != nil && post.UserID == loginUser.UserID
+	var postImageLocations []*response.ImageLocation
 
 	for _, postImage := range postImages {
-		postImageBase64 := fmt.Sprintf(
-			"data:%s;base64,%s",
-			getImageBase64(postImage.FileName),
-			base64.StdEncoding.EncodeToString(postImage.Data),
-		)
-		postImagesBase64 = append(postImagesBase64, postImageBase64)
+		variants := variantsMap[postImage.ID]
+		postImageURLs = append(postImageURLs, variantOrOriginalURL(postImage, variants, imaging.VariantMedium))
+		postImageSrcSets = append(postImageSrcSets, buildImageSources(postImage, variants))
+		postImageIDs = append(postImageIDs, postImage.ID)
+		postImageAltTexts = append(postImageAltTexts, postImage.AltText)
+		postImageCaptions = append(postImageCaptions, postImage.Caption)
+
+		if isOwnPost {
+			postImageLocations = append(postImageLocations, buildImageLocation(postImage))
+		}
+	}
+
+	var coverImageID uint
+	if coverImage, ok := CoverImage(postImages); ok {
+		coverImageID = coverImage.ID
 	}
 
 	responsePost := response.PostDetail{
-		ID:               post.ID,
-		Title:            post.Title,
-		Content:          post.Content,
-		PostImagesBase64: postImagesBase64,
+		ID:                 post.ID,
+		Title:              post.Title,
+		Content:            post.Content,
+		PostImageURLs:      postImageURLs,
+		PostImageSrcSets:   postImageSrcSets,
+		PostImageIDs:       postImageIDs,
+		PostImageAltTexts:  postImageAltTexts,
+		PostImageCaptions:  postImageCaptions,
+		CoverImageID:       coverImageID,
+		PostImageLocations: postImageLocations,
 	}
 
 	return responsePost
 }
 
-func getImageBase64(iconURL string) string {
-	// ファイル名から拡張子を取得
-	ext := strings.ToLower(filepath.Ext(iconURL))
-
-	// 拡張子に基づくMIMEタイプの判定
-	mimeType := ""
-	switch ext {
-	case ".jpg", ".jpeg":
-		mimeType = "image/jpeg"
-	case ".png":
-		mimeType = "image/png"
-	case ".gif":
-		mimeType = "image/gif"
-	case ".bmp":
-		mimeType = "image/bmp"
-	case ".webp":
-		mimeType = "image/webp"
-	default:
-		return ""
-	}
-
-	return mimeType
+// カバー画像に指定された画像を返す。指定が無い場合は並び順が先頭の画像
+func CoverImage(postImages []entity.PostImage) (entity.PostImage, bool) {
+	if len(postImages) == 0 {
+		return entity.PostImage{}, false
+	}
+	for _, postImage := range postImages {
+		if postImage.IsCover {
+			return postImage, true
+		}
+	}
+	return postImages[0], true
+}
+
+func buildImageLocation(postImage entity.PostImage) *response.ImageLocation {
+	if postImage.GPSLatitude == nil || postImage.GPSLongitude == nil {
+		return nil
+	}
+	return &response.ImageLocation{
+		Latitude:  *postImage.GPSLatitude,
+		Longitude: *postImage.GPSLongitude,
+	}
 }
 
 // AES暗号化
@@ -183,8 +212,10 @@ func createKey(passPhrase string) []byte {
 
 // AES復号化
 func decrypt(data []byte, passphrase string) ([]byte, error) {
+	key := createKey(passphrase)
+
 	// AESブロック暗号を生成
-	block, err := aes.NewCipher([]byte(passphrase))
+	block, err := aes.NewCipher(key)
 	if err != nil {
 		return nil, err
 	}
@@ -212,3 +243,35 @@ func decrypt(data []byte, passphrase string) ([]byte, error) {
 
 	return plaintext, nil
 }
+
+// 削除対象を除いた画像IDを表示順に並べる。order に含まれない画像は現在の順序で末尾に続ける
+func ResolveImageOrder(postImages []entity.PostImage, deleteImageIDs, order []uint) ([]uint, error) {
+	remaining := make(map[uint]bool, len(postImages))
+	for _, postImage := range postImages {
+		remaining[postImage.ID] = true
+	}
+
+	for _, id := range deleteImageIDs {
+		if !remaining[id] {
+			return nil, apperror.InvalidFields(apperror.Field("delete_image_ids[]", fmt.Sprintf("image %d does not belong to the post", id)))
+		}
+		delete(remaining, id)
+	}
+
+	resolved := make([]uint, 0, len(remaining))
+	for _, id := range order {
+		if !remaining[id] {
+			return nil, apperror.InvalidFields(apperror.Field("image_order[]", fmt.Sprintf("image %d cannot be ordered", id)))
+		}
+		resolved = append(resolved, id)
+		delete(remaining, id)
+	}
+
+	for _, postImage := range postImages {
+		if remaining[postImage.ID] {
+			resolved = append(resolved, postImage.ID)
+		}
+	}
+
+	return resolved, nil
+}
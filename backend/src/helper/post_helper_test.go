@@ -0,0 +1,52 @@
+package helper
+
+import (
+	"reflect"
+	"testing"
+
+	"proto-pulse-plat/domain/apperror"
+	"proto-pulse-plat/domain/entity"
+)
+
+func TestResolveImageOrder(t *testing.T) {
+	postImages := []entity.PostImage{{ID: 1}, {ID: 2}, {ID: 3}}
+
+	tests := []struct {
+		name           string
+		deleteImageIDs []uint
+		order          []uint
+		want           []uint
+		wantField      string
+	}{
+		{name: "keeps current order", want: []uint{1, 2, 3}},
+		{name: "full order", order: []uint{3, 1, 2}, want: []uint{3, 1, 2}},
+		// 指定されなかった画像は現在の順序で末尾に続く
+		{name: "partial order", order: []uint{3}, want: []uint{3, 1, 2}},
+		{name: "delete", deleteImageIDs: []uint{2}, want: []uint{1, 3}},
+		{name: "delete and order", deleteImageIDs: []uint{1}, order: []uint{3, 2}, want: []uint{3, 2}},
+		{name: "delete all", deleteImageIDs: []uint{1, 2, 3}, want: []uint{}},
+		{name: "delete image of another post", deleteImageIDs: []uint{4}, wantField: "delete_image_ids[]"},
+		{name: "delete twice", deleteImageIDs: []uint{1, 1}, wantField: "delete_image_ids[]"},
+		{name: "order image of another post", order: []uint{4}, wantField: "image_order[]"},
+		{name: "order deleted image", deleteImageIDs: []uint{1}, order: []uint{1}, wantField: "image_order[]"},
+		{name: "order twice", order: []uint{2, 2}, wantField: "image_order[]"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ResolveImageOrder(postImages, tt.deleteImageIDs, tt.order)
+			if tt.wantField != "" {
+				appErr, ok := apperror.As(err)
+				if !ok || len(appErr.Details) != 1 || appErr.Details[0].Field != tt.wantField {
+					t.Fatalf("err = %v, want a violation of %s", err, tt.wantField)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ResolveImageOrder: %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ResolveImageOrder = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,24 @@
+package helper
+
+import (
+	"proto-pulse-plat/domain/entity"
+	"proto-pulse-plat/infrastructure/response"
+	"time"
+)
+
+func BuildSessionListResponse(sessions []entity.Session, currentSessionID string) response.SessionList {
+	responseSessions := make([]response.Session, 0, len(sessions))
+	for _, session := range sessions {
+		responseSessions = append(responseSessions, response.Session{
+			ID:         session.ID,
+			UserAgent:  session.UserAgent,
+			LastSeenAt: session.LastSeenAt.Format(time.RFC3339),
+			CreatedAt:  session.CreatedAt.Format(time.RFC3339),
+			Current:    session.ID == currentSessionID,
+		})
+	}
+
+	return response.SessionList{
+		Sessions: responseSessions,
+	}
+}
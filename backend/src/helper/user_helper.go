@@ -1,22 +1,28 @@
 package helper
 
 import (
-	"encoding/base64"
-	"fmt"
+	"path"
 	"proto-pulse-plat/domain/entity"
 	"proto-pulse-plat/infrastructure/response"
+	"strings"
 )
 
 func BuildUserResponse(
 	user entity.User,
 ) *response.User {
 	return &response.User{
-		ID:        user.ID,
-		UserName:  user.UserName,
-		AccountID: user.AccountID,
-		IconImageBase64: fmt.Sprintf(
-			"data:%s;base64,%s",
-			getImageBase64(user.IconFileName),
-			base64.StdEncoding.EncodeToString(user.IconData)),
+		ID:           user.ID,
+		UserName:     user.UserName,
+		AccountID:    user.AccountID,
+		IconImageURL: UserIconURL(user),
 	}
 }
+
+// X のプロフィール画像URLから原寸画像のURLを返す
+func OriginalProfileImageURL(profileImageURL string) string {
+	return strings.Replace(profileImageURL, "_normal", "", 1)
+}
+
+func IconFileName(iconURL string) string {
+	return path.Base(iconURL)
+}
@@ -0,0 +1,19 @@
+package blobstore
+
+import (
+	"fmt"
+	"proto-pulse-plat/config"
+	"proto-pulse-plat/domain/storage"
+)
+
+// BLOB_STORE の設定に応じた保存先を返す
+func New(blobStoreConfig *config.BlobStoreConfig) (storage.BlobStore, error) {
+	switch blobStoreConfig.Driver {
+	case config.BlobStoreLocal:
+		return NewLocalStore(blobStoreConfig.LocalDir)
+	case config.BlobStoreS3:
+		return NewS3Store(blobStoreConfig.S3)
+	default:
+		return nil, fmt.Errorf("blob store %q is not supported", blobStoreConfig.Driver)
+	}
+}
@@ -0,0 +1,26 @@
+package blobstore
+
+import (
+	"fmt"
+	"path"
+	"strings"
+)
+
+const (
+	PostImageNamespace        = "post-images"
+	PostImageVariantNamespace = "post-image-variants"
+	UserIconNamespace         = "user-icons"
+)
+
+// 内容の SHA-256 から決まるキー。同じキーの内容は変わらない
+func ContentKey(namespace, checksum string) string {
+	return path.Join(namespace, checksum[:2], checksum)
+}
+
+// ".." や絶対パスを含むキーと、保存先そのものを指すキーを拒否する
+func validateKey(key string) error {
+	if key == "" || key == "." || strings.HasPrefix(key, "/") || path.Clean(key) != key || strings.HasPrefix(key, "..") {
+		return fmt.Errorf("invalid blob key %q", key)
+	}
+	return nil
+}
@@ -0,0 +1,43 @@
+package blobstore
+
+import "testing"
+
+func TestValidateKey(t *testing.T) {
+	tests := []struct {
+		key       string
+		wantValid bool
+	}{
+		{key: "post-images/ab/abcdef", wantValid: true},
+		{key: "user-icons/1", wantValid: true},
+		{key: "file", wantValid: true},
+		{key: "", wantValid: false},
+		{key: ".", wantValid: false},
+		{key: "..", wantValid: false},
+		{key: "../etc/passwd", wantValid: false},
+		{key: "post-images/../../etc/passwd", wantValid: false},
+		{key: "post-images/../user-icons/1", wantValid: false},
+		{key: "/etc/passwd", wantValid: false},
+		{key: "post-images/./ab", wantValid: false},
+		{key: "post-images//ab", wantValid: false},
+		{key: "post-images/ab/", wantValid: false},
+		{key: "..hidden", wantValid: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.key, func(t *testing.T) {
+			if err := validateKey(tt.key); (err == nil) != tt.wantValid {
+				t.Errorf("validateKey(%q) = %v, want valid %v", tt.key, err, tt.wantValid)
+			}
+		})
+	}
+}
+
+func TestContentKey(t *testing.T) {
+	checksum := "ab3f0c"
+	key := ContentKey(PostImageNamespace, checksum)
+	if key != "post-images/ab/ab3f0c" {
+		t.Errorf("ContentKey = %s, want post-images/ab/ab3f0c", key)
+	}
+	if err := validateKey(key); err != nil {
+		t.Errorf("ContentKey returned an invalid key: %v", err)
+	}
+}
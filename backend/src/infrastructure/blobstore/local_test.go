@@ -0,0 +1,97 @@
+package blobstore
+
+import (
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"proto-pulse-plat/domain/storage"
+)
+
+func TestLocalStore(t *testing.T) {
+	store, err := NewLocalStore(filepath.Join(t.TempDir(), "blobs"))
+	if err != nil {
+		t.Fatalf("NewLocalStore: %v", err)
+	}
+
+	key := ContentKey(PostImageNamespace, "ab3f0c")
+	if err := store.Put(key, []byte("image data"), "image/png"); err != nil {
+		t.Fatalf("Put: %v", err)
+	}
+	if got := readBlob(t, store, key); got != "image data" {
+		t.Errorf("Get = %q, want %q", got, "image data")
+	}
+
+	// 同じキーへの保存は上書きし、一時ファイルを残さない
+	if err := store.Put(key, []byte("replaced"), "image/png"); err != nil {
+		t.Fatalf("Put: %v", err)
+	}
+	if got := readBlob(t, store, key); got != "replaced" {
+		t.Errorf("Get = %q, want %q", got, "replaced")
+	}
+	entries, err := os.ReadDir(filepath.Join(store.root, PostImageNamespace, "ab"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 1 {
+		t.Errorf("blob directory has %d entries, want 1", len(entries))
+	}
+
+	if err := store.Delete(key); err != nil {
+		t.Fatalf("Delete: %v", err)
+	}
+	if _, err := store.Get(key); !errors.Is(err, storage.ErrBlobNotFound) {
+		t.Errorf("Get after Delete = %v, want ErrBlobNotFound", err)
+	}
+	if err := store.Delete(key); err != nil {
+		t.Errorf("Delete of a missing blob = %v, want nil", err)
+	}
+}
+
+func TestLocalStoreRejectsTraversal(t *testing.T) {
+	dir := t.TempDir()
+	store, err := NewLocalStore(filepath.Join(dir, "blobs"))
+	if err != nil {
+		t.Fatalf("NewLocalStore: %v", err)
+	}
+	outside := filepath.Join(dir, "outside")
+	if err := os.WriteFile(outside, []byte("secret"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, key := range []string{"../outside", "post-images/../../outside", outside, "."} {
+		t.Run(key, func(t *testing.T) {
+			if err := store.Put(key, []byte("overwritten"), "text/plain"); err == nil {
+				t.Error("Put accepted the key")
+			}
+			if _, err := store.Get(key); err == nil || errors.Is(err, storage.ErrBlobNotFound) {
+				t.Errorf("Get = %v, want a key error", err)
+			}
+			if err := store.Delete(key); err == nil {
+				t.Error("Delete accepted the key")
+			}
+		})
+	}
+
+	data, err := os.ReadFile(outside)
+	if err != nil || string(data) != "secret" {
+		t.Errorf("file outside the store = %q, %v; want it untouched", data, err)
+	}
+}
+
+func readBlob(t *testing.T, store storage.BlobStore, key string) string {
+	t.Helper()
+
+	blob, err := store.Get(key)
+	if err != nil {
+		t.Fatalf("Get: %v", err)
+	}
+	defer blob.Close()
+	data, err := io.ReadAll(blob)
+	if err != nil {
+		t.Fatalf("read blob: %v", err)
+	}
+	return string(data)
+}
@@ -0,0 +1,168 @@
+package blobstore
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"proto-pulse-plat/config"
+	"proto-pulse-plat/domain/storage"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/aws/credentials"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/s3"
+)
+
+// S3 と S3 互換のストレージに保存する
+type S3Store struct {
+	client *s3.S3
+	bucket string
+}
+
+func NewS3Store(s3Config config.S3Config) (*S3Store, error) {
+	awsConfig := aws.NewConfig().
+		WithRegion(s3Config.Region).
+		WithS3ForcePathStyle(s3Config.ForcePathStyle)
+	if s3Config.Endpoint != "" {
+		awsConfig = awsConfig.WithEndpoint(s3Config.Endpoint)
+	}
+	// 指定が無い場合は環境変数や IAM ロールなど SDK の既定の方法で認証する
+	if s3Config.AccessKeyID != "" {
+		awsConfig = awsConfig.WithCredentials(
+			credentials.NewStaticCredentials(s3Config.AccessKeyID, s3Config.SecretAccessKey, ""),
+		)
+	}
+
+	sess, err := session.NewSession(awsConfig)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create S3 session: %w", err)
+	}
+
+	return &S3Store{
+		client: s3.New(sess),
+		bucket: s3Config.Bucket,
+	}, nil
+}
+
+func (s *S3Store) Put(key string, data []byte, contentType string) error {
+	if err := validateKey(key); err != nil {
+		return err
+	}
+
+	_, err := s.client.PutObject(&s3.PutObjectInput{
+		Bucket:        aws.String(s.bucket),
+		Key:           aws.String(key),
+		Body:          bytes.NewReader(data),
+		ContentLength: aws.Int64(int64(len(data))),
+		ContentType:   aws.String(contentType),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to put blob %q: %w", key, err)
+	}
+	return nil
+}
+
+// 大きさだけ先に確認し、内容は読み込むときに読む位置から Range 指定で取得する
+func (s *S3Store) Get(key string) (io.ReadSeekCloser, error) {
+	if err := validateKey(key); err != nil {
+		return nil, err
+	}
+
+	output, err := s.client.HeadObject(&s3.HeadObjectInput{
+		Bucket: aws.String(s.bucket),
+		Key:    aws.String(key),
+	})
+	if err != nil {
+		// HEAD のレスポンスには本文が無いため、NoSuchKey ではなく 404 だけが返る
+		var requestErr awserr.RequestFailure
+		if errors.As(err, &requestErr) && requestErr.StatusCode() == http.StatusNotFound {
+			return nil, fmt.Errorf("blob %q: %w", key, storage.ErrBlobNotFound)
+		}
+		return nil, fmt.Errorf("failed to get blob %q: %w", key, err)
+	}
+
+	return &s3Object{store: s, key: key, size: aws.Int64Value(output.ContentLength)}, nil
+}
+
+// S3 の DeleteObject は存在しないキーでも成功する
+func (s *S3Store) Delete(key string) error {
+	if err := validateKey(key); err != nil {
+		return err
+	}
+
+	_, err := s.client.DeleteObject(&s3.DeleteObjectInput{
+		Bucket: aws.String(s.bucket),
+		Key:    aws.String(key),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to delete blob %q: %w", key, err)
+	}
+	return nil
+}
+
+// Seek では取得せず、次に Read したときに現在の位置から末尾までを取得する
+type s3Object struct {
+	store  *S3Store
+	key    string
+	size   int64
+	offset int64
+	body   io.ReadCloser
+}
+
+func (o *s3Object) Read(p []byte) (int, error) {
+	if o.offset >= o.size {
+		return 0, io.EOF
+	}
+
+	if o.body == nil {
+		output, err := o.store.client.GetObject(&s3.GetObjectInput{
+			Bucket: aws.String(o.store.bucket),
+			Key:    aws.String(o.key),
+			Range:  aws.String(fmt.Sprintf("bytes=%d-", o.offset)),
+		})
+		if err != nil {
+			return 0, fmt.Errorf("failed to get blob %q: %w", o.key, err)
+		}
+		o.body = output.Body
+	}
+
+	n, err := o.body.Read(p)
+	o.offset += int64(n)
+	return n, err
+}
+
+func (o *s3Object) Seek(offset int64, whence int) (int64, error) {
+	var position int64
+	switch whence {
+	case io.SeekStart:
+		position = offset
+	case io.SeekCurrent:
+		position = o.offset + offset
+	case io.SeekEnd:
+		position = o.size + offset
+	default:
+		return 0, fmt.Errorf("invalid whence %d", whence)
+	}
+	if position < 0 {
+		return 0, fmt.Errorf("cannot seek blob %q to negative position %d", o.key, position)
+	}
+
+	if position != o.offset && o.body != nil {
+		o.body.Close()
+		o.body = nil
+	}
+	o.offset = position
+	return position, nil
+}
+
+func (o *s3Object) Close() error {
+	if o.body == nil {
+		return nil
+	}
+	err := o.body.Close()
+	o.body = nil
+	return err
+}
@@ -0,0 +1,216 @@
+package blobstore
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"proto-pulse-plat/config"
+	"proto-pulse-plat/domain/storage"
+)
+
+const testBucket = "test-bucket"
+
+// パス形式でアクセスされる S3 互換のサーバー。GetObject の Range を記録する
+type fakeS3 struct {
+	mu           sync.Mutex
+	objects      map[string][]byte
+	contentTypes map[string]string
+	ranges       []string
+}
+
+func (f *fakeS3) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	key, ok := strings.CutPrefix(r.URL.Path, "/"+testBucket+"/")
+	if !ok {
+		http.Error(w, "unknown bucket", http.StatusNotFound)
+		return
+	}
+
+	switch r.Method {
+	case http.MethodPut:
+		data, err := io.ReadAll(r.Body)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		f.objects[key] = data
+		f.contentTypes[key] = r.Header.Get("Content-Type")
+	case http.MethodDelete:
+		delete(f.objects, key)
+		w.WriteHeader(http.StatusNoContent)
+	case http.MethodHead, http.MethodGet:
+		data, ok := f.objects[key]
+		if !ok {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		if r.Method == http.MethodGet {
+			f.ranges = append(f.ranges, r.Header.Get("Range"))
+		}
+		http.ServeContent(w, r, key, time.Time{}, bytes.NewReader(data))
+	default:
+		w.WriteHeader(http.StatusMethodNotAllowed)
+	}
+}
+
+func (f *fakeS3) requestedRanges() []string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return append([]string(nil), f.ranges...)
+}
+
+func newTestS3Store(t *testing.T) (*S3Store, *fakeS3) {
+	t.Helper()
+
+	fake := &fakeS3{objects: make(map[string][]byte), contentTypes: make(map[string]string)}
+	server := httptest.NewServer(fake)
+	t.Cleanup(server.Close)
+
+	store, err := NewS3Store(config.S3Config{
+		Bucket:          testBucket,
+		Region:          "us-east-1",
+		Endpoint:        server.URL,
+		AccessKeyID:     "access-key",
+		SecretAccessKey: "secret-key",
+		ForcePathStyle:  true,
+	})
+	if err != nil {
+		t.Fatalf("NewS3Store: %v", err)
+	}
+	return store, fake
+}
+
+func TestS3Store(t *testing.T) {
+	store, fake := newTestS3Store(t)
+
+	key := ContentKey(PostImageNamespace, "ab3f0c")
+	if err := store.Put(key, []byte("image data"), "image/png"); err != nil {
+		t.Fatalf("Put: %v", err)
+	}
+	if got := fake.contentTypes[key]; got != "image/png" {
+		t.Errorf("Content-Type = %q, want image/png", got)
+	}
+	if got := readBlob(t, store, key); got != "image data" {
+		t.Errorf("Get = %q, want %q", got, "image data")
+	}
+
+	if err := store.Delete(key); err != nil {
+		t.Fatalf("Delete: %v", err)
+	}
+	if _, err := store.Get(key); !errors.Is(err, storage.ErrBlobNotFound) {
+		t.Errorf("Get after Delete = %v, want ErrBlobNotFound", err)
+	}
+}
+
+func TestS3StoreRejectsTraversal(t *testing.T) {
+	store, fake := newTestS3Store(t)
+
+	for _, key := range []string{"../other-bucket/key", "post-images/../../key", "/key"} {
+		t.Run(key, func(t *testing.T) {
+			if err := store.Put(key, []byte("data"), "text/plain"); err == nil {
+				t.Error("Put accepted the key")
+			}
+			if _, err := store.Get(key); err == nil {
+				t.Error("Get accepted the key")
+			}
+			if err := store.Delete(key); err == nil {
+				t.Error("Delete accepted the key")
+			}
+		})
+	}
+
+	// 検証で拒否したキーはリクエストを送らない
+	if len(fake.objects) != 0 {
+		t.Errorf("fake S3 has objects %v, want none", fake.objects)
+	}
+}
+
+func TestS3ObjectRangedRead(t *testing.T) {
+	store, fake := newTestS3Store(t)
+	key := "post-images/ab/ranged"
+	fake.objects[key] = []byte("0123456789abcdef")
+
+	blob, err := store.Get(key)
+	if err != nil {
+		t.Fatalf("Get: %v", err)
+	}
+	defer blob.Close()
+
+	// 大きさは HEAD で確認し、内容は Read するまで取得しない
+	end, err := blob.Seek(0, io.SeekEnd)
+	if err != nil || end != 16 {
+		t.Fatalf("Seek(0, SeekEnd) = %d, %v; want 16", end, err)
+	}
+	if ranges := fake.requestedRanges(); len(ranges) != 0 {
+		t.Fatalf("Seek requested %v", ranges)
+	}
+
+	steps := []struct {
+		offset    int64
+		whence    int
+		size      int
+		want      string
+		wantRange string
+	}{
+		{offset: 10, whence: io.SeekStart, size: 3, want: "abc", wantRange: "bytes=10-"},
+		// 続けて読む場合は同じレスポンスを読み進める
+		{offset: 0, whence: io.SeekCurrent, size: 3, want: "def"},
+		{offset: -16, whence: io.SeekEnd, size: 4, want: "0123", wantRange: "bytes=0-"},
+		{offset: 2, whence: io.SeekCurrent, size: 2, want: "67", wantRange: "bytes=6-"},
+	}
+	for _, step := range steps {
+		if _, err := blob.Seek(step.offset, step.whence); err != nil {
+			t.Fatalf("Seek(%d, %d): %v", step.offset, step.whence, err)
+		}
+		before := len(fake.requestedRanges())
+
+		buf := make([]byte, step.size)
+		if _, err := io.ReadFull(blob, buf); err != nil {
+			t.Fatalf("read after Seek(%d, %d): %v", step.offset, step.whence, err)
+		}
+		if string(buf) != step.want {
+			t.Errorf("read after Seek(%d, %d) = %q, want %q", step.offset, step.whence, buf, step.want)
+		}
+
+		ranges := fake.requestedRanges()[before:]
+		if step.wantRange == "" && len(ranges) != 0 {
+			t.Errorf("Seek(%d, %d) requested %v, want the open response reused", step.offset, step.whence, ranges)
+		}
+		if step.wantRange != "" && (len(ranges) != 1 || ranges[0] != step.wantRange) {
+			t.Errorf("Seek(%d, %d) requested %v, want %s", step.offset, step.whence, ranges, step.wantRange)
+		}
+	}
+
+	// 末尾以降は取得せずに EOF を返す
+	if _, err := blob.Seek(0, io.SeekEnd); err != nil {
+		t.Fatal(err)
+	}
+	before := len(fake.requestedRanges())
+	if n, err := blob.Read(make([]byte, 1)); n != 0 || err != io.EOF {
+		t.Errorf("Read at the end = %d, %v; want io.EOF", n, err)
+	}
+	if len(fake.requestedRanges()) != before {
+		t.Error("Read at the end requested the object")
+	}
+
+	if _, err := blob.Seek(-1, io.SeekStart); err == nil {
+		t.Error("Seek to a negative position succeeded")
+	}
+}
+
+func TestS3StoreGetMissing(t *testing.T) {
+	store, _ := newTestS3Store(t)
+
+	if _, err := store.Get("post-images/ab/missing"); !errors.Is(err, storage.ErrBlobNotFound) {
+		t.Errorf("Get = %v, want ErrBlobNotFound", err)
+	}
+}
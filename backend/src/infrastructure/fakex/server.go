@@ -0,0 +1,452 @@
+package fakex
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"html/template"
+	"image"
+	"image/color"
+	"image/png"
+	"log"
+	"net/http"
+	"net/url"
+	"proto-pulse-plat/config"
+	"proto-pulse-plat/helper"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gorilla/mux"
+)
+
+// 発行したリクエストトークンとアクセストークンを保持する期間
+const tokenTTL = 10 * time.Minute
+
+// ローカル開発・結合テスト用に X の OAuth 1.0a を模したサーバー。
+// 本物の X と同じく HMAC-SHA1 の署名を、コンシューマーシークレットとトークンのシークレットで検証する
+type Server struct {
+	config *config.FakeXConfig
+
+	mu            sync.Mutex
+	requestTokens map[string]*requestToken
+	accessTokens  map[string]*accessToken
+}
+
+type requestToken struct {
+	secret    string
+	callback  string
+	verifier  string
+	persona   *config.FakeXPersona
+	expiresAt time.Time
+}
+
+type accessToken struct {
+	secret    string
+	persona   config.FakeXPersona
+	expiresAt time.Time
+}
+
+type verifyCredentialsResponse struct {
+	IDStr           string `json:"id_str"`
+	Name            string `json:"name"`
+	ScreenName      string `json:"screen_name"`
+	ProfileImageUrl string `json:"profile_image_url_https"`
+}
+
+func NewServer(fakeXConfig *config.FakeXConfig) *Server {
+	return &Server{
+		config:        fakeXConfig,
+		requestTokens: make(map[string]*requestToken),
+		accessTokens:  make(map[string]*accessToken),
+	}
+}
+
+// FakeXPathPrefix を取り除いたパスで受け付けるハンドラーを返す
+func (s *Server) Handler() http.Handler {
+	r := mux.NewRouter()
+	r.HandleFunc("/oauth/request_token", s.RequestToken).Methods(http.MethodPost)
+	r.HandleFunc("/oauth/authorize", s.Authorize).Methods(http.MethodGet)
+	r.HandleFunc("/oauth/access_token", s.AccessToken).Methods(http.MethodPost)
+	r.HandleFunc("/1.1/account/verify_credentials.json", s.VerifyCredentials).Methods(http.MethodGet)
+	r.HandleFunc("/avatar/{screenName}.png", s.Avatar).Methods(http.MethodGet)
+	return r
+}
+
+func (s *Server) RequestToken(w http.ResponseWriter, r *http.Request) {
+	params, err := authorizationParams(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusUnauthorized)
+		return
+	}
+
+	// トークンを持たないため、コンシューマーシークレットのみで署名される
+	if err := s.verifySignature(r, params, ""); err != nil {
+		http.Error(w, err.Error(), http.StatusUnauthorized)
+		return
+	}
+
+	callback := params.Get("oauth_callback")
+	if callback == "" {
+		http.Error(w, "oauth_callback is required", http.StatusBadRequest)
+		return
+	}
+
+	token, secret, err := newTokenPair()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	s.mu.Lock()
+	s.removeExpiredTokens(time.Now())
+	s.requestTokens[token] = &requestToken{secret: secret, callback: callback, expiresAt: time.Now().Add(tokenTTL)}
+	s.mu.Unlock()
+
+	writeForm(w, url.Values{
+		"oauth_token":              {token},
+		"oauth_token_secret":       {secret},
+		"oauth_callback_confirmed": {"true"},
+	})
+}
+
+var authorizeTemplate = template.Must(template.New("authorize").Parse(`<!DOCTYPE html>
+<html lang="ja">
+<head><meta charset="utf-8"><title>Fake X</title></head>
+<body>
+<h1>ログインするテストユーザーを選択</h1>
+<ul>
+{{range .Personas}}<li><a href="?oauth_token={{$.Token}}&amp;persona={{.ScreenName}}">{{.Name}} (@{{.ScreenName}})</a></li>
+{{end}}</ul>
+</body>
+</html>
+`))
+
+// persona を指定しない場合は選択画面を表示する。結合テストでは persona を直接指定できる
+func (s *Server) Authorize(w http.ResponseWriter, r *http.Request) {
+	token := r.URL.Query().Get("oauth_token")
+	screenName := r.URL.Query().Get("persona")
+
+	s.mu.Lock()
+	pending, ok := s.requestTokens[token]
+	ok = ok && time.Now().Before(pending.expiresAt)
+	s.mu.Unlock()
+	if !ok {
+		http.Error(w, "unknown oauth_token", http.StatusBadRequest)
+		return
+	}
+
+	if screenName == "" {
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		err := authorizeTemplate.Execute(w, map[string]any{
+			"Token":    token,
+			"Personas": s.config.Personas,
+		})
+		if err != nil {
+			log.Println("Error rendering fake X authorize page:", err)
+		}
+		return
+	}
+
+	persona, ok := s.findPersona(screenName)
+	if !ok {
+		http.Error(w, "unknown persona", http.StatusBadRequest)
+		return
+	}
+
+	verifier, err := helper.GenerateNonce(16)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	s.mu.Lock()
+	pending.verifier = verifier
+	pending.persona = &persona
+	s.mu.Unlock()
+
+	callbackURL, err := url.Parse(pending.callback)
+	if err != nil {
+		http.Error(w, "invalid oauth_callback", http.StatusBadRequest)
+		return
+	}
+	query := callbackURL.Query()
+	query.Set("oauth_token", token)
+	query.Set("oauth_verifier", verifier)
+	callbackURL.RawQuery = query.Encode()
+
+	http.Redirect(w, r, callbackURL.String(), http.StatusFound)
+}
+
+func (s *Server) AccessToken(w http.ResponseWriter, r *http.Request) {
+	params, err := authorizationParams(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusUnauthorized)
+		return
+	}
+
+	token := params.Get("oauth_token")
+
+	// リクエストトークンは成否にかかわらず一度だけ交換を試せる
+	s.mu.Lock()
+	pending, ok := s.requestTokens[token]
+	delete(s.requestTokens, token)
+	s.mu.Unlock()
+	if !ok || time.Now().After(pending.expiresAt) {
+		http.Error(w, "invalid oauth_token", http.StatusUnauthorized)
+		return
+	}
+
+	// アクセストークンの取得はリクエストトークンのシークレットで署名される
+	if err := s.verifySignature(r, params, pending.secret); err != nil {
+		http.Error(w, err.Error(), http.StatusUnauthorized)
+		return
+	}
+
+	verifier := params.Get("oauth_verifier")
+	if pending.persona == nil || subtle.ConstantTimeCompare([]byte(verifier), []byte(pending.verifier)) != 1 {
+		http.Error(w, "invalid oauth_verifier", http.StatusUnauthorized)
+		return
+	}
+
+	token, secret, err := newTokenPair()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	s.mu.Lock()
+	s.accessTokens[token] = &accessToken{secret: secret, persona: *pending.persona, expiresAt: time.Now().Add(tokenTTL)}
+	s.mu.Unlock()
+
+	writeForm(w, url.Values{
+		"oauth_token":        {token},
+		"oauth_token_secret": {secret},
+		"user_id":            {pending.persona.IDStr},
+		"screen_name":        {pending.persona.ScreenName},
+	})
+}
+
+func (s *Server) VerifyCredentials(w http.ResponseWriter, r *http.Request) {
+	params, err := authorizationParams(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusUnauthorized)
+		return
+	}
+
+	s.mu.Lock()
+	token, ok := s.accessTokens[params.Get("oauth_token")]
+	s.mu.Unlock()
+	if !ok || time.Now().After(token.expiresAt) {
+		http.Error(w, "invalid access token", http.StatusUnauthorized)
+		return
+	}
+
+	if err := s.verifySignature(r, params, token.secret); err != nil {
+		http.Error(w, err.Error(), http.StatusUnauthorized)
+		return
+	}
+	persona := token.persona
+
+	w.Header().Set("Content-Type", "application/json")
+	err = json.NewEncoder(w).Encode(verifyCredentialsResponse{
+		IDStr:      persona.IDStr,
+		Name:       persona.Name,
+		ScreenName: persona.ScreenName,
+		// 本物の X と同じく _normal 付きの URL を返す
+		ProfileImageUrl: s.config.BaseURL + "/avatar/" + url.PathEscape(persona.ScreenName) + "_normal.png",
+	})
+	if err != nil {
+		log.Println("Error encoding verify_credentials response:", err)
+	}
+}
+
+// スクリーンネームから決まる単色の画像を返す
+func (s *Server) Avatar(w http.ResponseWriter, r *http.Request) {
+	screenName, size := mux.Vars(r)["screenName"], 400
+	if trimmed, ok := strings.CutSuffix(screenName, "_normal"); ok {
+		screenName, size = trimmed, 48
+	}
+
+	if _, ok := s.findPersona(screenName); !ok {
+		http.NotFound(w, r)
+		return
+	}
+
+	sum := sha256.Sum256([]byte(screenName))
+	fill := color.RGBA{R: sum[0], G: sum[1], B: sum[2], A: 0xff}
+
+	img := image.NewRGBA(image.Rect(0, 0, size, size))
+	for y := 0; y < size; y++ {
+		for x := 0; x < size; x++ {
+			img.Set(x, y, fill)
+		}
+	}
+
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "image/png")
+	w.Write(buf.Bytes())
+}
+
+func (s *Server) findPersona(screenName string) (config.FakeXPersona, bool) {
+	for _, persona := range s.config.Personas {
+		if persona.ScreenName == screenName {
+			return persona, true
+		}
+	}
+	return config.FakeXPersona{}, false
+}
+
+// Authorization: OAuth ヘッダーのパラメーターを取り出し、コンシューマーキーを確認する
+func authorizationParams(r *http.Request) (url.Values, error) {
+	header, ok := strings.CutPrefix(r.Header.Get("Authorization"), "OAuth ")
+	if !ok {
+		return nil, errors.New("missing OAuth authorization header")
+	}
+
+	params := url.Values{}
+	for _, pair := range strings.Split(header, ",") {
+		key, value, ok := strings.Cut(strings.TrimSpace(pair), "=")
+		if !ok {
+			continue
+		}
+		value, err := url.QueryUnescape(strings.Trim(value, `"`))
+		if err != nil {
+			return nil, errors.New("malformed OAuth authorization header")
+		}
+		params.Set(key, value)
+	}
+
+	if params.Get("oauth_consumer_key") != config.FakeXConsumerKey {
+		return nil, errors.New("unknown oauth_consumer_key")
+	}
+
+	return params, nil
+}
+
+// 期限切れのトークンを削除する。呼び出し側で mu をロックする
+func (s *Server) removeExpiredTokens(now time.Time) {
+	for token, pending := range s.requestTokens {
+		if now.After(pending.expiresAt) {
+			delete(s.requestTokens, token)
+		}
+	}
+	for token, issued := range s.accessTokens {
+		if now.After(issued.expiresAt) {
+			delete(s.accessTokens, token)
+		}
+	}
+}
+
+// RFC 5849 3.4 の HMAC-SHA1 署名を検証する。URL はクライアントが使う BaseURL で組み立てる
+func (s *Server) verifySignature(r *http.Request, params url.Values, tokenSecret string) error {
+	if params.Get("oauth_signature_method") != "HMAC-SHA1" {
+		return errors.New("unsupported oauth_signature_method")
+	}
+
+	signature, err := base64.StdEncoding.DecodeString(params.Get("oauth_signature"))
+	if err != nil || len(signature) == 0 {
+		return errors.New("malformed oauth_signature")
+	}
+
+	baseString, err := s.signatureBaseString(r, params)
+	if err != nil {
+		return err
+	}
+
+	mac := hmac.New(sha1.New, []byte(percentEncode(config.FakeXConsumerSecret)+"&"+percentEncode(tokenSecret)))
+	mac.Write([]byte(baseString))
+	if !hmac.Equal(signature, mac.Sum(nil)) {
+		return errors.New("invalid oauth_signature")
+	}
+	return nil
+}
+
+func (s *Server) signatureBaseString(r *http.Request, params url.Values) (string, error) {
+	baseURL, err := url.Parse(s.config.BaseURL + r.URL.Path)
+	if err != nil {
+		return "", err
+	}
+	host := strings.ToLower(baseURL.Host)
+	if h, port, ok := strings.Cut(host, ":"); ok && (port == "80" || port == "443") {
+		host = h
+	}
+	baseURI := strings.ToLower(baseURL.Scheme) + "://" + host + baseURL.EscapedPath()
+
+	// Authorization ヘッダー・クエリ・フォーム本文のパラメーターを合わせて並べる
+	var pairs [][2]string
+	addPairs := func(values url.Values) {
+		for key, list := range values {
+			if key == "oauth_signature" || key == "realm" {
+				continue
+			}
+			for _, value := range list {
+				pairs = append(pairs, [2]string{percentEncode(key), percentEncode(value)})
+			}
+		}
+	}
+	addPairs(params)
+	addPairs(r.URL.Query())
+	if r.Header.Get("Content-Type") == "application/x-www-form-urlencoded" {
+		if err := r.ParseForm(); err != nil {
+			return "", fmt.Errorf("malformed form body: %w", err)
+		}
+		addPairs(r.PostForm)
+	}
+
+	// エンコード後の名前、値の順に並べる
+	sort.Slice(pairs, func(i, j int) bool {
+		if pairs[i][0] != pairs[j][0] {
+			return pairs[i][0] < pairs[j][0]
+		}
+		return pairs[i][1] < pairs[j][1]
+	})
+	joined := make([]string, 0, len(pairs))
+	for _, pair := range pairs {
+		joined = append(joined, pair[0]+"="+pair[1])
+	}
+
+	return strings.ToUpper(r.Method) + "&" + percentEncode(baseURI) + "&" + percentEncode(strings.Join(joined, "&")), nil
+}
+
+// RFC 3986 の非予約文字以外をエンコードする
+func percentEncode(value string) string {
+	var b strings.Builder
+	for _, c := range []byte(value) {
+		if 'A' <= c && c <= 'Z' || 'a' <= c && c <= 'z' || '0' <= c && c <= '9' || c == '-' || c == '.' || c == '_' || c == '~' {
+			b.WriteByte(c)
+			continue
+		}
+		fmt.Fprintf(&b, "%%%02X", c)
+	}
+	return b.String()
+}
+
+func newTokenPair() (string, string, error) {
+	token, err := helper.GenerateNonce(16)
+	if err != nil {
+		return "", "", err
+	}
+	secret, err := helper.GenerateNonce(16)
+	if err != nil {
+		return "", "", err
+	}
+	return token, secret, nil
+}
+
+func writeForm(w http.ResponseWriter, values url.Values) {
+	w.Header().Set("Content-Type", "application/x-www-form-urlencoded")
+	w.Write([]byte(values.Encode()))
+}
@@ -0,0 +1,155 @@
+package fakex
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"proto-pulse-plat/config"
+	"proto-pulse-plat/domain/identity"
+	"proto-pulse-plat/infrastructure/identityprovider"
+)
+
+const testCallbackURL = "https://app.example.com/api/oauth2callback"
+
+// main.go と同じく FakeXPathPrefix の下に偽 X を置き、それに向けた XProvider を返す
+func newTestServer(t *testing.T) (*httptest.Server, *config.Xconfig) {
+	t.Helper()
+
+	mux := http.NewServeMux()
+	server := httptest.NewServer(mux)
+	t.Cleanup(server.Close)
+
+	baseURL := server.URL + config.FakeXPathPrefix
+	fakeX := NewServer(&config.FakeXConfig{
+		BaseURL: baseURL,
+		Personas: []config.FakeXPersona{
+			{IDStr: "1000000001", ScreenName: "alice", Name: "Alice"},
+			{IDStr: "1000000002", ScreenName: "bob", Name: "Bob"},
+		},
+	})
+	mux.Handle(config.FakeXPathPrefix+"/", http.StripPrefix(config.FakeXPathPrefix, fakeX.Handler()))
+
+	return server, &config.Xconfig{
+		RequestTokenURL:   baseURL + "/oauth/request_token",
+		AccessTokenURL:    baseURL + "/oauth/access_token",
+		ConsumerKey:       config.FakeXConsumerKey,
+		ConsumerSecret:    config.FakeXConsumerSecret,
+		AuthorizeURL:      baseURL + "/oauth/authorize",
+		VerifyCredentials: baseURL + "/1.1/account/verify_credentials.json",
+		CallBackURL:       testCallbackURL,
+	}
+}
+
+// ブラウザが認可画面で persona を選び、コールバックにリダイレクトされたときのクエリを返す
+func authorize(t *testing.T, authURL, persona string) url.Values {
+	t.Helper()
+
+	client := &http.Client{CheckRedirect: func(req *http.Request, via []*http.Request) error {
+		return http.ErrUseLastResponse
+	}}
+	resp, err := client.Get(authURL + "&persona=" + url.QueryEscape(persona))
+	if err != nil {
+		t.Fatalf("authorize: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusFound {
+		t.Fatalf("authorize returned %d, want 302", resp.StatusCode)
+	}
+
+	location, err := url.Parse(resp.Header.Get("Location"))
+	if err != nil {
+		t.Fatalf("invalid redirect: %v", err)
+	}
+	if got := location.Scheme + "://" + location.Host + location.Path; got != testCallbackURL {
+		t.Fatalf("redirected to %s, want the callback URL", got)
+	}
+	return location.Query()
+}
+
+func TestXProviderLogin(t *testing.T) {
+	tests := []struct {
+		persona string
+		want    identity.Profile
+	}{
+		{persona: "alice", want: identity.Profile{Provider: identityprovider.XProviderName, ExternalID: "1000000001", Handle: "alice", DisplayName: "Alice"}},
+		{persona: "bob", want: identity.Profile{Provider: identityprovider.XProviderName, ExternalID: "1000000002", Handle: "bob", DisplayName: "Bob"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.persona, func(t *testing.T) {
+			server, xConfig := newTestServer(t)
+			provider := identityprovider.NewXProvider(xConfig)
+
+			authURL, state, err := provider.Begin(context.Background())
+			if err != nil {
+				t.Fatalf("Begin: %v", err)
+			}
+			profile, err := provider.Complete(context.Background(), authorize(t, authURL, tt.persona), state)
+			if err != nil {
+				t.Fatalf("Complete: %v", err)
+			}
+
+			// プロフィール画像は _normal を除いた元の大きさの URL になる
+			tt.want.AvatarURL = server.URL + config.FakeXPathPrefix + "/avatar/" + tt.persona + ".png"
+			if *profile != tt.want {
+				t.Errorf("profile = %+v, want %+v", *profile, tt.want)
+			}
+
+			resp, err := http.Get(profile.AvatarURL)
+			if err != nil {
+				t.Fatalf("avatar: %v", err)
+			}
+			resp.Body.Close()
+			if resp.StatusCode != http.StatusOK || resp.Header.Get("Content-Type") != "image/png" {
+				t.Errorf("avatar returned %d %s, want a PNG", resp.StatusCode, resp.Header.Get("Content-Type"))
+			}
+		})
+	}
+}
+
+func TestXProviderRejectsReusedCallback(t *testing.T) {
+	_, xConfig := newTestServer(t)
+	provider := identityprovider.NewXProvider(xConfig)
+
+	authURL, state, err := provider.Begin(context.Background())
+	if err != nil {
+		t.Fatalf("Begin: %v", err)
+	}
+	callback := authorize(t, authURL, "alice")
+	if _, err := provider.Complete(context.Background(), callback, state); err != nil {
+		t.Fatalf("Complete: %v", err)
+	}
+
+	// リクエストトークンは一度しか交換できない
+	if _, err := provider.Complete(context.Background(), callback, state); err == nil {
+		t.Error("Complete accepted the same callback twice")
+	}
+}
+
+func TestXProviderRejectsWrongVerifier(t *testing.T) {
+	_, xConfig := newTestServer(t)
+	provider := identityprovider.NewXProvider(xConfig)
+
+	authURL, state, err := provider.Begin(context.Background())
+	if err != nil {
+		t.Fatalf("Begin: %v", err)
+	}
+	callback := authorize(t, authURL, "alice")
+	callback.Set("oauth_verifier", "forged")
+
+	if _, err := provider.Complete(context.Background(), callback, state); err == nil {
+		t.Error("Complete accepted a forged oauth_verifier")
+	}
+}
+
+func TestXProviderRejectsWrongConsumerSecret(t *testing.T) {
+	_, xConfig := newTestServer(t)
+	xConfig.ConsumerSecret = "wrong-secret"
+	provider := identityprovider.NewXProvider(xConfig)
+
+	if _, _, err := provider.Begin(context.Background()); err == nil {
+		t.Error("Begin succeeded with a request signed by the wrong consumer secret")
+	}
+}
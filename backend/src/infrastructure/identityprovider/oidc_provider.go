@@ -0,0 +1,262 @@
+package identityprovider
+
+import (
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"proto-pulse-plat/config"
+	"proto-pulse-plat/domain/identity"
+	"proto-pulse-plat/helper"
+	"slices"
+	"strings"
+	"time"
+
+	"golang.org/x/oauth2"
+)
+
+// OAuth2 / OpenID Connect の認可コードフロー (PKCE) によるログイン。
+// ISSUER_URL を指定した場合は OpenID Connect として ID トークンを検証し、その sub をユーザーの ID にする。
+// 指定しない場合は ID トークンの無い OAuth2 プロバイダー (GitHub など) として userinfo の IDClaim を使う
+type OIDCProvider struct {
+	providerConfig  config.OIDCProviderConfig
+	oauthConfig     *oauth2.Config
+	userInfoURL     string
+	idTokenVerifier *idTokenVerifier
+}
+
+type discoveryDocument struct {
+	Issuer                string `json:"issuer"`
+	AuthorizationEndpoint string `json:"authorization_endpoint"`
+	TokenEndpoint         string `json:"token_endpoint"`
+	UserInfoEndpoint      string `json:"userinfo_endpoint"`
+	JWKSURI               string `json:"jwks_uri"`
+}
+
+func NewOIDCProvider(ctx context.Context, providerConfig config.OIDCProviderConfig) (*OIDCProvider, error) {
+	authURL := providerConfig.AuthURL
+	tokenURL := providerConfig.TokenURL
+	userInfoURL := providerConfig.UserInfoURL
+	scopes := providerConfig.Scopes
+
+	// ID トークンの検証に発行者と公開鍵の URL が必要なため、エンドポイントをすべて指定した場合も取得する
+	var verifier *idTokenVerifier
+	if providerConfig.IssuerURL != "" {
+		document, err := discover(ctx, providerConfig.IssuerURL)
+		if err != nil {
+			return nil, fmt.Errorf("failed to discover %s: %w", providerConfig.Name, err)
+		}
+		if strings.TrimSuffix(document.Issuer, "/") != strings.TrimSuffix(providerConfig.IssuerURL, "/") {
+			return nil, fmt.Errorf("%s discovery issuer %q does not match %q", providerConfig.Name, document.Issuer, providerConfig.IssuerURL)
+		}
+		if document.JWKSURI == "" {
+			return nil, fmt.Errorf("%s discovery has no jwks_uri", providerConfig.Name)
+		}
+		verifier = &idTokenVerifier{
+			issuer:   document.Issuer,
+			clientID: providerConfig.ClientID,
+			keys:     newKeySet(document.JWKSURI),
+		}
+		if !slices.Contains(scopes, "openid") {
+			scopes = append([]string{"openid"}, scopes...)
+		}
+		if authURL == "" {
+			authURL = document.AuthorizationEndpoint
+		}
+		if tokenURL == "" {
+			tokenURL = document.TokenEndpoint
+		}
+		if userInfoURL == "" {
+			userInfoURL = document.UserInfoEndpoint
+		}
+	}
+
+	return &OIDCProvider{
+		providerConfig: providerConfig,
+		oauthConfig: &oauth2.Config{
+			ClientID:     providerConfig.ClientID,
+			ClientSecret: providerConfig.ClientSecret,
+			Endpoint: oauth2.Endpoint{
+				AuthURL:  authURL,
+				TokenURL: tokenURL,
+			},
+			RedirectURL: providerConfig.RedirectURL,
+			Scopes:      scopes,
+		},
+		userInfoURL:     userInfoURL,
+		idTokenVerifier: verifier,
+	}, nil
+}
+
+func (p *OIDCProvider) Name() string {
+	return p.providerConfig.Name
+}
+
+func (p *OIDCProvider) Begin(ctx context.Context) (string, identity.FlowState, error) {
+	state, err := helper.GenerateNonce(16)
+	if err != nil {
+		return "", nil, err
+	}
+	verifier := oauth2.GenerateVerifier()
+	flowState := identity.FlowState{
+		"state":         state,
+		"code_verifier": verifier,
+	}
+	options := []oauth2.AuthCodeOption{oauth2.S256ChallengeOption(verifier)}
+
+	// ID トークンが今回の認可要求に対して発行されたことを確認するため nonce を渡す
+	if p.idTokenVerifier != nil {
+		nonce, err := helper.GenerateNonce(16)
+		if err != nil {
+			return "", nil, err
+		}
+		flowState["nonce"] = nonce
+		options = append(options, oauth2.SetAuthURLParam("nonce", nonce))
+	}
+
+	return p.oauthConfig.AuthCodeURL(state, options...), flowState, nil
+}
+
+func (p *OIDCProvider) Complete(ctx context.Context, callback url.Values, state identity.FlowState) (*identity.Profile, error) {
+	if callbackError := callback.Get("error"); callbackError != "" {
+		return nil, fmt.Errorf("%s returned error: %s %s", p.Name(), callbackError, callback.Get("error_description"))
+	}
+
+	if state["state"] == "" || subtle.ConstantTimeCompare([]byte(callback.Get("state")), []byte(state["state"])) != 1 {
+		return nil, errors.New("state does not match the pending request")
+	}
+
+	code := callback.Get("code")
+	if code == "" {
+		return nil, errors.New("callback did not receive a code")
+	}
+
+	token, err := p.oauthConfig.Exchange(ctx, code, oauth2.VerifierOption(state["code_verifier"]))
+	if err != nil {
+		return nil, fmt.Errorf("failed to exchange code: %w", err)
+	}
+
+	claims, externalID, err := p.profileClaims(ctx, token, state)
+	if err != nil {
+		return nil, err
+	}
+
+	handle := claimString(claims, p.providerConfig.HandleClaim)
+	if handle == "" {
+		// ハンドルが無いプロバイダーではメールアドレスのローカル部を使う
+		handle, _, _ = strings.Cut(claimString(claims, "email"), "@")
+	}
+
+	return &identity.Profile{
+		Provider:    p.Name(),
+		ExternalID:  externalID,
+		Handle:      handle,
+		DisplayName: claimString(claims, p.providerConfig.NameClaim),
+		AvatarURL:   claimString(claims, p.providerConfig.AvatarClaim),
+	}, nil
+}
+
+// プロフィールに使うクレームとユーザーの ID を返す
+func (p *OIDCProvider) profileClaims(ctx context.Context, token *oauth2.Token, state identity.FlowState) (map[string]any, string, error) {
+	if p.idTokenVerifier == nil {
+		claims, err := p.fetchUserInfo(ctx, token)
+		if err != nil {
+			return nil, "", err
+		}
+
+		externalID := claimString(claims, p.providerConfig.IDClaim)
+		if externalID == "" {
+			return nil, "", fmt.Errorf("userinfo has no %q claim", p.providerConfig.IDClaim)
+		}
+		return claims, externalID, nil
+	}
+
+	rawIDToken, _ := token.Extra("id_token").(string)
+	if rawIDToken == "" {
+		return nil, "", errors.New("token response has no id_token")
+	}
+	idClaims, err := p.idTokenVerifier.Verify(ctx, rawIDToken, state["nonce"])
+	if err != nil {
+		return nil, "", err
+	}
+	subject := claimString(idClaims, "sub")
+
+	if p.userInfoURL == "" {
+		return idClaims, subject, nil
+	}
+
+	// 名前やアイコンは userinfo から取る。別のユーザーのものを使わないよう sub の一致を確認する
+	claims, err := p.fetchUserInfo(ctx, token)
+	if err != nil {
+		return nil, "", err
+	}
+	if claimString(claims, "sub") != subject {
+		return nil, "", errors.New("userinfo sub does not match id_token")
+	}
+	return claims, subject, nil
+}
+
+func (p *OIDCProvider) fetchUserInfo(ctx context.Context, token *oauth2.Token) (map[string]any, error) {
+	resp, err := p.oauthConfig.Client(ctx, token).Get(p.userInfoURL)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("userinfo returned status %d", resp.StatusCode)
+	}
+
+	// 数値のIDを文字列として扱えるよう json.Number で受け取る
+	decoder := json.NewDecoder(resp.Body)
+	decoder.UseNumber()
+
+	var claims map[string]any
+	if err := decoder.Decode(&claims); err != nil {
+		return nil, err
+	}
+
+	return claims, nil
+}
+
+func discover(ctx context.Context, issuerURL string) (*discoveryDocument, error) {
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+
+	wellKnownURL := strings.TrimSuffix(issuerURL, "/") + "/.well-known/openid-configuration"
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, wellKnownURL, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("discovery returned status %d", resp.StatusCode)
+	}
+
+	var document discoveryDocument
+	if err := json.NewDecoder(resp.Body).Decode(&document); err != nil {
+		return nil, err
+	}
+
+	return &document, nil
+}
+
+func claimString(claims map[string]any, name string) string {
+	switch value := claims[name].(type) {
+	case string:
+		return value
+	case json.Number:
+		return value.String()
+	default:
+		return ""
+	}
+}
@@ -0,0 +1,379 @@
+package identityprovider
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v4"
+
+	"proto-pulse-plat/config"
+	"proto-pulse-plat/domain/identity"
+)
+
+const (
+	testClientID    = "test-client"
+	testKeyID       = "test-key"
+	testCode        = "test-code"
+	testAccessToken = "test-access-token"
+)
+
+// discovery, JWKS, token, userinfo を返す OpenID Connect プロバイダー
+type mockOIDCServer struct {
+	server *httptest.Server
+	key    *rsa.PrivateKey
+	// 発行する ID トークンのクレーム。nonce は認可 URL で受け取った値
+	idTokenClaims func(issuer, nonce string) jwt.MapClaims
+	// nil の場合は key で署名する
+	signingKey *rsa.PrivateKey
+	userInfo   map[string]any
+	// discovery で返す issuer。空の場合はサーバーの URL
+	issuer string
+
+	nonce string
+}
+
+func newMockOIDCServer(t *testing.T) *mockOIDCServer {
+	t.Helper()
+
+	m := &mockOIDCServer{
+		key:           generateRSAKey(t),
+		idTokenClaims: validIDTokenClaims,
+		userInfo:      map[string]any{"sub": "user-1", "preferred_username": "alice", "name": "Alice", "picture": "https://example.com/alice.png"},
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/.well-known/openid-configuration", func(w http.ResponseWriter, r *http.Request) {
+		issuer := m.issuer
+		if issuer == "" {
+			issuer = m.server.URL
+		}
+		writeJSON(w, map[string]string{
+			"issuer":                 issuer,
+			"authorization_endpoint": m.server.URL + "/authorize",
+			"token_endpoint":         m.server.URL + "/token",
+			"userinfo_endpoint":      m.server.URL + "/userinfo",
+			"jwks_uri":               m.server.URL + "/jwks",
+		})
+	})
+	mux.HandleFunc("/jwks", func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, map[string]any{"keys": []map[string]string{{
+			"kty": "RSA",
+			"kid": testKeyID,
+			"use": "sig",
+			"n":   base64.RawURLEncoding.EncodeToString(m.key.N.Bytes()),
+			"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(m.key.E)).Bytes()),
+		}}})
+	})
+	mux.HandleFunc("/token", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost || r.FormValue("code") != testCode || r.FormValue("code_verifier") == "" {
+			http.Error(w, `{"error":"invalid_grant"}`, http.StatusBadRequest)
+			return
+		}
+
+		signingKey := m.signingKey
+		if signingKey == nil {
+			signingKey = m.key
+		}
+		idToken := jwt.NewWithClaims(jwt.SigningMethodRS256, m.idTokenClaims(m.server.URL, m.nonce))
+		idToken.Header["kid"] = testKeyID
+		rawIDToken, err := idToken.SignedString(signingKey)
+		if err != nil {
+			t.Errorf("failed to sign id_token: %v", err)
+		}
+
+		writeJSON(w, map[string]any{
+			"access_token": testAccessToken,
+			"token_type":   "Bearer",
+			"expires_in":   3600,
+			"id_token":     rawIDToken,
+		})
+	})
+	mux.HandleFunc("/userinfo", func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer "+testAccessToken {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		writeJSON(w, m.userInfo)
+	})
+
+	m.server = httptest.NewServer(mux)
+	t.Cleanup(m.server.Close)
+	return m
+}
+
+func (m *mockOIDCServer) providerConfig() config.OIDCProviderConfig {
+	return config.OIDCProviderConfig{
+		Name:         "mock",
+		ClientID:     testClientID,
+		ClientSecret: "test-secret",
+		IssuerURL:    m.server.URL,
+		RedirectURL:  "https://app.example.com/api/oauth/mock/callback",
+		Scopes:       []string{"profile"},
+		IDClaim:      "sub",
+		HandleClaim:  "preferred_username",
+		NameClaim:    "name",
+		AvatarClaim:  "picture",
+	}
+}
+
+// Begin の認可 URL をプロバイダーが受け取ったものとして nonce を覚え、コールバックを Complete に渡す
+func (m *mockOIDCServer) login(t *testing.T, provider identity.Provider) (*identity.Profile, error) {
+	t.Helper()
+
+	authURL, state, err := provider.Begin(context.Background())
+	if err != nil {
+		t.Fatalf("Begin: %v", err)
+	}
+	parsed, err := url.Parse(authURL)
+	if err != nil {
+		t.Fatalf("invalid auth URL %q: %v", authURL, err)
+	}
+	m.nonce = parsed.Query().Get("nonce")
+
+	callback := url.Values{"state": {parsed.Query().Get("state")}, "code": {testCode}}
+	return provider.Complete(context.Background(), callback, state)
+}
+
+func validIDTokenClaims(issuer, nonce string) jwt.MapClaims {
+	now := time.Now()
+	return jwt.MapClaims{
+		"iss":   issuer,
+		"aud":   testClientID,
+		"sub":   "user-1",
+		"nonce": nonce,
+		"iat":   now.Unix(),
+		"exp":   now.Add(time.Hour).Unix(),
+	}
+}
+
+func generateRSAKey(t *testing.T) *rsa.PrivateKey {
+	t.Helper()
+
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("failed to generate key: %v", err)
+	}
+	return key
+}
+
+func writeJSON(w http.ResponseWriter, value any) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(value)
+}
+
+func TestOIDCProviderBegin(t *testing.T) {
+	m := newMockOIDCServer(t)
+	provider, err := NewOIDCProvider(context.Background(), m.providerConfig())
+	if err != nil {
+		t.Fatalf("NewOIDCProvider: %v", err)
+	}
+
+	authURL, state, err := provider.Begin(context.Background())
+	if err != nil {
+		t.Fatalf("Begin: %v", err)
+	}
+
+	// エンドポイントは discovery から取得する
+	if !strings.HasPrefix(authURL, m.server.URL+"/authorize?") {
+		t.Errorf("auth URL = %s, want the discovered authorization_endpoint", authURL)
+	}
+	parsed, _ := url.Parse(authURL)
+	query := parsed.Query()
+	if scopes := strings.Fields(query.Get("scope")); len(scopes) == 0 || scopes[0] != "openid" {
+		t.Errorf("scope = %q, want openid to be added", query.Get("scope"))
+	}
+	if query.Get("nonce") == "" || query.Get("nonce") != state["nonce"] {
+		t.Errorf("nonce = %q, want the nonce kept in the flow state", query.Get("nonce"))
+	}
+	if query.Get("code_challenge") == "" || query.Get("code_challenge_method") != "S256" {
+		t.Error("auth URL has no PKCE challenge")
+	}
+}
+
+func TestOIDCProviderComplete(t *testing.T) {
+	otherKey := generateRSAKey(t)
+
+	tests := []struct {
+		name  string
+		setup func(m *mockOIDCServer)
+		// 空の場合はログインできる
+		wantErr string
+	}{
+		{name: "valid"},
+		{
+			name: "wrong issuer",
+			setup: func(m *mockOIDCServer) {
+				m.idTokenClaims = withClaim("iss", "https://attacker.example.com")
+			},
+			wantErr: "issued by",
+		},
+		{
+			name: "wrong audience",
+			setup: func(m *mockOIDCServer) {
+				m.idTokenClaims = withClaim("aud", "another-client")
+			},
+			wantErr: "not issued for client",
+		},
+		{
+			name: "expired",
+			setup: func(m *mockOIDCServer) {
+				m.idTokenClaims = withClaim("exp", time.Now().Add(-time.Minute).Unix())
+			},
+			wantErr: "expired",
+		},
+		{
+			name: "no exp",
+			setup: func(m *mockOIDCServer) {
+				m.idTokenClaims = withoutClaim("exp")
+			},
+			wantErr: "no exp",
+		},
+		{
+			name: "wrong nonce",
+			setup: func(m *mockOIDCServer) {
+				m.idTokenClaims = withClaim("nonce", "replayed-nonce")
+			},
+			wantErr: "nonce",
+		},
+		{
+			name: "no nonce",
+			setup: func(m *mockOIDCServer) {
+				m.idTokenClaims = withoutClaim("nonce")
+			},
+			wantErr: "nonce",
+		},
+		{
+			name: "no sub",
+			setup: func(m *mockOIDCServer) {
+				m.idTokenClaims = withoutClaim("sub")
+			},
+			wantErr: "no sub",
+		},
+		{
+			name: "bad signature",
+			setup: func(m *mockOIDCServer) {
+				m.signingKey = otherKey
+			},
+			wantErr: "invalid id_token",
+		},
+		{
+			name: "userinfo sub mismatch",
+			setup: func(m *mockOIDCServer) {
+				m.userInfo["sub"] = "user-2"
+			},
+			wantErr: "userinfo sub does not match",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := newMockOIDCServer(t)
+			if tt.setup != nil {
+				tt.setup(m)
+			}
+			provider, err := NewOIDCProvider(context.Background(), m.providerConfig())
+			if err != nil {
+				t.Fatalf("NewOIDCProvider: %v", err)
+			}
+
+			profile, err := m.login(t, provider)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("Complete error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Complete: %v", err)
+			}
+
+			want := identity.Profile{
+				Provider:    "mock",
+				ExternalID:  "user-1",
+				Handle:      "alice",
+				DisplayName: "Alice",
+				AvatarURL:   "https://example.com/alice.png",
+			}
+			if *profile != want {
+				t.Errorf("profile = %+v, want %+v", *profile, want)
+			}
+		})
+	}
+}
+
+func TestOIDCProviderRejectsMismatchedState(t *testing.T) {
+	m := newMockOIDCServer(t)
+	provider, err := NewOIDCProvider(context.Background(), m.providerConfig())
+	if err != nil {
+		t.Fatalf("NewOIDCProvider: %v", err)
+	}
+
+	_, state, err := provider.Begin(context.Background())
+	if err != nil {
+		t.Fatalf("Begin: %v", err)
+	}
+	callback := url.Values{"state": {"forged"}, "code": {testCode}}
+	if _, err := provider.Complete(context.Background(), callback, state); err == nil {
+		t.Error("Complete accepted a callback with another state")
+	}
+}
+
+func TestNewOIDCProviderRejectsMismatchedIssuer(t *testing.T) {
+	m := newMockOIDCServer(t)
+	m.issuer = "https://attacker.example.com"
+
+	if _, err := NewOIDCProvider(context.Background(), m.providerConfig()); err == nil {
+		t.Error("NewOIDCProvider accepted a discovery document for another issuer")
+	}
+}
+
+// ISSUER_URL を指定しない OAuth2 プロバイダーは userinfo の IDClaim をユーザーの ID にする
+func TestOAuth2ProviderUsesUserInfoIDClaim(t *testing.T) {
+	m := newMockOIDCServer(t)
+	m.userInfo = map[string]any{"id": 12345, "login": "alice"}
+
+	providerConfig := m.providerConfig()
+	providerConfig.IssuerURL = ""
+	providerConfig.AuthURL = m.server.URL + "/authorize"
+	providerConfig.TokenURL = m.server.URL + "/token"
+	providerConfig.UserInfoURL = m.server.URL + "/userinfo"
+	providerConfig.IDClaim = "id"
+	providerConfig.HandleClaim = "login"
+	provider, err := NewOIDCProvider(context.Background(), providerConfig)
+	if err != nil {
+		t.Fatalf("NewOIDCProvider: %v", err)
+	}
+
+	profile, err := m.login(t, provider)
+	if err != nil {
+		t.Fatalf("Complete: %v", err)
+	}
+	if profile.ExternalID != "12345" || profile.Handle != "alice" {
+		t.Errorf("profile = %+v, want id 12345 and handle alice", *profile)
+	}
+}
+
+func withClaim(name string, value any) func(issuer, nonce string) jwt.MapClaims {
+	return func(issuer, nonce string) jwt.MapClaims {
+		claims := validIDTokenClaims(issuer, nonce)
+		claims[name] = value
+		return claims
+	}
+}
+
+func withoutClaim(name string) func(issuer, nonce string) jwt.MapClaims {
+	return func(issuer, nonce string) jwt.MapClaims {
+		claims := validIDTokenClaims(issuer, nonce)
+		delete(claims, name)
+		return claims
+	}
+}
@@ -0,0 +1,116 @@
+package identityprovider
+
+import (
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"proto-pulse-plat/config"
+	"proto-pulse-plat/domain/identity"
+	"proto-pulse-plat/helper"
+	"proto-pulse-plat/infrastructure/response"
+
+	"github.com/dghubble/oauth1"
+)
+
+const XProviderName = "x"
+
+// X の OAuth 1.0a によるログイン
+type XProvider struct {
+	xConfig     *config.Xconfig
+	oauthConfig *oauth1.Config
+}
+
+func NewXProvider(xConfig *config.Xconfig) *XProvider {
+	return &XProvider{
+		xConfig: xConfig,
+		oauthConfig: &oauth1.Config{
+			ConsumerKey:    xConfig.ConsumerKey,
+			ConsumerSecret: xConfig.ConsumerSecret,
+			CallbackURL:    xConfig.CallBackURL,
+			Endpoint: oauth1.Endpoint{
+				RequestTokenURL: xConfig.RequestTokenURL,
+				AuthorizeURL:    xConfig.AuthorizeURL,
+				AccessTokenURL:  xConfig.AccessTokenURL,
+			},
+		},
+	}
+}
+
+func (p *XProvider) Name() string {
+	return XProviderName
+}
+
+func (p *XProvider) Begin(ctx context.Context) (string, identity.FlowState, error) {
+	requestToken, requestSecret, err := p.oauthConfig.RequestToken()
+	if err != nil {
+		return "", nil, fmt.Errorf("failed to get request token: %w", err)
+	}
+
+	authorizationURL, err := p.oauthConfig.AuthorizationURL(requestToken)
+	if err != nil {
+		return "", nil, fmt.Errorf("failed to build authorization URL: %w", err)
+	}
+
+	return authorizationURL.String(), identity.FlowState{
+		"oauth_token":        requestToken,
+		"oauth_token_secret": requestSecret,
+	}, nil
+}
+
+func (p *XProvider) Complete(ctx context.Context, callback url.Values, state identity.FlowState) (*identity.Profile, error) {
+	requestToken := callback.Get("oauth_token")
+	verifier := callback.Get("oauth_verifier")
+	if requestToken == "" || verifier == "" {
+		return nil, errors.New("oauth1: callback did not receive an oauth_token or oauth_verifier")
+	}
+
+	// 認可を開始したブラウザのリクエストトークンと一致するか確認する
+	if subtle.ConstantTimeCompare([]byte(requestToken), []byte(state["oauth_token"])) != 1 {
+		return nil, errors.New("oauth_token does not match the pending request")
+	}
+
+	// リクエストトークンの secret で署名してアクセストークンを取得する
+	accessToken, accessSecret, err := p.oauthConfig.AccessToken(requestToken, state["oauth_token_secret"], verifier)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get access token: %w", err)
+	}
+
+	oauthClient := p.oauthConfig.Client(ctx, oauth1.NewToken(accessToken, accessSecret))
+
+	resp, err := oauthClient.Get(p.xConfig.VerifyCredentials)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("verify_credentials returned status %d", resp.StatusCode)
+	}
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	var profile response.UserProfile
+	if err := json.Unmarshal(body, &profile); err != nil {
+		return nil, err
+	}
+
+	if profile.IDStr == "" {
+		return nil, errors.New("verify_credentials response has no id_str")
+	}
+
+	return &identity.Profile{
+		Provider:    XProviderName,
+		ExternalID:  profile.IDStr,
+		Handle:      profile.ScreenName,
+		DisplayName: profile.Name,
+		AvatarURL:   helper.OriginalProfileImageURL(profile.ProfileImageUrl),
+	}, nil
+}
@@ -0,0 +1,186 @@
+package imaging
+
+import (
+	"encoding/binary"
+	"errors"
+)
+
+const (
+	exifTagOrientation = 0x0112
+	exifTagGPSIFD      = 0x8825
+
+	gpsTagLatitudeRef  = 0x0001
+	gpsTagLatitude     = 0x0002
+	gpsTagLongitudeRef = 0x0003
+	gpsTagLongitude    = 0x0004
+
+	exifTypeASCII    = 2
+	exifTypeShort    = 3
+	exifTypeLong     = 4
+	exifTypeRational = 5
+)
+
+var errInvalidEXIF = errors.New("invalid EXIF data")
+
+// 撮影位置 (度)
+type GPS struct {
+	Latitude  float64
+	Longitude float64
+}
+
+// 保存前の処理に必要な EXIF の項目
+type exifData struct {
+	// 1 から 8。タグが無い場合は 1
+	Orientation int
+	GPS         *GPS
+}
+
+type ifdEntry struct {
+	tag    uint16
+	typ    uint16
+	count  uint32
+	offset uint32
+	// 値が4バイト以内の場合はエントリー自体に入っている
+	inline []byte
+}
+
+// TIFF 形式の EXIF (JPEG の APP1 から "Exif\0\0" を除いた部分、PNG の eXIf チャンク) を読む
+func parseEXIF(tiff []byte) (*exifData, error) {
+	if len(tiff) < 8 {
+		return nil, errInvalidEXIF
+	}
+
+	var order binary.ByteOrder
+	switch string(tiff[:2]) {
+	case "II":
+		order = binary.LittleEndian
+	case "MM":
+		order = binary.BigEndian
+	default:
+		return nil, errInvalidEXIF
+	}
+	if order.Uint16(tiff[2:4]) != 42 {
+		return nil, errInvalidEXIF
+	}
+
+	ifd0, err := readIFD(tiff, order, order.Uint32(tiff[4:8]))
+	if err != nil {
+		return nil, err
+	}
+
+	data := &exifData{Orientation: 1}
+	for _, entry := range ifd0 {
+		switch entry.tag {
+		case exifTagOrientation:
+			if entry.typ == exifTypeShort && entry.count >= 1 {
+				if orientation := int(order.Uint16(entry.inline)); orientation >= 1 && orientation <= 8 {
+					data.Orientation = orientation
+				}
+			}
+		case exifTagGPSIFD:
+			if entry.typ == exifTypeLong && entry.count == 1 {
+				// 位置情報が壊れていても向きの補正は行う
+				data.GPS, _ = readGPS(tiff, order, order.Uint32(entry.inline))
+			}
+		}
+	}
+
+	return data, nil
+}
+
+func readIFD(tiff []byte, order binary.ByteOrder, offset uint32) ([]ifdEntry, error) {
+	if uint64(offset)+2 > uint64(len(tiff)) {
+		return nil, errInvalidEXIF
+	}
+	count := int(order.Uint16(tiff[offset:]))
+	start := uint64(offset) + 2
+	if start+uint64(count)*12 > uint64(len(tiff)) {
+		return nil, errInvalidEXIF
+	}
+
+	entries := make([]ifdEntry, 0, count)
+	for i := 0; i < count; i++ {
+		raw := tiff[start+uint64(i)*12 : start+uint64(i+1)*12]
+		entries = append(entries, ifdEntry{
+			tag:    order.Uint16(raw[0:2]),
+			typ:    order.Uint16(raw[2:4]),
+			count:  order.Uint32(raw[4:8]),
+			offset: order.Uint32(raw[8:12]),
+			inline: raw[8:12],
+		})
+	}
+	return entries, nil
+}
+
+func readGPS(tiff []byte, order binary.ByteOrder, offset uint32) (*GPS, error) {
+	entries, err := readIFD(tiff, order, offset)
+	if err != nil {
+		return nil, err
+	}
+
+	var latitudeRef, longitudeRef string
+	var latitude, longitude []float64
+	for _, entry := range entries {
+		switch entry.tag {
+		case gpsTagLatitudeRef:
+			latitudeRef = readRef(entry)
+		case gpsTagLongitudeRef:
+			longitudeRef = readRef(entry)
+		case gpsTagLatitude:
+			latitude, err = readRationals(tiff, order, entry)
+		case gpsTagLongitude:
+			longitude, err = readRationals(tiff, order, entry)
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if len(latitude) != 3 || len(longitude) != 3 {
+		return nil, errInvalidEXIF
+	}
+
+	gps := &GPS{
+		Latitude:  latitude[0] + latitude[1]/60 + latitude[2]/3600,
+		Longitude: longitude[0] + longitude[1]/60 + longitude[2]/3600,
+	}
+	if latitudeRef == "S" {
+		gps.Latitude = -gps.Latitude
+	}
+	if longitudeRef == "W" {
+		gps.Longitude = -gps.Longitude
+	}
+	if gps.Latitude < -90 || gps.Latitude > 90 || gps.Longitude < -180 || gps.Longitude > 180 {
+		return nil, errInvalidEXIF
+	}
+	return gps, nil
+}
+
+func readRef(entry ifdEntry) string {
+	if entry.typ != exifTypeASCII || entry.count == 0 {
+		return ""
+	}
+	return string(entry.inline[:1])
+}
+
+func readRationals(tiff []byte, order binary.ByteOrder, entry ifdEntry) ([]float64, error) {
+	if entry.typ != exifTypeRational || entry.count != 3 {
+		return nil, errInvalidEXIF
+	}
+	end := uint64(entry.offset) + uint64(entry.count)*8
+	if end > uint64(len(tiff)) {
+		return nil, errInvalidEXIF
+	}
+
+	values := make([]float64, 0, entry.count)
+	for i := uint64(0); i < uint64(entry.count); i++ {
+		at := uint64(entry.offset) + i*8
+		numerator := order.Uint32(tiff[at : at+4])
+		denominator := order.Uint32(tiff[at+4 : at+8])
+		if denominator == 0 {
+			return nil, errInvalidEXIF
+		}
+		values = append(values, float64(numerator)/float64(denominator))
+	}
+	return values, nil
+}
@@ -0,0 +1,100 @@
+package imaging
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"image"
+	_ "image/gif"
+	_ "image/jpeg"
+	_ "image/png"
+	"path/filepath"
+	"strings"
+
+	_ "golang.org/x/image/webp"
+)
+
+const (
+	// 展開後のサイズで判定する。40M pixel は RGBA で約 160 MB
+	MaxPixels    = 40_000_000
+	MaxDimension = 12_000
+)
+
+var (
+	ErrUnsupportedType = errors.New("unsupported image type")
+	ErrTypeMismatch    = errors.New("image content does not match its type")
+	ErrTooLarge        = errors.New("image dimensions are too large")
+)
+
+type Info struct {
+	// image.DecodeConfig のフォーマット名 (jpeg, png, gif, webp)
+	Format   string
+	MimeType string
+	Width    int
+	Height   int
+}
+
+var mimeTypes = map[string]string{
+	"jpeg": "image/jpeg",
+	"png":  "image/png",
+	"gif":  "image/gif",
+	"webp": "image/webp",
+}
+
+var extensionFormats = map[string]string{
+	".jpg":  "jpeg",
+	".jpeg": "jpeg",
+	".png":  "png",
+	".gif":  "gif",
+	".webp": "webp",
+}
+
+// 先頭のマジックバイトと image.DecodeConfig で形式と大きさを確認する。画素データは展開しない
+func Inspect(fileName string, data []byte) (*Info, error) {
+	format := sniffFormat(data)
+	if format == "" {
+		return nil, ErrUnsupportedType
+	}
+
+	// 既知の画像の拡張子は中身と一致している必要がある
+	if extFormat, ok := extensionFormats[strings.ToLower(filepath.Ext(fileName))]; ok && extFormat != format {
+		return nil, fmt.Errorf("%s file has %s content: %w", extFormat, format, ErrTypeMismatch)
+	}
+
+	config, decodedFormat, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrTypeMismatch, err)
+	}
+	if decodedFormat != format {
+		return nil, fmt.Errorf("%s header decoded as %s: %w", format, decodedFormat, ErrTypeMismatch)
+	}
+
+	if config.Width <= 0 || config.Height <= 0 {
+		return nil, fmt.Errorf("%dx%d: %w", config.Width, config.Height, ErrTypeMismatch)
+	}
+	if config.Width > MaxDimension || config.Height > MaxDimension ||
+		int64(config.Width)*int64(config.Height) > MaxPixels {
+		return nil, fmt.Errorf("%dx%d: %w", config.Width, config.Height, ErrTooLarge)
+	}
+
+	return &Info{
+		Format:   format,
+		MimeType: mimeTypes[format],
+		Width:    config.Width,
+		Height:   config.Height,
+	}, nil
+}
+
+func sniffFormat(data []byte) string {
+	switch {
+	case bytes.HasPrefix(data, []byte{0xFF, 0xD8, 0xFF}):
+		return "jpeg"
+	case bytes.HasPrefix(data, []byte("\x89PNG\r\n\x1a\n")):
+		return "png"
+	case bytes.HasPrefix(data, []byte("GIF87a")), bytes.HasPrefix(data, []byte("GIF89a")):
+		return "gif"
+	case len(data) >= 12 && bytes.Equal(data[:4], []byte("RIFF")) && bytes.Equal(data[8:12], []byte("WEBP")):
+		return "webp"
+	}
+	return ""
+}
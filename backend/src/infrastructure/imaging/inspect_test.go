@@ -0,0 +1,86 @@
+package imaging
+
+import (
+	"encoding/binary"
+	"errors"
+	"hash/crc32"
+	"testing"
+)
+
+func TestInspect(t *testing.T) {
+	pngData := pngFixture(t, nil)
+
+	tests := []struct {
+		name       string
+		fileName   string
+		data       []byte
+		wantMime   string
+		wantWidth  int
+		wantHeight int
+		wantErr    error
+	}{
+		{name: "jpeg", fileName: "photo.jpg", data: jpegFixture(t, nil), wantMime: "image/jpeg", wantWidth: 16, wantHeight: 8},
+		{name: "jpeg with jpeg extension", fileName: "photo.jpeg", data: jpegFixture(t, nil), wantMime: "image/jpeg", wantWidth: 16, wantHeight: 8},
+		{name: "png", fileName: "image.png", data: pngData, wantMime: "image/png", wantWidth: 16, wantHeight: 8},
+		{name: "upper case extension", fileName: "IMAGE.PNG", data: pngData, wantMime: "image/png", wantWidth: 16, wantHeight: 8},
+		{name: "gif", fileName: "anim.gif", data: gifFixture(t), wantMime: "image/gif", wantWidth: 16, wantHeight: 8},
+		{name: "webp", fileName: "image.webp", data: webpFixture(4, 2, 255, nil), wantMime: "image/webp", wantWidth: 4, wantHeight: 2},
+		// 画像以外の拡張子や拡張子の無いファイルは中身で判定する
+		{name: "unknown extension", fileName: "image.bin", data: pngData, wantMime: "image/png", wantWidth: 16, wantHeight: 8},
+		{name: "no extension", fileName: "image", data: pngData, wantMime: "image/png", wantWidth: 16, wantHeight: 8},
+		{name: "png named jpg", fileName: "photo.jpg", data: pngData, wantErr: ErrTypeMismatch},
+		{name: "jpeg named png", fileName: "image.png", data: jpegFixture(t, nil), wantErr: ErrTypeMismatch},
+		{name: "gif named webp", fileName: "image.webp", data: gifFixture(t), wantErr: ErrTypeMismatch},
+		{name: "text", fileName: "image.png", data: []byte("not an image"), wantErr: ErrUnsupportedType},
+		{name: "svg", fileName: "image.svg", data: []byte(`<svg xmlns="http://www.w3.org/2000/svg"/>`), wantErr: ErrUnsupportedType},
+		{name: "empty", fileName: "image.png", data: nil, wantErr: ErrUnsupportedType},
+		{name: "signature only", fileName: "image.png", data: pngSignature, wantErr: ErrTypeMismatch},
+		{name: "corrupt header", fileName: "image.png", data: pngData[:20], wantErr: ErrTypeMismatch},
+		{name: "max dimension", data: pngWithSize(t, MaxDimension, 1), wantMime: "image/png", wantWidth: MaxDimension, wantHeight: 1},
+		{name: "width over max dimension", data: pngWithSize(t, MaxDimension+1, 1), wantErr: ErrTooLarge},
+		{name: "height over max dimension", data: pngWithSize(t, 1, MaxDimension+1), wantErr: ErrTooLarge},
+		{name: "max pixels", data: pngWithSize(t, 10_000, MaxPixels/10_000), wantMime: "image/png", wantWidth: 10_000, wantHeight: MaxPixels / 10_000},
+		// 各辺は上限以内でも画素数で判定する
+		{name: "over max pixels", data: pngWithSize(t, 10_000, MaxPixels/10_000+1), wantErr: ErrTooLarge},
+		{name: "gif over max dimension", data: gifWithSize(t, MaxDimension+1, 1), wantErr: ErrTooLarge},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			info, err := Inspect(tt.fileName, tt.data)
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Fatalf("err = %v, want %v", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Inspect: %v", err)
+			}
+			if info.MimeType != tt.wantMime || info.Width != tt.wantWidth || info.Height != tt.wantHeight {
+				t.Errorf("info = %s %dx%d, want %s %dx%d", info.MimeType, info.Width, info.Height, tt.wantMime, tt.wantWidth, tt.wantHeight)
+			}
+		})
+	}
+}
+
+// IHDR の大きさだけを書き換えた PNG。Inspect は画素データを読まないため中身は元のまま
+func pngWithSize(t *testing.T, width, height int) []byte {
+	t.Helper()
+	data := pngFixture(t, nil)
+
+	// CRC の対象になる IHDR の種類 (4) とデータ (13)。データは幅と高さから始まる
+	ihdr := data[len(pngSignature)+4 : len(pngSignature)+4+4+13]
+	binary.BigEndian.PutUint32(ihdr[4:], uint32(width))
+	binary.BigEndian.PutUint32(ihdr[8:], uint32(height))
+	binary.BigEndian.PutUint32(data[len(pngSignature)+4+4+13:], crc32.ChecksumIEEE(ihdr))
+	return data
+}
+
+// 論理画面の大きさだけを書き換えた GIF
+func gifWithSize(t *testing.T, width, height int) []byte {
+	t.Helper()
+	data := gifFixture(t)
+	binary.LittleEndian.PutUint16(data[6:], uint16(width))
+	binary.LittleEndian.PutUint16(data[8:], uint16(height))
+	return data
+}
@@ -0,0 +1,171 @@
+package imaging
+
+import (
+	"fmt"
+	"image"
+	"math"
+	"strings"
+
+	"golang.org/x/image/draw"
+)
+
+const (
+	// BlurHash の横・縦の成分数。カードの大きさでは 4x3 で十分に形が分かる
+	blurHashComponentsX = 4
+	blurHashComponentsY = 3
+	// BlurHash と代表色は縮小した画像から計算する
+	placeholderSampleSize = 32
+)
+
+// 画像の読み込み中に表示するプレースホルダー
+type Placeholder struct {
+	BlurHash string
+	// "#rrggbb" 形式
+	DominantColor string
+}
+
+// 透過部分は白の背景に重ねた色として計算する
+func ComputePlaceholder(src image.Image) Placeholder {
+	bounds := src.Bounds()
+	width, height, ok := fitWithin(bounds.Dx(), bounds.Dy(), placeholderSampleSize)
+	if !ok {
+		width, height = bounds.Dx(), bounds.Dy()
+	}
+
+	sample := image.NewRGBA(image.Rect(0, 0, width, height))
+	draw.ApproxBiLinear.Scale(sample, sample.Bounds(), src, bounds, draw.Src, nil)
+
+	pixels := make([][3]uint8, 0, width*height)
+	for i := 0; i < len(sample.Pix); i += 4 {
+		// 乗算済みアルファなので、白との合成は足すだけでよい
+		background := 255 - sample.Pix[i+3]
+		pixels = append(pixels, [3]uint8{
+			sample.Pix[i] + background,
+			sample.Pix[i+1] + background,
+			sample.Pix[i+2] + background,
+		})
+	}
+
+	return Placeholder{
+		BlurHash:      encodeBlurHash(pixels, width, height),
+		DominantColor: dominantColor(pixels),
+	}
+}
+
+// 各チャンネルを上位4ビットで分類し、最も多い分類の平均色を返す
+func dominantColor(pixels [][3]uint8) string {
+	type bucket struct {
+		count   int
+		r, g, b int
+	}
+	buckets := make(map[int]*bucket)
+	var best *bucket
+	for _, p := range pixels {
+		index := int(p[0]>>4)<<8 | int(p[1]>>4)<<4 | int(p[2]>>4)
+		b, ok := buckets[index]
+		if !ok {
+			b = &bucket{}
+			buckets[index] = b
+		}
+		b.count++
+		b.r += int(p[0])
+		b.g += int(p[1])
+		b.b += int(p[2])
+		if best == nil || b.count > best.count {
+			best = b
+		}
+	}
+	if best == nil {
+		return "#ffffff"
+	}
+	return fmt.Sprintf("#%02x%02x%02x", best.r/best.count, best.g/best.count, best.b/best.count)
+}
+
+// https://github.com/woltapp/blurhash のアルゴリズムで符号化する
+func encodeBlurHash(pixels [][3]uint8, width, height int) string {
+	factors := make([][3]float64, 0, blurHashComponentsX*blurHashComponentsY)
+	for j := 0; j < blurHashComponentsY; j++ {
+		for i := 0; i < blurHashComponentsX; i++ {
+			normalisation := 2.0
+			if i == 0 && j == 0 {
+				normalisation = 1
+			}
+
+			var factor [3]float64
+			for y := 0; y < height; y++ {
+				for x := 0; x < width; x++ {
+					basis := normalisation *
+						math.Cos(math.Pi*float64(i)*float64(x)/float64(width)) *
+						math.Cos(math.Pi*float64(j)*float64(y)/float64(height))
+					p := pixels[y*width+x]
+					factor[0] += basis * sRGBToLinear(p[0])
+					factor[1] += basis * sRGBToLinear(p[1])
+					factor[2] += basis * sRGBToLinear(p[2])
+				}
+			}
+
+			scale := 1 / float64(width*height)
+			factors = append(factors, [3]float64{factor[0] * scale, factor[1] * scale, factor[2] * scale})
+		}
+	}
+
+	var hash strings.Builder
+	hash.WriteString(encodeBase83((blurHashComponentsX-1)+(blurHashComponentsY-1)*9, 1))
+
+	dc, ac := factors[0], factors[1:]
+
+	maximumValue := 1.0
+	if len(ac) > 0 {
+		actualMaximum := 0.0
+		for _, factor := range ac {
+			actualMaximum = math.Max(actualMaximum, math.Max(math.Abs(factor[0]), math.Max(math.Abs(factor[1]), math.Abs(factor[2]))))
+		}
+		quantisedMaximum := int(math.Max(0, math.Min(82, math.Floor(actualMaximum*166-0.5))))
+		maximumValue = float64(quantisedMaximum+1) / 166
+		hash.WriteString(encodeBase83(quantisedMaximum, 1))
+	} else {
+		hash.WriteString(encodeBase83(0, 1))
+	}
+
+	hash.WriteString(encodeBase83(linearToSRGB(dc[0])<<16|linearToSRGB(dc[1])<<8|linearToSRGB(dc[2]), 4))
+
+	for _, factor := range ac {
+		quantise := func(value float64) int {
+			return int(math.Max(0, math.Min(18, math.Floor(signPow(value/maximumValue, 0.5)*9+9.5))))
+		}
+		hash.WriteString(encodeBase83(quantise(factor[0])*19*19+quantise(factor[1])*19+quantise(factor[2]), 2))
+	}
+
+	return hash.String()
+}
+
+const base83Characters = "0123456789ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz#$%*+,-.:;=?@[]^_{|}~"
+
+func encodeBase83(value, length int) string {
+	encoded := make([]byte, length)
+	for i := length - 1; i >= 0; i-- {
+		encoded[i] = base83Characters[value%83]
+		value /= 83
+	}
+	return string(encoded)
+}
+
+func sRGBToLinear(value uint8) float64 {
+	v := float64(value) / 255
+	if v <= 0.04045 {
+		return v / 12.92
+	}
+	return math.Pow((v+0.055)/1.055, 2.4)
+}
+
+func linearToSRGB(value float64) int {
+	v := math.Max(0, math.Min(1, value))
+	if v <= 0.0031308 {
+		return int(v*12.92*255 + 0.5)
+	}
+	return int((1.055*math.Pow(v, 1/2.4)-0.055)*255 + 0.5)
+}
+
+func signPow(value, exp float64) float64 {
+	return math.Copysign(math.Pow(math.Abs(value), exp), value)
+}
@@ -0,0 +1,91 @@
+package imaging
+
+import (
+	"image"
+	"image/color"
+	"strings"
+	"testing"
+)
+
+func TestComputePlaceholder(t *testing.T) {
+	tests := []struct {
+		name      string
+		img       image.Image
+		wantHash  string
+		wantColor string
+	}{
+		// 黒は AC 成分がすべて 0 になり、中央値を表す "fQ" が続く
+		{name: "black", img: uniformImage(4, 3, color.NRGBA{A: 255}), wantHash: "L00000fQfQfQfQfQfQfQfQfQfQfQ", wantColor: "#000000"},
+		// 単色でも基底の余弦の和が 0 にならないため AC 成分を持つ。参照実装と同じ値になる
+		{name: "white", img: uniformImage(4, 3, color.NRGBA{R: 255, G: 255, B: 255, A: 255}), wantHash: "L~TSUA~qfQ~q~q%MfQ%MfQfQfQfQ", wantColor: "#ffffff"},
+		{name: "red", img: uniformImage(4, 3, red), wantHash: "L~TI:j|cfQ|c|c$5fQ$5fQfQfQfQ", wantColor: "#ff0000"},
+		// 大きな画像は 32px に縮小してから計算する
+		{name: "large red", img: uniformImage(640, 480, red), wantHash: "LDTI:j]9fQ]9|co1fQo1fQfQfQfQ", wantColor: "#ff0000"},
+		// 透過部分は白の背景に重ねる
+		{name: "transparent", img: uniformImage(4, 3, color.NRGBA{}), wantHash: "L~TSUA~qfQ~q~q%MfQ%MfQfQfQfQ", wantColor: "#ffffff"},
+		{name: "half transparent black", img: uniformImage(4, 3, color.NRGBA{A: 128}), wantColor: "#7f7f7f"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			placeholder := ComputePlaceholder(tt.img)
+			if tt.wantHash != "" && placeholder.BlurHash != tt.wantHash {
+				t.Errorf("BlurHash = %s, want %s", placeholder.BlurHash, tt.wantHash)
+			}
+			if placeholder.DominantColor != tt.wantColor {
+				t.Errorf("DominantColor = %s, want %s", placeholder.DominantColor, tt.wantColor)
+			}
+		})
+	}
+}
+
+func TestComputePlaceholderComponents(t *testing.T) {
+	// 左半分が赤、右半分が青
+	hash := ComputePlaceholder(halfRedImage()).BlurHash
+
+	if len(hash) != 6+2*(blurHashComponentsX*blurHashComponentsY-1) {
+		t.Fatalf("BlurHash %s has length %d", hash, len(hash))
+	}
+	if hash[0] != 'L' {
+		t.Errorf("size flag = %c, want L for 4x3 components", hash[0])
+	}
+
+	// 横方向の最初の成分は左で赤、右で青が強いことを表す
+	if r, g, b := acComponent(t, hash, 0); r <= 9 || g != 9 || b >= 9 {
+		t.Errorf("first horizontal component = (%d, %d, %d), want red positive and blue negative", r, g, b)
+	}
+}
+
+func TestDominantColor(t *testing.T) {
+	tests := []struct {
+		name   string
+		pixels [][3]uint8
+		want   string
+	}{
+		{name: "empty", pixels: nil, want: "#ffffff"},
+		{name: "majority", pixels: [][3]uint8{{0, 0, 255}, {255, 0, 0}, {0, 0, 255}}, want: "#0000ff"},
+		// 同じ分類の色は平均する
+		{name: "average of bucket", pixels: [][3]uint8{{0, 0, 0}, {15, 15, 15}, {255, 255, 255}}, want: "#070707"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := dominantColor(tt.pixels); got != tt.want {
+				t.Errorf("dominantColor = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+// index 番目の AC 成分を 0 から 18 の量子化された値で返す。9 が 0 を表す
+func acComponent(t *testing.T, hash string, index int) (int, int, int) {
+	t.Helper()
+
+	value := 0
+	for _, c := range hash[6+index*2 : 8+index*2] {
+		digit := strings.IndexRune(base83Characters, c)
+		if digit < 0 {
+			t.Fatalf("BlurHash %s has invalid character %c", hash, c)
+		}
+		value = value*83 + digit
+	}
+	return value / (19 * 19), value / 19 % 19, value % 19
+}
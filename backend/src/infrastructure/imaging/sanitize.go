@@ -0,0 +1,351 @@
+package imaging
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"image"
+	"image/jpeg"
+	"image/png"
+)
+
+// 向きを補正して再エンコードする場合の JPEG の品質
+const orientedJPEGQuality = 92
+
+var errMalformed = errors.New("malformed image data")
+
+// メタデータを取り除いた画像
+type Sanitized struct {
+	Data []byte
+	// WebP の向きを補正した場合は JPEG か PNG になるため、元の形式と異なることがある
+	MimeType string
+	// 向きを補正した後の大きさ
+	Width  int
+	Height int
+	// keepGPS が true で、EXIF に位置情報があった場合のみ設定される
+	GPS *GPS
+}
+
+// EXIF, XMP, IPTC, コメントなどのメタデータを取り除き、EXIF の向きを画素に反映する。
+// 向きの補正が不要な場合は画素データを再エンコードしない。Inspect で確認済みのデータを渡す
+func Sanitize(data []byte, info *Info, keepGPS bool) (*Sanitized, error) {
+	var (
+		stripped []byte
+		exif     *exifData
+		err      error
+	)
+	switch info.Format {
+	case "jpeg":
+		stripped, exif, err = stripJPEG(data)
+	case "png":
+		stripped, exif, err = stripPNG(data)
+	case "gif":
+		stripped, err = stripGIF(data)
+	case "webp":
+		stripped, exif, err = stripWebP(data)
+	default:
+		return nil, ErrUnsupportedType
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to strip %s metadata: %w", info.Format, err)
+	}
+
+	// 取り除いた結果が読み込めることを確認する
+	if _, _, err := image.DecodeConfig(bytes.NewReader(stripped)); err != nil {
+		return nil, fmt.Errorf("stripped %s is not decodable: %w", info.Format, err)
+	}
+
+	sanitized := &Sanitized{
+		Data:     stripped,
+		MimeType: info.MimeType,
+		Width:    info.Width,
+		Height:   info.Height,
+	}
+	if exif == nil {
+		return sanitized, nil
+	}
+	if keepGPS {
+		sanitized.GPS = exif.GPS
+	}
+
+	if exif.Orientation == 1 {
+		return sanitized, nil
+	}
+
+	img, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode %s: %w", info.Format, err)
+	}
+	oriented := applyOrientation(img, exif.Orientation)
+
+	// WebP のエンコーダーは無いため、不透明なら JPEG、透過があれば PNG で保存する
+	format := info.Format
+	if format == "webp" {
+		format = "png"
+		if isOpaque(img) {
+			format = "jpeg"
+		}
+	}
+
+	var buf bytes.Buffer
+	switch format {
+	case "jpeg":
+		err = jpeg.Encode(&buf, oriented, &jpeg.Options{Quality: orientedJPEGQuality})
+	case "png":
+		err = png.Encode(&buf, oriented)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode %s: %w", format, err)
+	}
+
+	sanitized.Data = buf.Bytes()
+	sanitized.MimeType = mimeTypes[format]
+	sanitized.Width = oriented.Bounds().Dx()
+	sanitized.Height = oriented.Bounds().Dy()
+	return sanitized, nil
+}
+
+// JPEG のセグメントのうち、表示に必要な JFIF, ICC プロファイル, Adobe 以外の APPn と COM を取り除く
+func stripJPEG(data []byte) ([]byte, *exifData, error) {
+	if len(data) < 4 || data[0] != 0xFF || data[1] != 0xD8 {
+		return nil, nil, errMalformed
+	}
+
+	out := bytes.NewBuffer(make([]byte, 0, len(data)))
+	out.Write(data[:2])
+
+	var exif *exifData
+	i := 2
+	for {
+		if i+2 > len(data) || data[i] != 0xFF {
+			return nil, nil, errMalformed
+		}
+		marker := data[i+1]
+		// 詰め物の 0xFF
+		if marker == 0xFF {
+			i++
+			continue
+		}
+		// SOS 以降は圧縮データなのでそのまま残す
+		if marker == 0xDA {
+			out.Write(data[i:])
+			return out.Bytes(), exif, nil
+		}
+		// 長さを持たないマーカー
+		if marker == 0x01 || (marker >= 0xD0 && marker <= 0xD7) {
+			out.Write(data[i : i+2])
+			i += 2
+			continue
+		}
+
+		if i+4 > len(data) {
+			return nil, nil, errMalformed
+		}
+		end := i + 2 + int(binary.BigEndian.Uint16(data[i+2:i+4]))
+		if end > len(data) || end < i+4 {
+			return nil, nil, errMalformed
+		}
+		payload := data[i+4 : end]
+
+		switch {
+		case marker == 0xE1:
+			if tiff, ok := bytes.CutPrefix(payload, []byte("Exif\x00\x00")); ok && exif == nil {
+				// 壊れた EXIF は向きの補正を行わずに取り除く
+				exif, _ = parseEXIF(tiff)
+			}
+		case marker == 0xE0, marker == 0xEE:
+			out.Write(data[i:end])
+		case marker == 0xE2:
+			// MPF など ICC プロファイル以外の APP2 は別の画像や EXIF を含むことがある
+			if bytes.HasPrefix(payload, []byte("ICC_PROFILE\x00")) {
+				out.Write(data[i:end])
+			}
+		case marker >= 0xE3 && marker <= 0xEF, marker == 0xFE:
+			// IPTC, メーカー固有の情報, コメントは残さない
+		default:
+			out.Write(data[i:end])
+		}
+		i = end
+	}
+}
+
+var pngSignature = []byte("\x89PNG\r\n\x1a\n")
+
+// テキスト、EXIF、更新日時のチャンクを取り除く
+func stripPNG(data []byte) ([]byte, *exifData, error) {
+	if !bytes.HasPrefix(data, pngSignature) {
+		return nil, nil, errMalformed
+	}
+
+	out := bytes.NewBuffer(make([]byte, 0, len(data)))
+	out.Write(pngSignature)
+
+	var exif *exifData
+	i := len(pngSignature)
+	for i < len(data) {
+		if i+12 > len(data) {
+			return nil, nil, errMalformed
+		}
+		length := binary.BigEndian.Uint32(data[i : i+4])
+		if uint64(length) > uint64(len(data)-i-12) {
+			return nil, nil, errMalformed
+		}
+		end := i + 12 + int(length)
+		chunkType := string(data[i+4 : i+8])
+
+		switch chunkType {
+		case "eXIf":
+			exif, _ = parseEXIF(data[i+8 : i+8+int(length)])
+		case "tEXt", "zTXt", "iTXt", "tIME":
+			// XMP は iTXt に入っている
+		default:
+			out.Write(data[i:end])
+		}
+
+		i = end
+		if chunkType == "IEND" {
+			return out.Bytes(), exif, nil
+		}
+	}
+	return nil, nil, errMalformed
+}
+
+// コメントと、ループ指定以外のアプリケーション拡張 (XMP など) を取り除く
+func stripGIF(data []byte) ([]byte, error) {
+	if len(data) < 13 {
+		return nil, errMalformed
+	}
+
+	i := 13
+	if data[10]&0x80 != 0 {
+		i += 3 << ((data[10] & 0x07) + 1)
+	}
+	if i > len(data) {
+		return nil, errMalformed
+	}
+
+	out := bytes.NewBuffer(make([]byte, 0, len(data)))
+	out.Write(data[:i])
+
+	for i < len(data) {
+		start := i
+		switch data[i] {
+		case 0x21:
+			if i+2 > len(data) {
+				return nil, errMalformed
+			}
+			label := data[i+1]
+			end, err := skipGIFSubBlocks(data, i+2)
+			if err != nil {
+				return nil, err
+			}
+			i = end
+
+			switch label {
+			case 0xFE:
+				continue
+			case 0xFF:
+				if !isGIFLoopExtension(data[start+2 : end]) {
+					continue
+				}
+			}
+			out.Write(data[start:end])
+		case 0x2C:
+			if i+10 > len(data) {
+				return nil, errMalformed
+			}
+			i += 10
+			if flags := data[start+9]; flags&0x80 != 0 {
+				i += 3 << ((flags & 0x07) + 1)
+			}
+			// LZW の最小コードサイズ
+			i++
+			if i > len(data) {
+				return nil, errMalformed
+			}
+			end, err := skipGIFSubBlocks(data, i)
+			if err != nil {
+				return nil, err
+			}
+			i = end
+			out.Write(data[start:end])
+		case 0x3B:
+			out.WriteByte(0x3B)
+			return out.Bytes(), nil
+		default:
+			return nil, errMalformed
+		}
+	}
+	return nil, errMalformed
+}
+
+func skipGIFSubBlocks(data []byte, i int) (int, error) {
+	for {
+		if i >= len(data) {
+			return 0, errMalformed
+		}
+		size := int(data[i])
+		i++
+		if size == 0 {
+			return i, nil
+		}
+		i += size
+	}
+}
+
+func isGIFLoopExtension(subBlocks []byte) bool {
+	if len(subBlocks) < 12 || subBlocks[0] != 11 {
+		return false
+	}
+	identifier := string(subBlocks[1:12])
+	return identifier == "NETSCAPE2.0" || identifier == "ANIMEXTS1.0"
+}
+
+// EXIF と XMP のチャンクを取り除き、VP8X のフラグを合わせる
+func stripWebP(data []byte) ([]byte, *exifData, error) {
+	if len(data) < 12 {
+		return nil, nil, errMalformed
+	}
+
+	out := bytes.NewBuffer(make([]byte, 0, len(data)))
+	out.Write(data[:12])
+
+	var exif *exifData
+	i := 12
+	for i+8 <= len(data) {
+		size := binary.LittleEndian.Uint32(data[i+4 : i+8])
+		if uint64(size) > uint64(len(data)-i-8) {
+			return nil, nil, errMalformed
+		}
+		end := i + 8 + int(size)
+		// チャンクは偶数バイトに揃える
+		padded := end + int(size&1)
+		if padded > len(data) {
+			padded = len(data)
+		}
+		payload := data[i+8 : end]
+
+		switch string(data[i : i+4]) {
+		case "EXIF":
+			tiff, _ := bytes.CutPrefix(payload, []byte("Exif\x00\x00"))
+			exif, _ = parseEXIF(tiff)
+		case "XMP ":
+			// 残さない
+		case "VP8X":
+			chunk := bytes.Clone(data[i:padded])
+			if len(chunk) > 8 {
+				// EXIF (0x08) と XMP (0x04) のフラグを下ろす
+				chunk[8] &^= 0x08 | 0x04
+			}
+			out.Write(chunk)
+		default:
+			out.Write(data[i:padded])
+		}
+		i = padded
+	}
+
+	stripped := out.Bytes()
+	binary.LittleEndian.PutUint32(stripped[4:8], uint32(len(stripped)-8))
+	return stripped, exif, nil
+}
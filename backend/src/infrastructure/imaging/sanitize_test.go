@@ -0,0 +1,404 @@
+package imaging
+
+import (
+	"bytes"
+	"encoding/binary"
+	"hash/crc32"
+	"image"
+	"image/color"
+	"image/gif"
+	"image/jpeg"
+	"image/png"
+	"math"
+	"testing"
+)
+
+var (
+	testGPS = &GPS{Latitude: 35.5, Longitude: -139.25}
+	red     = color.NRGBA{R: 255, A: 255}
+	blue    = color.NRGBA{B: 255, A: 255}
+)
+
+func TestSanitize(t *testing.T) {
+	tests := []struct {
+		name       string
+		data       []byte
+		keepGPS    bool
+		wantMime   string
+		wantWidth  int
+		wantHeight int
+		wantGPS    *GPS
+		// 向きを補正した結果、上半分が赤になっているか確認する
+		wantRedTop bool
+	}{
+		{name: "jpeg without metadata", data: jpegFixture(t, nil), wantMime: "image/jpeg", wantWidth: 16, wantHeight: 8},
+		{name: "jpeg gps removed", data: jpegFixture(t, exifFixture(1, testGPS)), wantMime: "image/jpeg", wantWidth: 16, wantHeight: 8},
+		{name: "jpeg gps kept", data: jpegFixture(t, exifFixture(1, testGPS)), keepGPS: true, wantMime: "image/jpeg", wantWidth: 16, wantHeight: 8, wantGPS: testGPS},
+		{name: "jpeg rotated", data: jpegFixture(t, exifFixture(6, nil)), wantMime: "image/jpeg", wantWidth: 8, wantHeight: 16, wantRedTop: true},
+		{name: "jpeg corrupt exif", data: jpegFixture(t, []byte("II*\x00\xff\xff\xff\xff")), keepGPS: true, wantMime: "image/jpeg", wantWidth: 16, wantHeight: 8},
+		{name: "png gps removed", data: pngFixture(t, exifFixture(1, testGPS)), wantMime: "image/png", wantWidth: 16, wantHeight: 8},
+		{name: "png rotated", data: pngFixture(t, exifFixture(6, testGPS)), keepGPS: true, wantMime: "image/png", wantWidth: 8, wantHeight: 16, wantGPS: testGPS, wantRedTop: true},
+		{name: "gif", data: gifFixture(t), wantMime: "image/gif", wantWidth: 16, wantHeight: 8},
+		{name: "webp without orientation", data: webpFixture(4, 2, 255, exifFixture(1, testGPS)), wantMime: "image/webp", wantWidth: 4, wantHeight: 2},
+		{name: "webp opaque rotated", data: webpFixture(4, 2, 255, exifFixture(6, nil)), wantMime: "image/jpeg", wantWidth: 2, wantHeight: 4},
+		{name: "webp transparent rotated", data: webpFixture(4, 2, 128, exifFixture(8, testGPS)), keepGPS: true, wantMime: "image/png", wantWidth: 2, wantHeight: 4, wantGPS: testGPS},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			info, err := Inspect("", tt.data)
+			if err != nil {
+				t.Fatalf("Inspect: %v", err)
+			}
+
+			sanitized, err := Sanitize(tt.data, info, tt.keepGPS)
+			if err != nil {
+				t.Fatalf("Sanitize: %v", err)
+			}
+
+			if sanitized.MimeType != tt.wantMime {
+				t.Errorf("MimeType = %s, want %s", sanitized.MimeType, tt.wantMime)
+			}
+			if sanitized.Width != tt.wantWidth || sanitized.Height != tt.wantHeight {
+				t.Errorf("size = %dx%d, want %dx%d", sanitized.Width, sanitized.Height, tt.wantWidth, tt.wantHeight)
+			}
+			if !sameGPS(sanitized.GPS, tt.wantGPS) {
+				t.Errorf("GPS = %+v, want %+v", sanitized.GPS, tt.wantGPS)
+			}
+
+			// 返した内容自体が MIME タイプと大きさに一致し、メタデータを含まないこと
+			sanitizedInfo, err := Inspect("", sanitized.Data)
+			if err != nil {
+				t.Fatalf("Inspect sanitized: %v", err)
+			}
+			if sanitizedInfo.MimeType != tt.wantMime || sanitizedInfo.Width != tt.wantWidth || sanitizedInfo.Height != tt.wantHeight {
+				t.Errorf("sanitized data is %s %dx%d", sanitizedInfo.MimeType, sanitizedInfo.Width, sanitizedInfo.Height)
+			}
+			for _, marker := range [][]byte{[]byte("Exif"), []byte("eXIf"), []byte("EXIF"), []byte("secret comment")} {
+				if bytes.Contains(sanitized.Data, marker) {
+					t.Errorf("sanitized data still contains %q", marker)
+				}
+			}
+
+			if tt.wantRedTop {
+				img, _, err := image.Decode(bytes.NewReader(sanitized.Data))
+				if err != nil {
+					t.Fatal(err)
+				}
+				bounds := img.Bounds()
+				if !isReddish(img.At(bounds.Dx()/2, bounds.Dy()/4)) || isReddish(img.At(bounds.Dx()/2, bounds.Dy()*3/4)) {
+					t.Errorf("orientation was not applied to the pixels")
+				}
+			}
+		})
+	}
+}
+
+func TestSanitizeMalformed(t *testing.T) {
+	jpegData := jpegFixture(t, exifFixture(6, testGPS))
+	pngData := pngFixture(t, exifFixture(6, testGPS))
+	gifData := gifFixture(t)
+	webpData := webpFixture(4, 2, 255, exifFixture(6, testGPS))
+
+	tests := []struct {
+		name   string
+		format string
+		data   []byte
+	}{
+		{name: "jpeg without start marker", format: "jpeg", data: []byte{0xFF, 0xD9, 0xFF, 0xE0}},
+		{name: "jpeg truncated in a segment", format: "jpeg", data: jpegData[:30]},
+		{name: "jpeg without scan", format: "jpeg", data: []byte{0xFF, 0xD8, 0xFF, 0xE0, 0x00, 0x02}},
+		{name: "jpeg oversized segment length", format: "jpeg", data: []byte{0xFF, 0xD8, 0xFF, 0xE1, 0xFF, 0xFF, 'E', 'x'}},
+		{name: "jpeg undersized segment length", format: "jpeg", data: []byte{0xFF, 0xD8, 0xFF, 0xE1, 0x00, 0x01, 0xFF, 0xDA}},
+		{name: "png without signature", format: "png", data: []byte("PNG\r\n")},
+		{name: "png truncated", format: "png", data: pngData[:len(pngData)/2]},
+		{name: "png without IEND", format: "png", data: pngData[:len(pngData)-12]},
+		{name: "png oversized chunk length", format: "png", data: withUint32(pngData, 8, binary.BigEndian, math.MaxUint32)},
+		{name: "gif header only", format: "gif", data: gifData[:10]},
+		{name: "gif truncated", format: "gif", data: gifData[:len(gifData)-4]},
+		{name: "gif oversized color table", format: "gif", data: gifData[:14]},
+		{name: "webp header only", format: "webp", data: webpData[:8]},
+		{name: "webp oversized chunk length", format: "webp", data: withUint32(webpData, 16, binary.LittleEndian, math.MaxUint32)},
+		{name: "webp truncated", format: "webp", data: webpData[:len(webpData)/2]},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			info := &Info{Format: tt.format, MimeType: mimeTypes[tt.format], Width: 16, Height: 8}
+			if _, err := Sanitize(tt.data, info, true); err == nil {
+				t.Errorf("Sanitize succeeded, want an error")
+			}
+		})
+	}
+}
+
+// どこで途切れた入力や、どのバイトが壊れた入力でも panic しない
+func TestSanitizeDoesNotPanic(t *testing.T) {
+	fixtures := map[string][]byte{
+		"jpeg": jpegFixture(t, exifFixture(6, testGPS)),
+		"png":  pngFixture(t, exifFixture(6, testGPS)),
+		"gif":  gifFixture(t),
+		"webp": webpFixture(4, 2, 255, exifFixture(6, testGPS)),
+	}
+	for format, data := range fixtures {
+		info := &Info{Format: format, MimeType: mimeTypes[format], Width: 16, Height: 8}
+		for n := 0; n <= len(data); n++ {
+			sanitizeWithoutPanic(t, format, data[:n], info)
+		}
+		for i := range data {
+			for _, value := range []byte{0x00, 0xFF} {
+				corrupted := bytes.Clone(data)
+				corrupted[i] = value
+				sanitizeWithoutPanic(t, format, corrupted, info)
+			}
+		}
+	}
+}
+
+func sanitizeWithoutPanic(t *testing.T, format string, data []byte, info *Info) {
+	t.Helper()
+	defer func() {
+		if rec := recover(); rec != nil {
+			t.Fatalf("Sanitize panicked on %d bytes of %s: %v", len(data), format, rec)
+		}
+	}()
+	Sanitize(data, info, true)
+}
+
+func sameGPS(got, want *GPS) bool {
+	if got == nil || want == nil {
+		return got == want
+	}
+	return math.Abs(got.Latitude-want.Latitude) < 1e-9 && math.Abs(got.Longitude-want.Longitude) < 1e-9
+}
+
+func isReddish(c color.Color) bool {
+	r, g, b, _ := c.RGBA()
+	return r > 0x8000 && g < 0x4000 && b < 0x4000
+}
+
+func withUint32(data []byte, at int, order binary.ByteOrder, value uint32) []byte {
+	data = bytes.Clone(data)
+	order.PutUint32(data[at:at+4], value)
+	return data
+}
+
+// 左半分が赤、右半分が青の 16x8 の画像
+func halfRedImage() *image.NRGBA {
+	img := image.NewNRGBA(image.Rect(0, 0, 16, 8))
+	for y := 0; y < 8; y++ {
+		for x := 0; x < 16; x++ {
+			if x < 8 {
+				img.SetNRGBA(x, y, red)
+			} else {
+				img.SetNRGBA(x, y, blue)
+			}
+		}
+	}
+	return img
+}
+
+// リトルエンディアンの TIFF 形式の EXIF。gps が nil の場合は GPS IFD を含めない
+func exifFixture(orientation uint16, gps *GPS) []byte {
+	le := binary.LittleEndian
+	entryCount := 1
+	if gps != nil {
+		entryCount = 2
+	}
+	ifd0Size := 2 + 12*entryCount + 4
+	gpsOffset := 8 + ifd0Size
+	gpsSize := 2 + 12*4 + 4
+	latitudeOffset := gpsOffset + gpsSize
+	longitudeOffset := latitudeOffset + 24
+
+	var buf bytes.Buffer
+	buf.WriteString("II")
+	binary.Write(&buf, le, uint16(42))
+	binary.Write(&buf, le, uint32(8))
+
+	writeEntry := func(tag, typ uint16, count uint32, value []byte) {
+		binary.Write(&buf, le, tag)
+		binary.Write(&buf, le, typ)
+		binary.Write(&buf, le, count)
+		var inline [4]byte
+		copy(inline[:], value)
+		buf.Write(inline[:])
+	}
+	uint32Bytes := func(v int) []byte {
+		return le.AppendUint32(nil, uint32(v))
+	}
+
+	binary.Write(&buf, le, uint16(entryCount))
+	writeEntry(exifTagOrientation, exifTypeShort, 1, le.AppendUint16(nil, orientation))
+	if gps != nil {
+		writeEntry(exifTagGPSIFD, exifTypeLong, 1, uint32Bytes(gpsOffset))
+	}
+	binary.Write(&buf, le, uint32(0))
+
+	if gps == nil {
+		return buf.Bytes()
+	}
+
+	latitudeRef, longitudeRef := "N", "E"
+	if gps.Latitude < 0 {
+		latitudeRef = "S"
+	}
+	if gps.Longitude < 0 {
+		longitudeRef = "W"
+	}
+	binary.Write(&buf, le, uint16(4))
+	writeEntry(gpsTagLatitudeRef, exifTypeASCII, 2, []byte(latitudeRef))
+	writeEntry(gpsTagLatitude, exifTypeRational, 3, uint32Bytes(latitudeOffset))
+	writeEntry(gpsTagLongitudeRef, exifTypeASCII, 2, []byte(longitudeRef))
+	writeEntry(gpsTagLongitude, exifTypeRational, 3, uint32Bytes(longitudeOffset))
+	binary.Write(&buf, le, uint32(0))
+
+	// 度、分、秒を 1/100 単位の有理数で書く
+	for _, degrees := range []float64{math.Abs(gps.Latitude), math.Abs(gps.Longitude)} {
+		whole := math.Floor(degrees)
+		minutes := (degrees - whole) * 60
+		for _, value := range []float64{whole, math.Floor(minutes), (minutes - math.Floor(minutes)) * 60} {
+			binary.Write(&buf, le, uint32(math.Round(value*100)))
+			binary.Write(&buf, le, uint32(100))
+		}
+	}
+	return buf.Bytes()
+}
+
+// SOI の直後に EXIF の APP1 とコメントを挿入した JPEG。exif が nil の場合はどちらも挿入しない
+func jpegFixture(t *testing.T, exif []byte) []byte {
+	t.Helper()
+	var encoded bytes.Buffer
+	if err := jpeg.Encode(&encoded, halfRedImage(), &jpeg.Options{Quality: 95}); err != nil {
+		t.Fatal(err)
+	}
+	if exif == nil {
+		return encoded.Bytes()
+	}
+
+	segment := func(marker byte, payload []byte) []byte {
+		return append([]byte{0xFF, marker, byte((len(payload) + 2) >> 8), byte(len(payload) + 2)}, payload...)
+	}
+
+	data := bytes.Clone(encoded.Bytes()[:2])
+	data = append(data, segment(0xE1, append([]byte("Exif\x00\x00"), exif...))...)
+	data = append(data, segment(0xFE, []byte("secret comment"))...)
+	return append(data, encoded.Bytes()[2:]...)
+}
+
+// IHDR の直後に eXIf と tEXt のチャンクを挿入した PNG
+func pngFixture(t *testing.T, exif []byte) []byte {
+	t.Helper()
+	var encoded bytes.Buffer
+	if err := png.Encode(&encoded, halfRedImage()); err != nil {
+		t.Fatal(err)
+	}
+
+	chunk := func(chunkType string, payload []byte) []byte {
+		out := binary.BigEndian.AppendUint32(nil, uint32(len(payload)))
+		out = append(out, chunkType...)
+		out = append(out, payload...)
+		return binary.BigEndian.AppendUint32(out, crc32.ChecksumIEEE(append([]byte(chunkType), payload...)))
+	}
+
+	// シグネチャ (8) と IHDR (25) の後
+	ihdrEnd := len(pngSignature) + 25
+	data := bytes.Clone(encoded.Bytes()[:ihdrEnd])
+	data = append(data, chunk("eXIf", exif)...)
+	data = append(data, chunk("tEXt", []byte("Comment\x00secret comment"))...)
+	return append(data, encoded.Bytes()[ihdrEnd:]...)
+}
+
+// 終端の直前にコメントと XMP の拡張を挿入した GIF
+func gifFixture(t *testing.T) []byte {
+	t.Helper()
+	palette := color.Palette{red, blue}
+	img := image.NewPaletted(image.Rect(0, 0, 16, 8), palette)
+	for y := 0; y < 8; y++ {
+		for x := 8; x < 16; x++ {
+			img.SetColorIndex(x, y, 1)
+		}
+	}
+
+	var encoded bytes.Buffer
+	if err := gif.Encode(&encoded, img, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	data := bytes.Clone(encoded.Bytes()[:encoded.Len()-1])
+	comment := "secret comment"
+	data = append(data, 0x21, 0xFE, byte(len(comment)))
+	data = append(data, comment...)
+	data = append(data, 0x00)
+	data = append(data, 0x21, 0xFF, 11)
+	data = append(data, "XMP DataXMP"...)
+	data = append(data, 4, 'x', 'm', 'p', '!', 0x00)
+	return append(data, 0x3B)
+}
+
+// 単色のロスレス (VP8L) 画像に EXIF を付けた拡張形式の WebP。
+// エンコーダーが無いため、各チャンネルを1つの値だけを持つ単純なプレフィックス符号で書く
+func webpFixture(width, height int, alpha byte, exif []byte) []byte {
+	var bits bitWriter
+	bits.write(uint32(width-1), 14)
+	bits.write(uint32(height-1), 14)
+	if alpha != 255 {
+		bits.write(1, 1)
+	} else {
+		bits.write(0, 1)
+	}
+	bits.write(0, 3) // バージョン
+	bits.write(0, 1) // 変換なし
+	bits.write(0, 1) // カラーキャッシュなし
+	bits.write(0, 1) // メタプレフィックス符号なし
+	// 緑、赤、青、アルファ、距離の順。値が1つの符号は画素ごとにビットを消費しない
+	for _, symbol := range []byte{0, 255, 0, alpha, 0} {
+		bits.write(1, 1) // 単純な符号
+		bits.write(0, 1) // 値は1つ
+		bits.write(1, 1) // 8ビットの値
+		bits.write(uint32(symbol), 8)
+	}
+	vp8l := append([]byte{0x2F}, bits.bytes()...)
+
+	chunk := func(chunkType string, payload []byte) []byte {
+		out := append([]byte(chunkType), binary.LittleEndian.AppendUint32(nil, uint32(len(payload)))...)
+		out = append(out, payload...)
+		if len(payload)%2 == 1 {
+			out = append(out, 0)
+		}
+		return out
+	}
+
+	// EXIF のフラグのみ立てる。golang.org/x/image/webp は VP8L と VP8X のアルファのフラグを併用すると読めない
+	vp8x := []byte{0x08, 0, 0, 0}
+	vp8x = append(vp8x, byte(width-1), byte((width-1)>>8), byte((width-1)>>16))
+	vp8x = append(vp8x, byte(height-1), byte((height-1)>>8), byte((height-1)>>16))
+
+	body := []byte("WEBP")
+	body = append(body, chunk("VP8X", vp8x)...)
+	body = append(body, chunk("VP8L", vp8l)...)
+	body = append(body, chunk("EXIF", exif)...)
+
+	data := append([]byte("RIFF"), binary.LittleEndian.AppendUint32(nil, uint32(len(body)))...)
+	return append(data, body...)
+}
+
+// VP8L のビット列は下位ビットから詰める
+type bitWriter struct {
+	buf   []byte
+	nBits uint
+}
+
+func (w *bitWriter) write(value uint32, n uint) {
+	for i := uint(0); i < n; i++ {
+		if w.nBits%8 == 0 {
+			w.buf = append(w.buf, 0)
+		}
+		if value&(1<<i) != 0 {
+			w.buf[len(w.buf)-1] |= 1 << (w.nBits % 8)
+		}
+		w.nBits++
+	}
+}
+
+func (w *bitWriter) bytes() []byte {
+	return w.buf
+}
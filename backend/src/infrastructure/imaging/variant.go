@@ -0,0 +1,106 @@
+package imaging
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	"image/jpeg"
+	"image/png"
+
+	"golang.org/x/image/draw"
+)
+
+const (
+	VariantThumbnail = "thumbnail"
+	VariantMedium    = "medium"
+	// メタデータを取り除いたアップロード画像そのもの。派生画像としては保存しない
+	VariantOriginal = "original"
+)
+
+// 長辺を MaxSize ピクセル以下に縮小した派生画像
+type VariantSpec struct {
+	Name    string
+	MaxSize int
+}
+
+type VariantOptions struct {
+	Specs []VariantSpec
+	// 不透明な画像を JPEG で保存する場合の品質
+	JPEGQuality int
+}
+
+type Variant struct {
+	Name     string
+	Data     []byte
+	MimeType string
+	Width    int
+	Height   int
+}
+
+// 縮小画像とプレースホルダーの生成用に画素データを展開する。
+// アニメーション GIF は先頭のフレームになる。Sanitize 済みのデータを渡す
+func Decode(data []byte) (image.Image, error) {
+	img, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode image: %w", err)
+	}
+	return img, nil
+}
+
+// 元画像より小さくなる派生画像のみ生成する。透過を含む画像は PNG、それ以外は JPEG で保存する
+func GenerateVariants(src image.Image, options VariantOptions) ([]Variant, error) {
+	var err error
+	bounds := src.Bounds()
+	opaque := isOpaque(src)
+
+	var variants []Variant
+	for _, spec := range options.Specs {
+		width, height, ok := fitWithin(bounds.Dx(), bounds.Dy(), spec.MaxSize)
+		if !ok {
+			continue
+		}
+
+		dst := image.NewRGBA(image.Rect(0, 0, width, height))
+		draw.CatmullRom.Scale(dst, dst.Bounds(), src, bounds, draw.Src, nil)
+
+		var buf bytes.Buffer
+		mimeType := mimeTypes["png"]
+		if opaque {
+			mimeType = mimeTypes["jpeg"]
+			err = jpeg.Encode(&buf, dst, &jpeg.Options{Quality: options.JPEGQuality})
+		} else {
+			err = png.Encode(&buf, dst)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to encode %s variant: %w", spec.Name, err)
+		}
+
+		variants = append(variants, Variant{
+			Name:     spec.Name,
+			Data:     buf.Bytes(),
+			MimeType: mimeType,
+			Width:    width,
+			Height:   height,
+		})
+	}
+
+	return variants, nil
+}
+
+// 長辺を maxSize に合わせた大きさ。既に収まっている場合は false
+func fitWithin(width, height, maxSize int) (int, int, bool) {
+	if width <= maxSize && height <= maxSize {
+		return 0, 0, false
+	}
+	if width >= height {
+		return maxSize, max(1, (height*maxSize+width/2)/width), true
+	}
+	return max(1, (width*maxSize+height/2)/height), maxSize, true
+}
+
+func isOpaque(img image.Image) bool {
+	if o, ok := img.(interface{ Opaque() bool }); ok {
+		return o.Opaque()
+	}
+	return true
+}
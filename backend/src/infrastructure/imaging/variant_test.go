@@ -0,0 +1,81 @@
+package imaging
+
+import (
+	"image"
+	"image/color"
+	"testing"
+)
+
+var testVariantOptions = VariantOptions{
+	Specs: []VariantSpec{
+		{Name: VariantThumbnail, MaxSize: 100},
+		{Name: VariantMedium, MaxSize: 300},
+	},
+	JPEGQuality: 80,
+}
+
+func TestGenerateVariants(t *testing.T) {
+	opaque := color.NRGBA{R: 255, A: 255}
+	transparent := color.NRGBA{R: 255, A: 128}
+
+	type size struct {
+		name          string
+		width, height int
+	}
+	tests := []struct {
+		name     string
+		img      image.Image
+		want     []size
+		wantMime string
+	}{
+		{name: "landscape", img: uniformImage(400, 200, opaque), want: []size{{VariantThumbnail, 100, 50}, {VariantMedium, 300, 150}}, wantMime: "image/jpeg"},
+		{name: "portrait", img: uniformImage(200, 400, opaque), want: []size{{VariantThumbnail, 50, 100}, {VariantMedium, 150, 300}}, wantMime: "image/jpeg"},
+		{name: "square", img: uniformImage(500, 500, opaque), want: []size{{VariantThumbnail, 100, 100}, {VariantMedium, 300, 300}}, wantMime: "image/jpeg"},
+		// 元画像より小さくならない派生画像は作らない
+		{name: "between sizes", img: uniformImage(200, 150, opaque), want: []size{{VariantThumbnail, 100, 75}}, wantMime: "image/jpeg"},
+		{name: "at thumbnail size", img: uniformImage(100, 40, opaque), want: nil},
+		{name: "smaller than thumbnail", img: uniformImage(16, 8, opaque), want: nil},
+		// 細長い画像でも 1px 未満にはしない
+		{name: "very wide", img: uniformImage(1000, 1, opaque), want: []size{{VariantThumbnail, 100, 1}, {VariantMedium, 300, 1}}, wantMime: "image/jpeg"},
+		{name: "rounded", img: uniformImage(301, 200, opaque), want: []size{{VariantThumbnail, 100, 66}, {VariantMedium, 300, 199}}, wantMime: "image/jpeg"},
+		{name: "transparent", img: uniformImage(400, 200, transparent), want: []size{{VariantThumbnail, 100, 50}, {VariantMedium, 300, 150}}, wantMime: "image/png"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			variants, err := GenerateVariants(tt.img, testVariantOptions)
+			if err != nil {
+				t.Fatalf("GenerateVariants: %v", err)
+			}
+			if len(variants) != len(tt.want) {
+				t.Fatalf("got %d variants, want %d", len(variants), len(tt.want))
+			}
+
+			for i, variant := range variants {
+				want := tt.want[i]
+				if variant.Name != want.name || variant.Width != want.width || variant.Height != want.height || variant.MimeType != tt.wantMime {
+					t.Errorf("variant = %s %s %dx%d, want %s %s %dx%d",
+						variant.Name, variant.MimeType, variant.Width, variant.Height, want.name, tt.wantMime, want.width, want.height)
+				}
+
+				// 保存するデータ自体が返した形式と大きさに一致すること
+				info, err := Inspect("", variant.Data)
+				if err != nil {
+					t.Fatalf("Inspect %s: %v", variant.Name, err)
+				}
+				if info.MimeType != variant.MimeType || info.Width != variant.Width || info.Height != variant.Height {
+					t.Errorf("%s data is %s %dx%d", variant.Name, info.MimeType, info.Width, info.Height)
+				}
+			}
+		})
+	}
+}
+
+func uniformImage(width, height int, c color.NRGBA) *image.NRGBA {
+	img := image.NewNRGBA(image.Rect(0, 0, width, height))
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			img.SetNRGBA(x, y, c)
+		}
+	}
+	return img
+}
@@ -0,0 +1,13 @@
+package mapper
+
+import (
+	"proto-pulse-plat/infrastructure/model"
+)
+
+func ToModelAppSetting(key, value string, updatedBy uint) model.AppSetting {
+	return model.AppSetting{
+		Key:       key,
+		Value:     value,
+		UpdatedBy: updatedBy,
+	}
+}
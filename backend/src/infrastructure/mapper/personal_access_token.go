@@ -0,0 +1,22 @@
+package mapper
+
+import (
+	"proto-pulse-plat/infrastructure/model"
+	"time"
+)
+
+func ToModelPersonalAccessToken(
+	userID uint,
+	name, tokenPrefix, tokenHash string,
+	scopes []string,
+	expiresAt *time.Time,
+) model.PersonalAccessToken {
+	return model.PersonalAccessToken{
+		UserID:      userID,
+		Name:        name,
+		TokenPrefix: tokenPrefix,
+		TokenHash:   tokenHash,
+		Scopes:      scopes,
+		ExpiresAt:   expiresAt,
+	}
+}
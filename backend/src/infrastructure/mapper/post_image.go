@@ -4,10 +4,34 @@ import (
 	"proto-pulse-plat/infrastructure/model"
 )
 
-func ToModelPostImage(fileName string, postID uint, datas []byte) model.PostImage {
+func ToModelPostImage(
+	fileName string,
+	postID uint,
+	storageKey string,
+	size int64,
+	checksum string,
+	mimeType string,
+	width, height int,
+	blurHash, dominantColor string,
+	sortOrder int,
+	altText, caption string,
+	gpsLatitude, gpsLongitude *float64,
+) model.PostImage {
 	return model.PostImage{
-		FileName: fileName,
-		PostID:   postID,
-		Data:     datas,
+		FileName:      fileName,
+		PostID:        postID,
+		StorageKey:    storageKey,
+		Size:          size,
+		Checksum:      checksum,
+		MimeType:      mimeType,
+		Width:         width,
+		Height:        height,
+		BlurHash:      blurHash,
+		DominantColor: dominantColor,
+		SortOrder:     sortOrder,
+		AltText:       altText,
+		Caption:       caption,
+		GPSLatitude:   gpsLatitude,
+		GPSLongitude:  gpsLongitude,
 	}
 }
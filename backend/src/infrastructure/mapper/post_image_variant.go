@@ -0,0 +1,26 @@
+package mapper
+
+import (
+	"proto-pulse-plat/infrastructure/model"
+)
+
+func ToModelPostImageVariant(
+	postImageID uint,
+	name string,
+	storageKey string,
+	mimeType string,
+	width, height int,
+	size int64,
+	checksum string,
+) model.PostImageVariant {
+	return model.PostImageVariant{
+		PostImageID: postImageID,
+		Name:        name,
+		StorageKey:  storageKey,
+		MimeType:    mimeType,
+		Width:       width,
+		Height:      height,
+		Size:        size,
+		Checksum:    checksum,
+	}
+}
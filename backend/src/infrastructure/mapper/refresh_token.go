@@ -0,0 +1,15 @@
+package mapper
+
+import (
+	"proto-pulse-plat/infrastructure/model"
+	"time"
+)
+
+func ToModelRefreshToken(sessionID string, userID uint, tokenHash string, expiresAt time.Time) model.RefreshToken {
+	return model.RefreshToken{
+		SessionID: sessionID,
+		UserID:    userID,
+		TokenHash: tokenHash,
+		ExpiresAt: expiresAt,
+	}
+}
@@ -0,0 +1,16 @@
+package mapper
+
+import (
+	"proto-pulse-plat/infrastructure/model"
+	"time"
+)
+
+func ToModelSession(id string, userID uint, userAgent string, now, expiresAt time.Time) model.Session {
+	return model.Session{
+		ID:         id,
+		UserID:     userID,
+		UserAgent:  userAgent,
+		LastSeenAt: now,
+		ExpiresAt:  expiresAt,
+	}
+}
@@ -4,11 +4,16 @@ import (
 	"proto-pulse-plat/infrastructure/model"
 )
 
-func ToModelUser(userName, accountID, iconFileName string, iconData []byte) model.User {
+func ToModelUser(
+	userName, accountID, iconFileName string,
+	iconStorageKey, iconMimeType, iconHash string,
+) model.User {
 	return model.User{
-		UserName:     userName,
-		AccountID:    accountID,
-		IconFileName: iconFileName,
-		IconData:     iconData,
+		UserName:       userName,
+		AccountID:      accountID,
+		IconFileName:   iconFileName,
+		IconStorageKey: iconStorageKey,
+		IconMimeType:   iconMimeType,
+		IconHash:       iconHash,
 	}
 }
@@ -0,0 +1,14 @@
+package mapper
+
+import (
+	"proto-pulse-plat/infrastructure/model"
+)
+
+func ToModelUserIdentity(userID uint, provider, externalID, handle string) model.UserIdentity {
+	return model.UserIdentity{
+		UserID:     userID,
+		Provider:   provider,
+		ExternalID: externalID,
+		Handle:     handle,
+	}
+}
@@ -0,0 +1,15 @@
+package mapper
+
+import (
+	"proto-pulse-plat/infrastructure/model"
+	"time"
+)
+
+func ToModelUserSuspension(userID uint, reason string, createdBy uint, expiresAt *time.Time) model.UserSuspension {
+	return model.UserSuspension{
+		UserID:    userID,
+		Reason:    reason,
+		CreatedBy: createdBy,
+		ExpiresAt: expiresAt,
+	}
+}
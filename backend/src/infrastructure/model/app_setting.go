@@ -0,0 +1,7 @@
+package model
+
+type AppSetting struct {
+	Key       string `json:"key"`
+	Value     string `json:"value"`
+	UpdatedBy uint   `json:"updated_by"`
+}
@@ -0,0 +1,12 @@
+package model
+
+import "time"
+
+type PersonalAccessToken struct {
+	UserID      uint       `json:"user_id"`
+	Name        string     `json:"name"`
+	TokenPrefix string     `json:"token_prefix"`
+	TokenHash   string     `json:"token_hash"`
+	Scopes      []string   `json:"scopes"`
+	ExpiresAt   *time.Time `json:"expires_at"`
+}
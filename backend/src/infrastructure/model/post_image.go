@@ -3,10 +3,23 @@ package model
 import "time"
 
 type PostImage struct {
-	ID        uint      `json:"id"`
-	FileName  string    `json:"file_name"`
-	PostID    uint      `json:"post_id"`
-	Data      []byte    `json:"data"`
-	CreatedAt time.Time `json:"created_at"`
-	UpdatedAt time.Time `json:"updated_at"`
+	ID            uint      `json:"id"`
+	FileName      string    `json:"file_name"`
+	PostID        uint      `json:"post_id"`
+	StorageKey    string    `json:"storage_key"`
+	Size          int64     `json:"size"`
+	Checksum      string    `json:"checksum"`
+	MimeType      string    `json:"mime_type"`
+	Width         int       `json:"width"`
+	Height        int       `json:"height"`
+	BlurHash      string    `json:"blur_hash"`
+	DominantColor string    `json:"dominant_color"`
+	SortOrder     int       `json:"sort_order"`
+	AltText       string    `json:"alt_text"`
+	Caption       string    `json:"caption"`
+	IsCover       bool      `json:"is_cover"`
+	GPSLatitude   *float64  `json:"gps_latitude"`
+	GPSLongitude  *float64  `json:"gps_longitude"`
+	CreatedAt     time.Time `json:"created_at"`
+	UpdatedAt     time.Time `json:"updated_at"`
 }
@@ -0,0 +1,12 @@
+package model
+
+type PostImageVariant struct {
+	PostImageID uint   `json:"post_image_id"`
+	Name        string `json:"name"`
+	StorageKey  string `json:"storage_key"`
+	MimeType    string `json:"mime_type"`
+	Width       int    `json:"width"`
+	Height      int    `json:"height"`
+	Size        int64  `json:"size"`
+	Checksum    string `json:"checksum"`
+}
@@ -0,0 +1,10 @@
+package model
+
+import "time"
+
+type RefreshToken struct {
+	SessionID string    `json:"session_id"`
+	UserID    uint      `json:"user_id"`
+	TokenHash string    `json:"token_hash"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
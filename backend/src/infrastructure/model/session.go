@@ -0,0 +1,11 @@
+package model
+
+import "time"
+
+type Session struct {
+	ID         string    `json:"id"`
+	UserID     uint      `json:"user_id"`
+	UserAgent  string    `json:"user_agent"`
+	LastSeenAt time.Time `json:"last_seen_at"`
+	ExpiresAt  time.Time `json:"expires_at"`
+}
@@ -1,9 +1,11 @@
 package model
 
 type User struct {
-	ID           uint   `gorm:"primaryKey"`
-	UserName     string `json:"user_name"`
-	AccountID    string `json:"account_id"`
-	IconFileName string `json:"icon_file_name"`
-	IconData     []byte `json:"icon_data"`
+	ID             uint   `gorm:"primaryKey"`
+	UserName       string `json:"user_name"`
+	AccountID      string `json:"account_id"`
+	IconFileName   string `json:"icon_file_name"`
+	IconStorageKey string `json:"icon_storage_key"`
+	IconMimeType   string `json:"icon_mime_type"`
+	IconHash       string `json:"icon_hash"`
 }
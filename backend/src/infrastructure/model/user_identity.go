@@ -0,0 +1,8 @@
+package model
+
+type UserIdentity struct {
+	UserID     uint   `json:"user_id"`
+	Provider   string `json:"provider"`
+	ExternalID string `json:"external_id"`
+	Handle     string `json:"handle"`
+}
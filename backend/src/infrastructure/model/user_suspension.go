@@ -0,0 +1,10 @@
+package model
+
+import "time"
+
+type UserSuspension struct {
+	UserID    uint       `json:"user_id"`
+	Reason    string     `json:"reason"`
+	CreatedBy uint       `json:"created_by"`
+	ExpiresAt *time.Time `json:"expires_at"`
+}
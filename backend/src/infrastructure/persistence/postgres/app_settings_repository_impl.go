@@ -0,0 +1,89 @@
+package postgres
+
+import (
+	"errors"
+	"fmt"
+	"proto-pulse-plat/domain/entity"
+	"proto-pulse-plat/infrastructure/model"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type GormAppSettingsRepository struct {
+	DB *gorm.DB
+}
+
+type AppSetting struct {
+	Key       string `gorm:"primaryKey;size:64"`
+	Value     string `gorm:"type:text;not null"`
+	UpdatedBy *uint
+	CreatedAt time.Time
+	UpdatedAt time.Time
+}
+
+func ToEntityAppSetting(setting AppSetting) *entity.AppSetting {
+	return &entity.AppSetting{
+		Key:       setting.Key,
+		Value:     setting.Value,
+		UpdatedBy: setting.UpdatedBy,
+		CreatedAt: setting.CreatedAt,
+		UpdatedAt: setting.UpdatedAt,
+	}
+}
+
+func NewGormAppSettingsRepository(db *gorm.DB) *GormAppSettingsRepository {
+	return &GormAppSettingsRepository{
+		DB: db,
+	}
+}
+
+func (r *GormAppSettingsRepository) FindAll() ([]entity.AppSetting, error) {
+	var settings []AppSetting
+
+	result := r.DB.Order("key ASC").Find(&settings)
+	if result.Error != nil {
+		return nil, fmt.Errorf("failed to retrieve app settings: %w", result.Error)
+	}
+
+	entities := make([]entity.AppSetting, 0, len(settings))
+	for _, setting := range settings {
+		entities = append(entities, *ToEntityAppSetting(setting))
+	}
+	return entities, nil
+}
+
+// 未設定の場合は gorm.ErrRecordNotFound を返す
+func (r *GormAppSettingsRepository) Find(key string) (*entity.AppSetting, error) {
+	var setting AppSetting
+
+	result := r.DB.Where("key = ?", key).First(&setting)
+	if result.Error != nil {
+		if errors.Is(result.Error, gorm.ErrRecordNotFound) {
+			return nil, fmt.Errorf("app setting %q: %w", key, gorm.ErrRecordNotFound)
+		}
+		return nil, fmt.Errorf("failed to retrieve app setting %q: %w", key, result.Error)
+	}
+
+	return ToEntityAppSetting(setting), nil
+}
+
+func (r *GormAppSettingsRepository) Save(setting model.AppSetting) (*entity.AppSetting, error) {
+	updatedBy := setting.UpdatedBy
+	newSetting := AppSetting{
+		Key:       setting.Key,
+		Value:     setting.Value,
+		UpdatedBy: &updatedBy,
+	}
+
+	result := r.DB.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "key"}},
+		DoUpdates: clause.AssignmentColumns([]string{"value", "updated_by", "updated_at"}),
+	}).Create(&newSetting)
+	if result.Error != nil {
+		return nil, fmt.Errorf("failed to save app setting %q: %w", setting.Key, result.Error)
+	}
+
+	return ToEntityAppSetting(newSetting), nil
+}
@@ -0,0 +1,51 @@
+package postgres
+
+import (
+	"fmt"
+	"sort"
+
+	"gorm.io/gorm"
+)
+
+// トランザクション単位のアドバイザリロックを使う。ロックはトランザクションの終了時に解放される
+type GormBlobLocksRepository struct {
+	DB *gorm.DB
+}
+
+func NewGormBlobLocksRepository(db *gorm.DB) *GormBlobLocksRepository {
+	return &GormBlobLocksRepository{
+		DB: db,
+	}
+}
+
+func (r *GormBlobLocksRepository) WithSharedLock(keys []string, fn func() error) error {
+	// 複数のキーを待ち合うデッドロックを避けるため、常に同じ順序でロックを取る
+	sorted := make([]string, 0, len(keys))
+	seen := make(map[string]bool, len(keys))
+	for _, key := range keys {
+		if key == "" || seen[key] {
+			continue
+		}
+		seen[key] = true
+		sorted = append(sorted, key)
+	}
+	sort.Strings(sorted)
+
+	return r.DB.Transaction(func(tx *gorm.DB) error {
+		for _, key := range sorted {
+			if err := tx.Exec("SELECT pg_advisory_xact_lock_shared(hashtextextended(?, 0))", key).Error; err != nil {
+				return fmt.Errorf("failed to lock blob %q: %w", key, err)
+			}
+		}
+		return fn()
+	})
+}
+
+func (r *GormBlobLocksRepository) WithExclusiveLock(key string, fn func() error) error {
+	return r.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Exec("SELECT pg_advisory_xact_lock(hashtextextended(?, 0))", key).Error; err != nil {
+			return fmt.Errorf("failed to lock blob %q: %w", key, err)
+		}
+		return fn()
+	})
+}
@@ -0,0 +1,130 @@
+package postgres
+
+import (
+	"errors"
+	"fmt"
+	"proto-pulse-plat/domain/entity"
+	"proto-pulse-plat/infrastructure/model"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type GormPersonalAccessTokensRepository struct {
+	DB *gorm.DB
+}
+
+type PersonalAccessToken struct {
+	ID          uint   `gorm:"primaryKey"`
+	UserID      uint   `gorm:"not null;index"`
+	Name        string `gorm:"size:100;not null"`
+	TokenPrefix string `gorm:"size:16;not null"`
+	TokenHash   string `gorm:"size:64;not null;unique"`
+	// スペース区切りで保存する
+	Scopes     string `gorm:"size:255;not null"`
+	ExpiresAt  *time.Time
+	LastUsedAt *time.Time
+	RevokedAt  *time.Time
+	CreatedAt  time.Time
+	UpdatedAt  time.Time
+}
+
+func ToEntityPersonalAccessToken(token PersonalAccessToken) *entity.PersonalAccessToken {
+	return &entity.PersonalAccessToken{
+		ID:          token.ID,
+		UserID:      token.UserID,
+		Name:        token.Name,
+		TokenPrefix: token.TokenPrefix,
+		TokenHash:   token.TokenHash,
+		Scopes:      strings.Fields(token.Scopes),
+		ExpiresAt:   token.ExpiresAt,
+		LastUsedAt:  token.LastUsedAt,
+		RevokedAt:   token.RevokedAt,
+		CreatedAt:   token.CreatedAt,
+		UpdatedAt:   token.UpdatedAt,
+	}
+}
+
+func NewGormPersonalAccessTokensRepository(db *gorm.DB) *GormPersonalAccessTokensRepository {
+	return &GormPersonalAccessTokensRepository{
+		DB: db,
+	}
+}
+
+func (r *GormPersonalAccessTokensRepository) Save(token model.PersonalAccessToken) (*entity.PersonalAccessToken, error) {
+	newToken := PersonalAccessToken{
+		UserID:      token.UserID,
+		Name:        token.Name,
+		TokenPrefix: token.TokenPrefix,
+		TokenHash:   token.TokenHash,
+		Scopes:      strings.Join(token.Scopes, " "),
+		ExpiresAt:   token.ExpiresAt,
+	}
+
+	result := r.DB.Create(&newToken)
+	if result.Error != nil {
+		return nil, fmt.Errorf("failed to save personal access token: %w", result.Error)
+	}
+
+	return ToEntityPersonalAccessToken(newToken), nil
+}
+
+func (r *GormPersonalAccessTokensRepository) FindActiveByHash(tokenHash string) (*entity.PersonalAccessToken, error) {
+	var token PersonalAccessToken
+
+	result := r.active().Where("token_hash = ?", tokenHash).First(&token)
+	if result.Error != nil {
+		if errors.Is(result.Error, gorm.ErrRecordNotFound) {
+			return nil, gorm.ErrRecordNotFound
+		}
+		return nil, fmt.Errorf("failed to retrieve personal access token by hash: %w", result.Error)
+	}
+
+	return ToEntityPersonalAccessToken(token), nil
+}
+
+func (r *GormPersonalAccessTokensRepository) FindActiveByUserID(userID uint) ([]entity.PersonalAccessToken, error) {
+	var tokens []PersonalAccessToken
+
+	result := r.active().Where("user_id = ?", userID).Order("created_at DESC").Find(&tokens)
+	if result.Error != nil {
+		return nil, fmt.Errorf("failed to retrieve personal access tokens for user ID %d: %w", userID, result.Error)
+	}
+
+	entities := make([]entity.PersonalAccessToken, 0, len(tokens))
+	for _, token := range tokens {
+		entities = append(entities, *ToEntityPersonalAccessToken(token))
+	}
+
+	return entities, nil
+}
+
+func (r *GormPersonalAccessTokensRepository) TouchLastUsed(id uint, lastUsedAt time.Time) error {
+	result := r.DB.Model(&PersonalAccessToken{}).Where("id = ?", id).Update("last_used_at", lastUsedAt)
+	if result.Error != nil {
+		return fmt.Errorf("failed to touch personal access token: %w", result.Error)
+	}
+
+	return nil
+}
+
+func (r *GormPersonalAccessTokensRepository) Revoke(userID, id uint) error {
+	result := r.DB.Model(&PersonalAccessToken{}).
+		Where("id = ? AND user_id = ? AND revoked_at IS NULL", id, userID).
+		Update("revoked_at", time.Now())
+	if result.Error != nil {
+		return fmt.Errorf("failed to revoke personal access token: %w", result.Error)
+	}
+
+	if result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+
+	return nil
+}
+
+// 失効・期限切れでないトークン
+func (r *GormPersonalAccessTokensRepository) active() *gorm.DB {
+	return r.DB.Where("revoked_at IS NULL AND (expires_at IS NULL OR expires_at > ?)", time.Now())
+}
@@ -0,0 +1,137 @@
+package postgres
+
+import (
+	"errors"
+	"fmt"
+	"proto-pulse-plat/domain/entity"
+	"proto-pulse-plat/infrastructure/model"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type GormPostImageVariantsRepository struct {
+	DB *gorm.DB
+}
+
+type PostImageVariant struct {
+	ID          uint   `gorm:"primaryKey"`
+	PostImageID uint   `gorm:"not null"`
+	Name        string `gorm:"size:16;not null"`
+	StorageKey  string `gorm:"size:255;not null"`
+	MimeType    string `gorm:"size:64;not null"`
+	Width       int    `gorm:"not null"`
+	Height      int    `gorm:"not null"`
+	Size        int64  `gorm:"not null"`
+	Checksum    string `gorm:"size:64;not null"`
+	CreatedAt   time.Time
+	UpdatedAt   time.Time
+}
+
+func ToEntityPostImageVariant(variant PostImageVariant) *entity.PostImageVariant {
+	return &entity.PostImageVariant{
+		ID:          variant.ID,
+		PostImageID: variant.PostImageID,
+		Name:        variant.Name,
+		StorageKey:  variant.StorageKey,
+		MimeType:    variant.MimeType,
+		Width:       variant.Width,
+		Height:      variant.Height,
+		Size:        variant.Size,
+		Checksum:    variant.Checksum,
+		CreatedAt:   variant.CreatedAt,
+		UpdatedAt:   variant.UpdatedAt,
+	}
+}
+
+func NewGormPostImageVariantsRepository(db *gorm.DB) *GormPostImageVariantsRepository {
+	return &GormPostImageVariantsRepository{
+		DB: db,
+	}
+}
+
+func (r *GormPostImageVariantsRepository) FindByPostImageIDs(postImageIDs []uint) ([]entity.PostImageVariant, error) {
+	if len(postImageIDs) == 0 {
+		return nil, nil
+	}
+
+	var variants []PostImageVariant
+
+	result := r.DB.Where("post_image_id IN ?", postImageIDs).Order("post_image_id ASC, width ASC").Find(&variants)
+	if result.Error != nil {
+		return nil, fmt.Errorf("failed to retrieve postImageVariants: %w", result.Error)
+	}
+
+	entities := make([]entity.PostImageVariant, 0, len(variants))
+	for _, variant := range variants {
+		entities = append(entities, *ToEntityPostImageVariant(variant))
+	}
+	return entities, nil
+}
+
+func (r *GormPostImageVariantsRepository) Find(postImageID uint, name string) (*entity.PostImageVariant, error) {
+	var variant PostImageVariant
+
+	result := r.DB.Where("post_image_id = ? AND name = ?", postImageID, name).First(&variant)
+	if result.Error != nil {
+		if errors.Is(result.Error, gorm.ErrRecordNotFound) {
+			return nil, fmt.Errorf("%s variant of postImage %d: %w", name, postImageID, gorm.ErrRecordNotFound)
+		}
+		return nil, fmt.Errorf("failed to retrieve %s variant of postImage %d: %w", name, postImageID, result.Error)
+	}
+
+	return ToEntityPostImageVariant(variant), nil
+}
+
+// 同じ画像の同じ名前の派生画像は置き換える
+func (r *GormPostImageVariantsRepository) Save(variant model.PostImageVariant) error {
+	newVariant := PostImageVariant{
+		PostImageID: variant.PostImageID,
+		Name:        variant.Name,
+		StorageKey:  variant.StorageKey,
+		MimeType:    variant.MimeType,
+		Width:       variant.Width,
+		Height:      variant.Height,
+		Size:        variant.Size,
+		Checksum:    variant.Checksum,
+	}
+
+	result := r.DB.Clauses(clause.OnConflict{
+		Columns: []clause.Column{{Name: "post_image_id"}, {Name: "name"}},
+		DoUpdates: clause.AssignmentColumns(
+			[]string{"storage_key", "mime_type", "width", "height", "size", "checksum", "updated_at"},
+		),
+	}).Create(&newVariant)
+	if result.Error != nil {
+		return fmt.Errorf("failed to save %s variant of postImage %d: %w", variant.Name, variant.PostImageID, result.Error)
+	}
+
+	return nil
+}
+
+// names に含まれない派生画像を削除する。設定の変更で生成しなくなった大きさを消すために使う
+func (r *GormPostImageVariantsRepository) DeleteExcept(postImageID uint, names []string) error {
+	query := r.DB.Where("post_image_id = ?", postImageID)
+	if len(names) > 0 {
+		query = query.Where("name NOT IN ?", names)
+	}
+
+	result := query.Delete(&PostImageVariant{})
+	if result.Error != nil {
+		return fmt.Errorf("failed to delete variants of postImage %d: %w", postImageID, result.Error)
+	}
+
+	return nil
+}
+
+func (r *GormPostImageVariantsRepository) CountByStorageKey(storageKey string) (int64, error) {
+	var count int64
+
+	result := r.DB.Model(&PostImageVariant{}).Where("storage_key = ?", storageKey).Count(&count)
+	if result.Error != nil {
+		return 0, fmt.Errorf("failed to count postImageVariants by storage key: %w", result.Error)
+	}
+
+	return count, nil
+}
@@ -15,13 +15,24 @@ type GormPostImagesRepository struct {
 }
 
 type PostImage struct {
-	ID        uint   `gorm:"primaryKey"`
-	FileName  string `gorm:"size:255"`
-	FilePath  string `gorm:"size:255"`
-	PostID    uint   `gorm:"not null"`
-	Post      Post   `gorm:"foreignKey:UserID"`
-	CreatedAt time.Time
-	UpdatedAt time.Time
+	ID            uint   `gorm:"primaryKey"`
+	FileName      string `gorm:"size:255"`
+	FilePath      string `gorm:"size:255"`
+	PostID        uint   `gorm:"not null"`
+	StorageKey    string `gorm:"size:255"`
+	Size          int64  `gorm:"not null;default:0"`
+	Checksum      string `gorm:"size:64;not null;default:''"`
+	MimeType      string `gorm:"size:64;not null;default:''"`
+	Width         int    `gorm:"not null;default:0"`
+	Height        int    `gorm:"not null;default:0"`
+	BlurHash      string `gorm:"size:64;not null;default:''"`
+	DominantColor string `gorm:"size:7;not null;default:''"`
+	AltText       string `gorm:"size:255;not null;default:''"`
+	Caption       string `gorm:"size:1000;not null;default:''"`
+	IsCover       bool   `gorm:"not null;default:false"`
+	Post          Post   `gorm:"foreignKey:UserID"`
+	CreatedAt     time.Time
+	UpdatedAt     time.Time
 }
 
 func NewGormPostImagesRepository(db *gorm.DB) *GormPostImagesRepository {
@@ -30,10 +41,24 @@ func NewGormPostImagesRepository(db *gorm.DB) *GormPostImagesRepository {
 	}
 }
 
+func (r *GormPostImagesRepository) FindByID(imageID uint) (*entity.PostImage, error) {
+	var postImage entity.PostImage
+
+	result := r.DB.First(&postImage, imageID)
+	if result.Error != nil {
+		if errors.Is(result.Error, gorm.ErrRecordNotFound) {
+			return nil, fmt.Errorf("postImage not found with id %d: %w", imageID, gorm.ErrRecordNotFound)
+		}
+		return nil, fmt.Errorf("failed to retrieve postImage %d: %w", imageID, result.Error)
+	}
+
+	return &postImage, nil
+}
+
 func (r *GormPostImagesRepository) FindByPostID(postID uint) ([]entity.PostImage, error) {
 	var postImages []entity.PostImage
 
-	result := r.DB.Where("post_id = ?", postID).Find(&postImages)
+	result := r.DB.Where("post_id = ?", postID).Order("sort_order ASC, id ASC").Find(&postImages)
 	if result.Error != nil {
 		if errors.Is(result.Error, gorm.ErrRecordNotFound) {
 			return nil, fmt.Errorf("no images found for post with id: %d", postID)
@@ -46,11 +71,163 @@ func (r *GormPostImagesRepository) FindByPostID(postID uint) ([]entity.PostImage
 	return postImages, nil
 }
 
-func (r *GormPostImagesRepository) Save(postImage model.PostImage) error {
+// 一括処理用。lastID より後の画像を ID 順に返す
+func (r *GormPostImagesRepository) FindAfterID(lastID uint, limit int) ([]entity.PostImage, error) {
+	var postImages []entity.PostImage
+
+	result := r.DB.Where("id > ?", lastID).Order("id ASC").Limit(limit).Find(&postImages)
+	if result.Error != nil {
+		return nil, fmt.Errorf("failed to retrieve postImages after id %d: %w", lastID, result.Error)
+	}
+
+	return postImages, nil
+}
+
+func (r *GormPostImagesRepository) Save(postImage model.PostImage) (*entity.PostImage, error) {
 	result := r.DB.Create(&postImage)
 	if result.Error != nil {
-		return fmt.Errorf("failed to save postImage: %w", result.Error)
+		return nil, fmt.Errorf("failed to save postImage: %w", result.Error)
+	}
+
+	return &entity.PostImage{
+		ID:            postImage.ID,
+		FileName:      postImage.FileName,
+		PostID:        postImage.PostID,
+		StorageKey:    postImage.StorageKey,
+		Size:          postImage.Size,
+		Checksum:      postImage.Checksum,
+		MimeType:      postImage.MimeType,
+		Width:         postImage.Width,
+		Height:        postImage.Height,
+		BlurHash:      postImage.BlurHash,
+		DominantColor: postImage.DominantColor,
+		SortOrder:     postImage.SortOrder,
+		AltText:       postImage.AltText,
+		Caption:       postImage.Caption,
+		IsCover:       postImage.IsCover,
+		GPSLatitude:   postImage.GPSLatitude,
+		GPSLongitude:  postImage.GPSLongitude,
+		CreatedAt:     postImage.CreatedAt,
+		UpdatedAt:     postImage.UpdatedAt,
+	}, nil
+}
+
+// 画像の内容から計算する値 (大きさとプレースホルダー) を更新する
+func (r *GormPostImagesRepository) UpdateDerivedAttributes(
+	imageID uint,
+	width, height int,
+	blurHash, dominantColor string,
+) error {
+	result := r.DB.Model(&entity.PostImage{}).Where("id = ?", imageID).Updates(map[string]any{
+		"width":          width,
+		"height":         height,
+		"blur_hash":      blurHash,
+		"dominant_color": dominantColor,
+	})
+	if result.Error != nil {
+		return fmt.Errorf("failed to update derived attributes of postImage %d: %w", imageID, result.Error)
 	}
 
 	return nil
 }
+
+func (r *GormPostImagesRepository) DeleteByIDs(postID uint, imageIDs []uint) error {
+	if len(imageIDs) == 0 {
+		return nil
+	}
+
+	result := r.DB.Where("post_id = ? AND id IN ?", postID, imageIDs).Delete(&entity.PostImage{})
+	if result.Error != nil {
+		return fmt.Errorf("failed to delete postImages: %w", result.Error)
+	}
+
+	if result.RowsAffected != int64(len(imageIDs)) {
+		return fmt.Errorf("some images do not belong to post with id: %d", postID)
+	}
+
+	return nil
+}
+
+// 同じ内容の画像が他に残っているか確認するために使う
+func (r *GormPostImagesRepository) CountByStorageKey(storageKey string) (int64, error) {
+	var count int64
+
+	result := r.DB.Model(&entity.PostImage{}).Where("storage_key = ?", storageKey).Count(&count)
+	if result.Error != nil {
+		return 0, fmt.Errorf("failed to count postImages by storage key: %w", result.Error)
+	}
+
+	return count, nil
+}
+
+func (r *GormPostImagesRepository) UpdateSortOrders(postID uint, imageIDs []uint) error {
+	return r.DB.Transaction(func(tx *gorm.DB) error {
+		for i, imageID := range imageIDs {
+			result := tx.Model(&entity.PostImage{}).
+				Where("post_id = ? AND id = ?", postID, imageID).
+				Update("sort_order", i)
+			if result.Error != nil {
+				return fmt.Errorf("failed to update sort order of postImage %d: %w", imageID, result.Error)
+			}
+			if result.RowsAffected == 0 {
+				return fmt.Errorf("no image found with id %d for post %d", imageID, postID)
+			}
+		}
+		return nil
+	})
+}
+
+// nil の項目は更新しない
+func (r *GormPostImagesRepository) UpdateDescription(postID, imageID uint, altText, caption *string) error {
+	updates := descriptionUpdates(altText, caption)
+	if len(updates) == 0 {
+		return nil
+	}
+
+	result := r.DB.Model(&entity.PostImage{}).
+		Where("post_id = ? AND id = ?", postID, imageID).
+		Updates(updates)
+	if result.Error != nil {
+		return fmt.Errorf("failed to update description of postImage %d: %w", imageID, result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return fmt.Errorf("no image found with id %d for post %d", imageID, postID)
+	}
+
+	return nil
+}
+
+// 空の値も保存するため map で更新する。送られた列のみを含める
+func descriptionUpdates(altText, caption *string) map[string]any {
+	updates := map[string]any{}
+	if altText != nil {
+		updates["alt_text"] = *altText
+	}
+	if caption != nil {
+		updates["caption"] = *caption
+	}
+	return updates
+}
+
+// 指定した画像を投稿のカバー画像にし、他の画像の指定を外す
+func (r *GormPostImagesRepository) SetCover(postID, imageID uint) error {
+	return r.DB.Transaction(func(tx *gorm.DB) error {
+		result := tx.Model(&entity.PostImage{}).
+			Where("post_id = ? AND is_cover AND id <> ?", postID, imageID).
+			Update("is_cover", false)
+		if result.Error != nil {
+			return fmt.Errorf("failed to clear cover of post %d: %w", postID, result.Error)
+		}
+
+		result = tx.Model(&entity.PostImage{}).
+			Where("post_id = ? AND id = ?", postID, imageID).
+			Update("is_cover", true)
+		if result.Error != nil {
+			return fmt.Errorf("failed to set cover of post %d: %w", postID, result.Error)
+		}
+		if result.RowsAffected == 0 {
+			return fmt.Errorf("no image found with id %d for post %d", imageID, postID)
+		}
+		return nil
+	})
+}
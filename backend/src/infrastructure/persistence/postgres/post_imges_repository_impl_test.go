@@ -0,0 +1,29 @@
+package postgres
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestDescriptionUpdates(t *testing.T) {
+	altText, caption, empty := "a cat", "on the sofa", ""
+	tests := []struct {
+		name    string
+		altText *string
+		caption *string
+		want    map[string]any
+	}{
+		{name: "nothing sent", want: map[string]any{}},
+		{name: "alt text only", altText: &altText, want: map[string]any{"alt_text": "a cat"}},
+		{name: "caption only", caption: &caption, want: map[string]any{"caption": "on the sofa"}},
+		{name: "both", altText: &altText, caption: &caption, want: map[string]any{"alt_text": "a cat", "caption": "on the sofa"}},
+		{name: "cleared alt text", altText: &empty, want: map[string]any{"alt_text": ""}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := descriptionUpdates(tt.altText, tt.caption); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("descriptionUpdates() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
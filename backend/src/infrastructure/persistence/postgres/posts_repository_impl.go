@@ -57,20 +57,21 @@ func (r *GormPostsRepository) Delete(postID int) error {
 }
 
 func (r *GormPostsRepository) FindAllWithPagination(
-    limit int, 
-    offset int, 
-    title, contentTitle, location string,
+	limit int,
+	offset int,
+	title, contentTitle, location string,
 ) ([]entity.Post, int64, error) {
 	var posts []entity.Post
 	var count int64
 
-	query := r.DB.Model(&entity.Post{})
+	// 利用停止中のユーザーの投稿は表示しない
+	query := r.DB.Model(&entity.Post{}).Where(notSuspendedCondition("posts.user_id"))
 
 	if title != "" {
 		query = query.Where("title ILIKE ?", "%"+title+"%")
-	}else if contentTitle != "" {
+	} else if contentTitle != "" {
 		query = query.Where("content_title ILIKE ?", "%"+contentTitle+"%")
-	}else if location != "" {
+	} else if location != "" {
 		query = query.Where("location ILIKE ?", "%"+location+"%")
 	}
 
@@ -84,7 +85,6 @@ func (r *GormPostsRepository) FindAllWithPagination(
 	return posts, count, nil
 }
 
-
 func (r *GormPostsRepository) Save(post model.Post) (*entity.Post, error) {
 	newPost := Post{
 		Title:        post.Title,
@@ -102,13 +102,23 @@ func (r *GormPostsRepository) Save(post model.Post) (*entity.Post, error) {
 	return ToEntityPost(newPost), nil
 }
 
+// 利用停止中のユーザーの投稿は見つからないものとして扱う
 func (r *GormPostsRepository) FindByID(postID int) (*entity.Post, error) {
+	return r.findByID(r.DB.Where(notSuspendedCondition("posts.user_id")), postID)
+}
+
+// モデレーション用。利用停止中のユーザーの投稿も返す
+func (r *GormPostsRepository) FindByIDIncludingSuspended(postID int) (*entity.Post, error) {
+	return r.findByID(r.DB, postID)
+}
+
+func (r *GormPostsRepository) findByID(db *gorm.DB, postID int) (*entity.Post, error) {
 	var post Post
 
-	result := r.DB.First(&post, postID)
+	result := db.First(&post, postID)
 	if result.Error != nil {
 		if result.Error == gorm.ErrRecordNotFound {
-			return nil, fmt.Errorf("post not found with id: %d", postID)
+			return nil, fmt.Errorf("post not found with id %d: %w", postID, gorm.ErrRecordNotFound)
 		}
 		return nil, fmt.Errorf("failed to retrieve post by ID: %w", result.Error)
 	}
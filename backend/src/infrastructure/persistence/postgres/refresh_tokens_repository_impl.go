@@ -0,0 +1,86 @@
+package postgres
+
+import (
+	"errors"
+	"fmt"
+	"proto-pulse-plat/domain/entity"
+	"proto-pulse-plat/infrastructure/model"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type GormRefreshTokensRepository struct {
+	DB *gorm.DB
+}
+
+type RefreshToken struct {
+	ID        uint   `gorm:"primaryKey"`
+	SessionID string `gorm:"size:64;not null;index"`
+	UserID    uint   `gorm:"not null"`
+	TokenHash string `gorm:"size:64;not null;unique"`
+	ExpiresAt time.Time
+	UsedAt    *time.Time
+	CreatedAt time.Time
+	UpdatedAt time.Time
+}
+
+func ToEntityRefreshToken(refreshToken RefreshToken) *entity.RefreshToken {
+	return &entity.RefreshToken{
+		ID:        refreshToken.ID,
+		SessionID: refreshToken.SessionID,
+		UserID:    refreshToken.UserID,
+		TokenHash: refreshToken.TokenHash,
+		ExpiresAt: refreshToken.ExpiresAt,
+		UsedAt:    refreshToken.UsedAt,
+		CreatedAt: refreshToken.CreatedAt,
+		UpdatedAt: refreshToken.UpdatedAt,
+	}
+}
+
+func NewGormRefreshTokensRepository(db *gorm.DB) *GormRefreshTokensRepository {
+	return &GormRefreshTokensRepository{
+		DB: db,
+	}
+}
+
+func (r *GormRefreshTokensRepository) Save(refreshToken model.RefreshToken) (*entity.RefreshToken, error) {
+	newRefreshToken := RefreshToken{
+		SessionID: refreshToken.SessionID,
+		UserID:    refreshToken.UserID,
+		TokenHash: refreshToken.TokenHash,
+		ExpiresAt: refreshToken.ExpiresAt,
+	}
+
+	result := r.DB.Create(&newRefreshToken)
+	if result.Error != nil {
+		return nil, fmt.Errorf("failed to save refresh token: %w", result.Error)
+	}
+
+	return ToEntityRefreshToken(newRefreshToken), nil
+}
+
+func (r *GormRefreshTokensRepository) FindByHash(tokenHash string) (*entity.RefreshToken, error) {
+	var refreshToken RefreshToken
+
+	result := r.DB.Where("token_hash = ?", tokenHash).First(&refreshToken)
+	if result.Error != nil {
+		if errors.Is(result.Error, gorm.ErrRecordNotFound) {
+			return nil, gorm.ErrRecordNotFound
+		}
+		return nil, fmt.Errorf("failed to retrieve refresh token: %w", result.Error)
+	}
+
+	return ToEntityRefreshToken(refreshToken), nil
+}
+
+func (r *GormRefreshTokensRepository) MarkUsed(id uint) (bool, error) {
+	result := r.DB.Model(&RefreshToken{}).
+		Where("id = ? AND used_at IS NULL", id).
+		Update("used_at", time.Now())
+	if result.Error != nil {
+		return false, fmt.Errorf("failed to mark refresh token as used: %w", result.Error)
+	}
+
+	return result.RowsAffected == 1, nil
+}
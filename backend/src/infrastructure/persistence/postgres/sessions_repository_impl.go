@@ -0,0 +1,132 @@
+package postgres
+
+import (
+	"errors"
+	"fmt"
+	"proto-pulse-plat/domain/entity"
+	"proto-pulse-plat/infrastructure/model"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type GormSessionsRepository struct {
+	DB *gorm.DB
+}
+
+type Session struct {
+	ID         string `gorm:"primaryKey;size:64"`
+	UserID     uint   `gorm:"not null;index"`
+	UserAgent  string `gorm:"size:512"`
+	LastSeenAt time.Time
+	ExpiresAt  time.Time
+	RevokedAt  *time.Time
+	CreatedAt  time.Time
+	UpdatedAt  time.Time
+}
+
+func ToEntitySession(session Session) *entity.Session {
+	return &entity.Session{
+		ID:         session.ID,
+		UserID:     session.UserID,
+		UserAgent:  session.UserAgent,
+		LastSeenAt: session.LastSeenAt,
+		ExpiresAt:  session.ExpiresAt,
+		RevokedAt:  session.RevokedAt,
+		CreatedAt:  session.CreatedAt,
+		UpdatedAt:  session.UpdatedAt,
+	}
+}
+
+func NewGormSessionsRepository(db *gorm.DB) *GormSessionsRepository {
+	return &GormSessionsRepository{
+		DB: db,
+	}
+}
+
+func (r *GormSessionsRepository) Save(session model.Session) (*entity.Session, error) {
+	newSession := Session{
+		ID:         session.ID,
+		UserID:     session.UserID,
+		UserAgent:  session.UserAgent,
+		LastSeenAt: session.LastSeenAt,
+		ExpiresAt:  session.ExpiresAt,
+	}
+
+	result := r.DB.Create(&newSession)
+	if result.Error != nil {
+		return nil, fmt.Errorf("failed to save session: %w", result.Error)
+	}
+
+	return ToEntitySession(newSession), nil
+}
+
+func (r *GormSessionsRepository) FindActiveByID(id string) (*entity.Session, error) {
+	var session Session
+
+	result := r.active().Where("id = ?", id).First(&session)
+	if result.Error != nil {
+		if errors.Is(result.Error, gorm.ErrRecordNotFound) {
+			return nil, gorm.ErrRecordNotFound
+		}
+		return nil, fmt.Errorf("failed to retrieve session by ID: %w", result.Error)
+	}
+
+	return ToEntitySession(session), nil
+}
+
+func (r *GormSessionsRepository) FindActiveByUserID(userID uint) ([]entity.Session, error) {
+	var sessions []Session
+
+	result := r.active().Where("user_id = ?", userID).Order("last_seen_at DESC").Find(&sessions)
+	if result.Error != nil {
+		return nil, fmt.Errorf("failed to retrieve sessions for user ID %d: %w", userID, result.Error)
+	}
+
+	entities := make([]entity.Session, 0, len(sessions))
+	for _, session := range sessions {
+		entities = append(entities, *ToEntitySession(session))
+	}
+
+	return entities, nil
+}
+
+func (r *GormSessionsRepository) Touch(id string, lastSeenAt time.Time) error {
+	result := r.DB.Model(&Session{}).Where("id = ?", id).Update("last_seen_at", lastSeenAt)
+	if result.Error != nil {
+		return fmt.Errorf("failed to touch session: %w", result.Error)
+	}
+
+	return nil
+}
+
+func (r *GormSessionsRepository) Revoke(userID uint, id string) error {
+	result := r.DB.Model(&Session{}).
+		Where("id = ? AND user_id = ? AND revoked_at IS NULL", id, userID).
+		Update("revoked_at", time.Now())
+	if result.Error != nil {
+		return fmt.Errorf("failed to revoke session: %w", result.Error)
+	}
+
+	if result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+
+	return nil
+}
+
+func (r *GormSessionsRepository) RevokeAllByUserID(userID uint) error {
+	result := r.DB.Model(&Session{}).
+		Where("user_id = ? AND revoked_at IS NULL", userID).
+		Update("revoked_at", time.Now())
+	if result.Error != nil {
+		return fmt.Errorf("failed to revoke sessions: %w", result.Error)
+	}
+
+	return nil
+}
+
+// 失効・期限切れでないセッション
+func (r *GormSessionsRepository) active() *gorm.DB {
+	return r.DB.Where("revoked_at IS NULL AND expires_at > ?", time.Now())
+}
@@ -0,0 +1,28 @@
+package postgres
+
+import (
+	"proto-pulse-plat/domain/repository"
+
+	"gorm.io/gorm"
+)
+
+type GormTransactor struct {
+	DB *gorm.DB
+}
+
+func NewGormTransactor(db *gorm.DB) *GormTransactor {
+	return &GormTransactor{
+		DB: db,
+	}
+}
+
+// リポジトリ内のトランザクションはセーブポイントとして入れ子になる
+func (t *GormTransactor) Transaction(fn func(repos repository.TxRepositories) error) error {
+	return t.DB.Transaction(func(tx *gorm.DB) error {
+		return fn(repository.TxRepositories{
+			Posts:             NewGormPostsRepository(tx),
+			PostImages:        NewGormPostImagesRepository(tx),
+			PostImageVariants: NewGormPostImageVariantsRepository(tx),
+		})
+	})
+}
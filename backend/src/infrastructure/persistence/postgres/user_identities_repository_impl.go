@@ -0,0 +1,122 @@
+package postgres
+
+import (
+	"errors"
+	"fmt"
+	"proto-pulse-plat/domain/entity"
+	"proto-pulse-plat/infrastructure/model"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type GormUserIdentitiesRepository struct {
+	DB *gorm.DB
+}
+
+type UserIdentity struct {
+	ID         uint   `gorm:"primaryKey"`
+	UserID     uint   `gorm:"not null;index"`
+	Provider   string `gorm:"size:32;uniqueIndex:idx_user_identities_provider_external_id"`
+	ExternalID string `gorm:"size:255;uniqueIndex:idx_user_identities_provider_external_id"`
+	Handle     string `gorm:"size:255"`
+	CreatedAt  time.Time
+	UpdatedAt  time.Time
+}
+
+func ToEntityUserIdentity(identity UserIdentity) *entity.UserIdentity {
+	return &entity.UserIdentity{
+		ID:         identity.ID,
+		UserID:     identity.UserID,
+		Provider:   identity.Provider,
+		ExternalID: identity.ExternalID,
+		Handle:     identity.Handle,
+		CreatedAt:  identity.CreatedAt,
+		UpdatedAt:  identity.UpdatedAt,
+	}
+}
+
+func NewGormUserIdentitiesRepository(db *gorm.DB) *GormUserIdentitiesRepository {
+	return &GormUserIdentitiesRepository{
+		DB: db,
+	}
+}
+
+func (r *GormUserIdentitiesRepository) Save(identity model.UserIdentity) (*entity.UserIdentity, error) {
+	newIdentity := UserIdentity{
+		UserID:     identity.UserID,
+		Provider:   identity.Provider,
+		ExternalID: identity.ExternalID,
+		Handle:     identity.Handle,
+	}
+
+	result := r.DB.Create(&newIdentity)
+	if result.Error != nil {
+		return nil, fmt.Errorf("failed to save user identity: %w", result.Error)
+	}
+
+	return ToEntityUserIdentity(newIdentity), nil
+}
+
+func (r *GormUserIdentitiesRepository) FindByExternalID(provider, externalID string) (*entity.UserIdentity, error) {
+	var identity UserIdentity
+
+	result := r.DB.Where("provider = ? AND external_id = ?", provider, externalID).First(&identity)
+	if result.Error != nil {
+		if errors.Is(result.Error, gorm.ErrRecordNotFound) {
+			return nil, gorm.ErrRecordNotFound
+		}
+		return nil, fmt.Errorf("failed to retrieve user identity by externalID: %w", result.Error)
+	}
+
+	return ToEntityUserIdentity(identity), nil
+}
+
+func (r *GormUserIdentitiesRepository) FindByUserID(userID uint) ([]entity.UserIdentity, error) {
+	var identities []UserIdentity
+
+	result := r.DB.Where("user_id = ?", userID).Order("id").Find(&identities)
+	if result.Error != nil {
+		return nil, fmt.Errorf("failed to retrieve user identities: %w", result.Error)
+	}
+
+	entityIdentities := make([]entity.UserIdentity, 0, len(identities))
+	for _, identity := range identities {
+		entityIdentities = append(entityIdentities, *ToEntityUserIdentity(identity))
+	}
+
+	return entityIdentities, nil
+}
+
+func (r *GormUserIdentitiesRepository) UpdateHandle(id uint, handle string) error {
+	result := r.DB.Model(&UserIdentity{}).Where("id = ?", id).Update("handle", handle)
+	if result.Error != nil {
+		return fmt.Errorf("failed to update user identity handle: %w", result.Error)
+	}
+
+	return nil
+}
+
+// ユーザーに他のログイン方法が残る場合のみ削除する。削除できなかった場合は gorm.ErrRecordNotFound を返す
+func (r *GormUserIdentitiesRepository) Delete(userID, id uint) error {
+	return r.DB.Transaction(func(tx *gorm.DB) error {
+		// 同じユーザーの解除が同時に実行されても最後の1つが残るよう、ユーザー行をロックする
+		if err := tx.Exec("SELECT id FROM users WHERE id = ? FOR UPDATE", userID).Error; err != nil {
+			return fmt.Errorf("failed to lock user: %w", err)
+		}
+
+		result := tx.
+			Where("id = ? AND user_id = ?", id, userID).
+			Where("(SELECT COUNT(*) FROM user_identities WHERE user_id = ?) > 1", userID).
+			Delete(&UserIdentity{})
+		if result.Error != nil {
+			return fmt.Errorf("failed to delete user identity: %w", result.Error)
+		}
+
+		if result.RowsAffected == 0 {
+			return gorm.ErrRecordNotFound
+		}
+
+		return nil
+	})
+}
@@ -0,0 +1,110 @@
+package postgres
+
+import (
+	"errors"
+	"fmt"
+	"proto-pulse-plat/domain/entity"
+	"proto-pulse-plat/infrastructure/model"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// userColumn のユーザーに有効な利用停止が無いことを表す条件
+func notSuspendedCondition(userColumn string) string {
+	return "NOT EXISTS (SELECT 1 FROM user_suspensions WHERE user_suspensions.user_id = " + userColumn +
+		" AND user_suspensions.lifted_at IS NULL" +
+		" AND (user_suspensions.expires_at IS NULL OR user_suspensions.expires_at > NOW()))"
+}
+
+type GormUserSuspensionsRepository struct {
+	DB *gorm.DB
+}
+
+type UserSuspension struct {
+	ID        uint   `gorm:"primaryKey"`
+	UserID    uint   `gorm:"not null;index"`
+	Reason    string `gorm:"type:text;not null"`
+	CreatedBy *uint
+	ExpiresAt *time.Time
+	LiftedAt  *time.Time
+	LiftedBy  *uint
+	CreatedAt time.Time
+	UpdatedAt time.Time
+}
+
+func ToEntityUserSuspension(suspension UserSuspension) *entity.UserSuspension {
+	return &entity.UserSuspension{
+		ID:        suspension.ID,
+		UserID:    suspension.UserID,
+		Reason:    suspension.Reason,
+		CreatedBy: suspension.CreatedBy,
+		ExpiresAt: suspension.ExpiresAt,
+		LiftedAt:  suspension.LiftedAt,
+		LiftedBy:  suspension.LiftedBy,
+		CreatedAt: suspension.CreatedAt,
+		UpdatedAt: suspension.UpdatedAt,
+	}
+}
+
+func NewGormUserSuspensionsRepository(db *gorm.DB) *GormUserSuspensionsRepository {
+	return &GormUserSuspensionsRepository{
+		DB: db,
+	}
+}
+
+func (r *GormUserSuspensionsRepository) Save(suspension model.UserSuspension) (*entity.UserSuspension, error) {
+	createdBy := suspension.CreatedBy
+	newSuspension := UserSuspension{
+		UserID:    suspension.UserID,
+		Reason:    suspension.Reason,
+		CreatedBy: &createdBy,
+		ExpiresAt: suspension.ExpiresAt,
+	}
+
+	result := r.DB.Create(&newSuspension)
+	if result.Error != nil {
+		return nil, fmt.Errorf("failed to save user suspension: %w", result.Error)
+	}
+
+	return ToEntityUserSuspension(newSuspension), nil
+}
+
+// 有効な利用停止のうち最も遅く終わるものを返す。無い場合は gorm.ErrRecordNotFound を返す
+func (r *GormUserSuspensionsRepository) FindActiveByUserID(userID uint) (*entity.UserSuspension, error) {
+	var suspension UserSuspension
+
+	result := r.active().
+		Where("user_id = ?", userID).
+		Order("expires_at DESC NULLS FIRST").
+		First(&suspension)
+	if result.Error != nil {
+		if errors.Is(result.Error, gorm.ErrRecordNotFound) {
+			return nil, gorm.ErrRecordNotFound
+		}
+		return nil, fmt.Errorf("failed to retrieve user suspension: %w", result.Error)
+	}
+
+	return ToEntityUserSuspension(suspension), nil
+}
+
+// 有効な利用停止をすべて解除する。解除するものが無い場合は gorm.ErrRecordNotFound を返す
+func (r *GormUserSuspensionsRepository) LiftActiveByUserID(userID, liftedBy uint) error {
+	result := r.active().Model(&UserSuspension{}).
+		Where("user_id = ?", userID).
+		Updates(map[string]any{"lifted_at": time.Now(), "lifted_by": liftedBy})
+	if result.Error != nil {
+		return fmt.Errorf("failed to lift user suspension: %w", result.Error)
+	}
+
+	if result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+
+	return nil
+}
+
+// 解除されておらず期限内の利用停止
+func (r *GormUserSuspensionsRepository) active() *gorm.DB {
+	return r.DB.Where("lifted_at IS NULL AND (expires_at IS NULL OR expires_at > ?)", time.Now())
+}
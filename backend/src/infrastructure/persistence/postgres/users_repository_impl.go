@@ -15,22 +15,28 @@ type GormUsersRepository struct {
 }
 
 type User struct {
-	ID           uint   `gorm:"primaryKey"`
-	UserName     string `gorm:"size:50"`
-	AccountID    string `gorm:"size:50;unique"`
-	IconFileName string `gorm:"size:255"`
-	IconData     []byte
-	CreatedAt    time.Time
-	UpdatedAt    time.Time
+	ID             uint   `gorm:"primaryKey"`
+	UserName       string `gorm:"size:50"`
+	AccountID      string `gorm:"size:50;index"`
+	IconFileName   string `gorm:"size:255"`
+	IconStorageKey string `gorm:"size:255"`
+	IconMimeType   string `gorm:"size:64"`
+	IconHash       string `gorm:"size:64"`
+	Role           string `gorm:"size:16;not null;default:user"`
+	CreatedAt      time.Time
+	UpdatedAt      time.Time
 }
 
 func ToEntityUser(user User) *entity.User {
 	return &entity.User{
-		ID:           user.ID,
-		UserName:     user.UserName,
-		AccountID:    user.AccountID,
-		IconFileName: user.IconFileName,
-		IconData:     user.IconData,
+		ID:             user.ID,
+		UserName:       user.UserName,
+		AccountID:      user.AccountID,
+		IconFileName:   user.IconFileName,
+		IconStorageKey: user.IconStorageKey,
+		IconMimeType:   user.IconMimeType,
+		IconHash:       user.IconHash,
+		Role:           user.Role,
 	}
 }
 
@@ -46,7 +52,7 @@ func (r *GormUsersRepository) Find(id uint) (*entity.User, error) {
 	result := r.DB.First(&user, id)
 	if result.Error != nil {
 		if result.Error == gorm.ErrRecordNotFound {
-			return nil, fmt.Errorf("user not found with id: %d", id)
+			return nil, fmt.Errorf("user not found with id: %d: %w", id, gorm.ErrRecordNotFound)
 		}
 		return nil, fmt.Errorf("failed to retrieve user by ID: %w", result.Error)
 	}
@@ -54,32 +60,115 @@ func (r *GormUsersRepository) Find(id uint) (*entity.User, error) {
 	return ToEntityUser(user), nil
 }
 
-func (r *GormUsersRepository) Save(user model.User) (*entity.User, error) {
-	newUser := User{
-		UserName:     user.UserName,
-		AccountID:    user.AccountID,
-		IconFileName: user.IconFileName,
-		IconData:     user.IconData,
-	}
+// 投稿と同じく、利用停止中のユーザーは gorm.ErrRecordNotFound を返す
+func (r *GormUsersRepository) FindNotSuspended(id uint) (*entity.User, error) {
+	var user User
 
-	result := r.DB.Create(&newUser)
+	result := r.DB.Where(notSuspendedCondition("users.id")).First(&user, id)
 	if result.Error != nil {
-		return nil, fmt.Errorf("failed to save user: %w", result.Error)
+		if errors.Is(result.Error, gorm.ErrRecordNotFound) {
+			return nil, fmt.Errorf("user not found with id: %d: %w", id, gorm.ErrRecordNotFound)
+		}
+		return nil, fmt.Errorf("failed to retrieve user by ID: %w", result.Error)
 	}
 
-	return ToEntityUser(newUser), nil
+	return ToEntityUser(user), nil
 }
 
-func (r *GormUsersRepository) FindByUserName(userName string) (*entity.User, error) {
+// 認証ミドルウェアがリクエストごとに使うため、アイコン画像などは読み込まない
+func (r *GormUsersRepository) FindForAuthentication(id uint) (*entity.User, error) {
 	var user User
 
-	result := r.DB.Where("user_name = ?", userName).First(&user)
+	result := r.DB.Select("id", "role").First(&user, id)
 	if result.Error != nil {
 		if errors.Is(result.Error, gorm.ErrRecordNotFound) {
 			return nil, gorm.ErrRecordNotFound
 		}
-		return nil, fmt.Errorf("failed to retrieve user by userName: %w", result.Error)
+		return nil, fmt.Errorf("failed to retrieve user for authentication: %w", result.Error)
 	}
 
 	return ToEntityUser(user), nil
-}
\ No newline at end of file
+}
+
+// ユーザーと最初のログイン方法を同じトランザクションで保存する
+func (r *GormUsersRepository) Save(user model.User, identity model.UserIdentity) (*entity.User, error) {
+	newUser := User{
+		UserName:       user.UserName,
+		AccountID:      user.AccountID,
+		IconFileName:   user.IconFileName,
+		IconStorageKey: user.IconStorageKey,
+		IconMimeType:   user.IconMimeType,
+		IconHash:       user.IconHash,
+	}
+
+	err := r.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&newUser).Error; err != nil {
+			return fmt.Errorf("failed to save user: %w", err)
+		}
+
+		newIdentity := UserIdentity{
+			UserID:     newUser.ID,
+			Provider:   identity.Provider,
+			ExternalID: identity.ExternalID,
+			Handle:     identity.Handle,
+		}
+		if err := tx.Create(&newIdentity).Error; err != nil {
+			return fmt.Errorf("failed to save user identity: %w", err)
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return ToEntityUser(newUser), nil
+}
+
+// ゼロ値のフィールドは更新しない
+func (r *GormUsersRepository) Update(user model.User) error {
+	result := r.DB.Model(&User{}).Where("id = ?", user.ID).Updates(User{
+		UserName:       user.UserName,
+		AccountID:      user.AccountID,
+		IconFileName:   user.IconFileName,
+		IconStorageKey: user.IconStorageKey,
+		IconMimeType:   user.IconMimeType,
+		IconHash:       user.IconHash,
+	})
+	if result.Error != nil {
+		return fmt.Errorf("failed to update user: %w", result.Error)
+	}
+
+	if result.RowsAffected == 0 {
+		return fmt.Errorf("no rows updated, user with id %d might not exist", user.ID)
+	}
+
+	return nil
+}
+
+func (r *GormUsersRepository) UpdateRole(id uint, role string) error {
+	result := r.DB.Model(&User{}).Where("id = ?", id).Update("role", role)
+	if result.Error != nil {
+		return fmt.Errorf("failed to update user role: %w", result.Error)
+	}
+
+	if result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+
+	return nil
+}
+
+// X のスクリーンネームは大文字・小文字を区別しないため、小文字にして比較する
+func (r *GormUsersRepository) ExistsUnlinkedByAccountID(accountID string) (bool, error) {
+	var count int64
+	result := r.DB.Model(&User{}).
+		Where("LOWER(account_id) = LOWER(?)", accountID).
+		Where("NOT EXISTS (SELECT 1 FROM user_identities WHERE user_identities.user_id = users.id)").
+		Count(&count)
+	if result.Error != nil {
+		return false, fmt.Errorf("failed to count unlinked users: %w", result.Error)
+	}
+
+	return count > 0, nil
+}
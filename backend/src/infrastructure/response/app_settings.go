@@ -0,0 +1,5 @@
+package response
+
+type AppSettings struct {
+	KeepUploadGPS bool `json:"keep_upload_gps"`
+}
@@ -0,0 +1,5 @@
+package response
+
+type CSRFToken struct {
+	Token string `json:"csrf_token"`
+}
@@ -0,0 +1,17 @@
+package response
+
+type FieldError struct {
+	Field   string `json:"field"`
+	Message string `json:"message"`
+}
+
+type ErrorBody struct {
+	Code    string       `json:"code"`
+	Message string       `json:"message"`
+	Details []FieldError `json:"details"`
+}
+
+// すべてのエラーレスポンスの形式
+type ErrorResponse struct {
+	Error ErrorBody `json:"error"`
+}
@@ -0,0 +1,12 @@
+package response
+
+type Identity struct {
+	ID        uint   `json:"id"`
+	Provider  string `json:"provider"`
+	Handle    string `json:"handle"`
+	CreatedAt string `json:"created_at"`
+}
+
+type IdentityList struct {
+	Identities []Identity `json:"identities"`
+}
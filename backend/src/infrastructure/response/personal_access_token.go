@@ -0,0 +1,21 @@
+package response
+
+type PersonalAccessToken struct {
+	ID          uint     `json:"id"`
+	Name        string   `json:"name"`
+	TokenPrefix string   `json:"token_prefix"`
+	Scopes      []string `json:"scopes"`
+	ExpiresAt   *string  `json:"expires_at"`
+	LastUsedAt  *string  `json:"last_used_at"`
+	CreatedAt   string   `json:"created_at"`
+}
+
+type PersonalAccessTokenList struct {
+	Tokens []PersonalAccessToken `json:"tokens"`
+}
+
+// 平文のトークンは発行時のレスポンスにのみ含める
+type CreatedPersonalAccessToken struct {
+	PersonalAccessToken
+	Token string `json:"token"`
+}
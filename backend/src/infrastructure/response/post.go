@@ -2,18 +2,24 @@ package response
 
 // 一覧画面用
 type Post struct {
-	ID              uint   `json:"id"`
-	Title           string `json:"title"`
-	Content         string `json:"content"`
-	ContentTitle    string `json:"content_title"`
-	Location        string `json:"location"`
-	PostImageBase64 string `json:"post_image_base64"`
-	UserName        string `json:"user_name"`
-	AccountID       string `json:"account_id"`
-	IconImageBase64 string `json:"icon_image_base64"`
-	IsOwnPost       bool   `json:"is_own_post"`
-	UserID          uint   `json:"user_id"`
-	CreatedAt string `json:"created_at"`
+	ID           uint   `json:"id"`
+	Title        string `json:"title"`
+	Content      string `json:"content"`
+	ContentTitle string `json:"content_title"`
+	Location     string `json:"location"`
+	// カバー画像 (指定が無い場合は先頭の画像) のサムネイルの URL。サムネイルが無い場合は元画像
+	PostImageURL     string        `json:"post_image_url"`
+	PostImageSrcSet  []ImageSource `json:"post_image_srcset"`
+	PostImageAltText string        `json:"post_image_alt_text"`
+	// 画像の読み込み前に表示する BlurHash と代表色 ("#rrggbb")。未計算の場合は空
+	PostImageBlurHash      string `json:"post_image_blur_hash"`
+	PostImageDominantColor string `json:"post_image_dominant_color"`
+	UserName               string `json:"user_name"`
+	AccountID              string `json:"account_id"`
+	IconImageURL           string `json:"icon_image_url"`
+	IsOwnPost              bool   `json:"is_own_post"`
+	UserID                 uint   `json:"user_id"`
+	CreatedAt              string `json:"created_at"`
 }
 
 type PostList struct {
@@ -25,8 +31,31 @@ type PostList struct {
 
 // 詳細画面用
 type PostDetail struct {
-	ID               uint     `json:"id"`
-	Title            string   `json:"title"`
-	Content          string   `json:"content"`
-	PostImagesBase64 []string `json:"post_images_base64"`
+	ID      uint   `json:"id"`
+	Title   string `json:"title"`
+	Content string `json:"content"`
+	// 中サイズがある場合は中サイズの URL
+	PostImageURLs     []string        `json:"post_image_urls"`
+	PostImageSrcSets  [][]ImageSource `json:"post_image_srcsets"`
+	PostImageIDs      []uint          `json:"post_image_ids"`
+	PostImageAltTexts []string        `json:"post_image_alt_texts"`
+	PostImageCaptions []string        `json:"post_image_captions"`
+	// 一覧に表示する画像。画像が無い場合は 0
+	CoverImageID uint `json:"cover_image_id"`
+	// 投稿者本人の場合のみ。post_image_ids と同じ順で、撮影位置が無い画像は null
+	PostImageLocations []*ImageLocation `json:"post_image_locations,omitempty"`
+}
+
+// srcset の候補。幅の小さい順に並べ、最後は元画像
+type ImageSource struct {
+	Variant string `json:"variant"`
+	URL     string `json:"url"`
+	// 大きさを計測する前に保存した元画像は 0
+	Width  int `json:"width"`
+	Height int `json:"height"`
+}
+
+type ImageLocation struct {
+	Latitude  float64 `json:"latitude"`
+	Longitude float64 `json:"longitude"`
 }
@@ -0,0 +1,13 @@
+package response
+
+type Session struct {
+	ID         string `json:"id"`
+	UserAgent  string `json:"user_agent"`
+	LastSeenAt string `json:"last_seen_at"`
+	CreatedAt  string `json:"created_at"`
+	Current    bool   `json:"current"`
+}
+
+type SessionList struct {
+	Sessions []Session `json:"sessions"`
+}
@@ -1,6 +1,7 @@
 package response
 
 type UserProfile struct {
+	IDStr           string `json:"id_str"`
 	Name            string `json:"name"`
 	ScreenName      string `json:"screen_name"`
 	ProfileImageUrl string `json:"profile_image_url_https"`
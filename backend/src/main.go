@@ -1,6 +1,7 @@
 package main
 
 import (
+	"context"
 	"log"
 	"net/http"
 	"os"
@@ -10,9 +11,15 @@ import (
 	postgres_driver "gorm.io/driver/postgres"
 	"gorm.io/gorm"
 
+	"proto-pulse-plat/app/application/web/authorization"
 	"proto-pulse-plat/app/application/web/usecase"
 	"proto-pulse-plat/app/presentation/http/web/handler"
+	"proto-pulse-plat/auth"
 	"proto-pulse-plat/config"
+	"proto-pulse-plat/domain/identity"
+	"proto-pulse-plat/infrastructure/blobstore"
+	"proto-pulse-plat/infrastructure/fakex"
+	"proto-pulse-plat/infrastructure/identityprovider"
 	"proto-pulse-plat/infrastructure/persistence/postgres"
 	"proto-pulse-plat/middleware"
 )
@@ -37,33 +44,187 @@ func main() {
 
 	xConfig := config.LoadXconfig()
 
+	oidcConfigs, err := config.LoadOIDCProviderConfigs()
+	if err != nil {
+		log.Fatalf("failed to load OIDC provider config: %v", err)
+	}
+
+	providers := []identity.Provider{identityprovider.NewXProvider(xConfig)}
+	for _, oidcConfig := range oidcConfigs {
+		provider, err := identityprovider.NewOIDCProvider(context.Background(), oidcConfig)
+		if err != nil {
+			log.Fatalf("failed to set up identity provider %s: %v", oidcConfig.Name, err)
+		}
+		providers = append(providers, provider)
+	}
+
+	identityRegistry, err := identity.NewRegistry(providers...)
+	if err != nil {
+		log.Fatalf("failed to register identity providers: %v", err)
+	}
+
+	cookieConfig, err := config.LoadCookieConfig()
+	if err != nil {
+		log.Fatalf("failed to load cookie config: %v", err)
+	}
+
+	keyRing, err := config.LoadKeyRing()
+	if err != nil {
+		log.Fatalf("failed to load JWT key ring: %v", err)
+	}
+
+	blobStoreConfig, err := config.LoadBlobStoreConfig()
+	if err != nil {
+		log.Fatalf("failed to load blob store config: %v", err)
+	}
+
+	blobStore, err := blobstore.New(blobStoreConfig)
+	if err != nil {
+		log.Fatalf("failed to create blob store: %v", err)
+	}
+
+	imageVariantConfig, err := config.LoadImageVariantConfig()
+	if err != nil {
+		log.Fatalf("failed to load image variant config: %v", err)
+	}
+
 	postsRepository := postgres.NewGormPostsRepository(db)
 	usersRepository := postgres.NewGormUsersRepository(db)
 	postImagesRepository := postgres.NewGormPostImagesRepository(db)
+	postImageVariantsRepository := postgres.NewGormPostImageVariantsRepository(db)
+	sessionsRepository := postgres.NewGormSessionsRepository(db)
+	refreshTokensRepository := postgres.NewGormRefreshTokensRepository(db)
+	userIdentitiesRepository := postgres.NewGormUserIdentitiesRepository(db)
+	personalAccessTokensRepository := postgres.NewGormPersonalAccessTokensRepository(db)
+	userSuspensionsRepository := postgres.NewGormUserSuspensionsRepository(db)
+	appSettingsRepository := postgres.NewGormAppSettingsRepository(db)
+	blobLocksRepository := postgres.NewGormBlobLocksRepository(db)
+	transactor := postgres.NewGormTransactor(db)
+
+	postAuthorizer := authorization.NewPostAuthorizer(postsRepository)
 
-	oauthUsecase := usecase.NewOAuthUseCase(xConfig, usersRepository)
-	postUsecase := usecase.NewPostUsecase(postsRepository, postImagesRepository, usersRepository)
+	tokenIssuer := usecase.NewTokenIssuer(keyRing, sessionsRepository, refreshTokensRepository)
+
+	oauthUsecase := usecase.NewOAuthUseCase(
+		identityRegistry,
+		usersRepository,
+		userIdentitiesRepository,
+		userSuspensionsRepository,
+		sessionsRepository,
+		tokenIssuer,
+		blobStore,
+	)
+	imageVariantUsecase := usecase.NewImageVariantUsecase(
+		postImagesRepository,
+		postImageVariantsRepository,
+		blobLocksRepository,
+		blobStore,
+		imageVariantConfig,
+	)
+	postUsecase := usecase.NewPostUsecase(
+		postsRepository,
+		postImagesRepository,
+		usersRepository,
+		appSettingsRepository,
+		postImageVariantsRepository,
+		blobLocksRepository,
+		transactor,
+		postAuthorizer,
+		blobStore,
+		imageVariantUsecase,
+	)
 	userUsecase := usecase.NewUserUsecase(usersRepository)
+	imageUsecase := usecase.NewImageUsecase(
+		postsRepository,
+		postImagesRepository,
+		postImageVariantsRepository,
+		usersRepository,
+		blobStore,
+	)
+	sessionUsecase := usecase.NewSessionUsecase(sessionsRepository, refreshTokensRepository)
+	identityUsecase := usecase.NewIdentityUsecase(userIdentitiesRepository)
+	personalAccessTokenUsecase := usecase.NewPersonalAccessTokenUsecase(personalAccessTokensRepository)
+	adminUsecase := usecase.NewAdminUsecase(usersRepository, userSuspensionsRepository, sessionsRepository, appSettingsRepository)
+	authUsecase := usecase.NewAuthUsecase(usersRepository, sessionsRepository, refreshTokensRepository, tokenIssuer)
+
+	sessionMiddleware := middleware.NewSessionMiddleware(
+		keyRing,
+		usersRepository,
+		userSuspensionsRepository,
+		sessionsRepository,
+		personalAccessTokensRepository,
+	)
 
 	healthCheckHandler := handler.NewHealthCheckHandler()
-	oauthClientHandler := handler.NewOAuthClient(oauthUsecase, xConfig)
+	oauthClientHandler := handler.NewOAuthClient(oauthUsecase, cookieConfig)
 	postHandler := handler.NewPostHandler(postUsecase)
-	logoutHandler := handler.NewLogoutHandler()
+	logoutHandler := handler.NewLogoutHandler(sessionUsecase)
 	userHandler := handler.NewUserHandler(userUsecase)
+	imageHandler := handler.NewImageHandler(imageUsecase)
+	sessionHandler := handler.NewSessionHandler(sessionUsecase)
+	authHandler := handler.NewAuthHandler(authUsecase)
+	jwksHandler := handler.NewJWKSHandler(keyRing)
+	identityHandler := handler.NewIdentityHandler(identityUsecase)
+	personalAccessTokenHandler := handler.NewPersonalAccessTokenHandler(personalAccessTokenUsecase)
+	csrfHandler := handler.NewCSRFHandler(cookieConfig)
+	adminHandler := handler.NewAdminHandler(adminUsecase)
 
 	r := mux.NewRouter()
+	if config.FakeXEnabled() {
+		fakeXConfig, err := config.LoadFakeXConfig()
+		if err != nil {
+			log.Fatalf("failed to load fake X config: %v", err)
+		}
+		// 誰でも任意のテストユーザーとしてログインできるため、起動時に目立つように出力する
+		log.Printf("WARNING: ==================================================================")
+		log.Printf("WARNING: fake X OAuth server is mounted at %s", fakeXConfig.BaseURL)
+		log.Printf("WARNING: anyone can sign in as any of %d fake personas", len(fakeXConfig.Personas))
+		log.Printf("WARNING: this is enabled because APP_ENV=local and X_CONSUMER is not set")
+		log.Printf("WARNING: ==================================================================")
+		fakeXServer := fakex.NewServer(fakeXConfig)
+		r.PathPrefix(config.FakeXPathPrefix).Handler(http.StripPrefix(config.FakeXPathPrefix, fakeXServer.Handler()))
+	}
+	r.HandleFunc("/.well-known/jwks.json", jwksHandler.JWKS)
+	// アクセストークンの期限が切れた後に呼ばれ、CSRF トークンを結び付けたセッションを確定できないため、/api の CSRF より先に登録する。
+	// リフレッシュトークンの Cookie は /api/auth 配下にのみ送信され、ローテーションした結果はクロスサイトから読み取れない
+	r.HandleFunc("/api/auth/refresh", authHandler.Refresh).Methods(http.MethodPost)
 	apiRouter := r.PathPrefix("/api").Subrouter()
+	apiRouter.Use(sessionMiddleware.CSRF(cookieConfig.StoreKey))
 	apiRouter.HandleFunc("/health", healthCheckHandler.HealthCheck)
+	apiRouter.HandleFunc("/csrf", sessionMiddleware.Optional(http.HandlerFunc(csrfHandler.Token)).ServeHTTP)
 	apiRouter.HandleFunc("/oauth", oauthClientHandler.OauthCertificate)
 	apiRouter.HandleFunc("/oauth2callback", oauthClientHandler.OauthCallback)
-	apiRouter.HandleFunc("/logout", logoutHandler.Logout)
+	apiRouter.HandleFunc("/oauth/{provider}", oauthClientHandler.OauthCertificate)
+	apiRouter.HandleFunc("/oauth/{provider}/callback", oauthClientHandler.OauthCallback)
+	// GET で受け付けると画像タグなどから他サイトにログアウトさせられるため、CSRF トークンを要求する POST のみにする
+	apiRouter.HandleFunc("/logout", sessionMiddleware.Optional(http.HandlerFunc(logoutHandler.Logout)).ServeHTTP).Methods(http.MethodPost)
+	apiRouter.HandleFunc("/auth/logout", sessionMiddleware.Optional(http.HandlerFunc(logoutHandler.Logout)).ServeHTTP).Methods(http.MethodPost)
+	apiRouter.HandleFunc("/logout/all", sessionMiddleware.Required(http.HandlerFunc(logoutHandler.LogoutAll)).ServeHTTP).Methods(http.MethodPost)
+	apiRouter.HandleFunc("/sessions", sessionMiddleware.Required(http.HandlerFunc(sessionHandler.List)).ServeHTTP)
+	apiRouter.HandleFunc("/sessions/revoke", sessionMiddleware.Required(http.HandlerFunc(sessionHandler.Revoke)).ServeHTTP)
+	apiRouter.HandleFunc("/identities", sessionMiddleware.Required(http.HandlerFunc(identityHandler.List)).ServeHTTP)
+	apiRouter.HandleFunc("/identities/unlink", sessionMiddleware.Required(http.HandlerFunc(identityHandler.Unlink)).ServeHTTP)
+	apiRouter.HandleFunc("/identities/link/{provider}", sessionMiddleware.Required(http.HandlerFunc(oauthClientHandler.OauthLink)).ServeHTTP)
+	apiRouter.HandleFunc("/tokens", sessionMiddleware.Required(http.HandlerFunc(personalAccessTokenHandler.List)).ServeHTTP)
+	apiRouter.HandleFunc("/tokens/create", sessionMiddleware.Required(http.HandlerFunc(personalAccessTokenHandler.Create)).ServeHTTP)
+	apiRouter.HandleFunc("/tokens/revoke", sessionMiddleware.Required(http.HandlerFunc(personalAccessTokenHandler.Revoke)).ServeHTTP)
 	postRouter := apiRouter.PathPrefix("/post").Subrouter()
-	postRouter.HandleFunc("/add", middleware.SessionMiddleware(http.HandlerFunc(postHandler.AddPost)).ServeHTTP)
-	postRouter.HandleFunc("/delete", middleware.SessionMiddleware(http.HandlerFunc(postHandler.DeletePost)).ServeHTTP)
-	postRouter.HandleFunc("/list", postHandler.GetPostList)
-	postRouter.HandleFunc("/get", postHandler.GetPost)
+	postRouter.HandleFunc("/add", sessionMiddleware.RequiredScope(auth.ScopePostsWrite, http.HandlerFunc(postHandler.AddPost)).ServeHTTP)
+	postRouter.HandleFunc("/update", sessionMiddleware.RequiredScope(auth.ScopePostsWrite, http.HandlerFunc(postHandler.UpdatePost)).ServeHTTP)
+	postRouter.HandleFunc("/delete", sessionMiddleware.RequiredScope(auth.ScopePostsWrite, http.HandlerFunc(postHandler.DeletePost)).ServeHTTP)
+	postRouter.HandleFunc("/list", sessionMiddleware.OptionalScope(auth.ScopePostsRead, http.HandlerFunc(postHandler.GetPostList)).ServeHTTP)
+	postRouter.HandleFunc("/get", sessionMiddleware.OptionalScope(auth.ScopePostsRead, http.HandlerFunc(postHandler.GetPost)).ServeHTTP)
 	userRouter := apiRouter.PathPrefix("/user").Subrouter()
 	userRouter.HandleFunc("/get", userHandler.Find)
+	apiRouter.HandleFunc("/images/{id}", imageHandler.GetPostImage)
+	apiRouter.HandleFunc("/users/{id}/icon", imageHandler.GetUserIcon)
+	adminRouter := apiRouter.PathPrefix("/admin").Subrouter()
+	adminRouter.Use(sessionMiddleware.Required, middleware.RequireRole(auth.RoleModerator))
+	adminRouter.HandleFunc("/users/suspend", adminHandler.Suspend)
+	adminRouter.HandleFunc("/users/unsuspend", adminHandler.Unsuspend)
+	adminRouter.Handle("/users/role", middleware.RequireRole(auth.RoleAdmin)(http.HandlerFunc(adminHandler.UpdateRole)))
+	adminRouter.Handle("/settings", middleware.RequireRole(auth.RoleAdmin)(http.HandlerFunc(adminHandler.GetSettings)))
+	adminRouter.Handle("/settings/update", middleware.RequireRole(auth.RoleAdmin)(http.HandlerFunc(adminHandler.UpdateSettings)))
 
 	corsMiddleware := middleware.CORSMiddleware()
 	srv := &http.Server{
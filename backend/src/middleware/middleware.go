@@ -1,11 +1,21 @@
 package middleware
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"net/http"
 	"os"
+	"proto-pulse-plat/auth"
+	"proto-pulse-plat/config"
+	"proto-pulse-plat/domain/apperror"
+	"proto-pulse-plat/domain/repository"
+	"proto-pulse-plat/helper"
+	"strings"
+	"time"
 
 	"github.com/gorilla/handlers"
+	"gorm.io/gorm"
 )
 
 func CORSMiddleware() func(http.Handler) http.Handler {
@@ -22,14 +32,270 @@ func CORSMiddleware() func(http.Handler) http.Handler {
 	)
 }
 
-func SessionMiddleware(next http.Handler) http.Handler {
+// last_seen_at の更新間隔。リクエストごとの書き込みを避ける
+const sessionTouchInterval = time.Minute
+
+type SessionMiddleware struct {
+	keyRing        *config.KeyRing
+	userRepo       repository.UsersRepository
+	suspensionRepo repository.UserSuspensionsRepository
+	sessionRepo    repository.SessionsRepository
+	tokenRepo      repository.PersonalAccessTokensRepository
+}
+
+func NewSessionMiddleware(
+	keyRing *config.KeyRing,
+	userRepo repository.UsersRepository,
+	suspensionRepo repository.UserSuspensionsRepository,
+	sessionRepo repository.SessionsRepository,
+	tokenRepo repository.PersonalAccessTokensRepository,
+) *SessionMiddleware {
+	return &SessionMiddleware{
+		keyRing:        keyRing,
+		userRepo:       userRepo,
+		suspensionRepo: suspensionRepo,
+		sessionRepo:    sessionRepo,
+		tokenRepo:      tokenRepo,
+	}
+}
+
+// 認証必須。ブラウザのセッションのみ受け付け、ログインユーザーを context に格納する
+func (m *SessionMiddleware) Required(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		cookie, err := r.Cookie("auth_token")
-		if err != nil || cookie.Value == "" {
-			fmt.Println(err)
-			http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		principal, err := m.authenticateSession(r)
+		if err != nil {
+			writeAuthenticationError(w, err)
+			return
+		}
+		next.ServeHTTP(w, r.WithContext(auth.WithPrincipal(r.Context(), principal)))
+	})
+}
+
+// 認証任意。トークンが無いか無効な場合は未ログインとして扱う
+func (m *SessionMiddleware) Optional(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		principal, err := m.authenticateSession(r)
+		if err != nil {
+			next.ServeHTTP(w, r)
+			return
+		}
+		next.ServeHTTP(w, r.WithContext(auth.WithPrincipal(r.Context(), principal)))
+	})
+}
+
+// 認証必須。ブラウザのセッションに加え、scope を持つパーソナルアクセストークンを受け付ける
+func (m *SessionMiddleware) RequiredScope(scope string, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		principal, err := m.authenticate(r)
+		if err != nil {
+			writeAuthenticationError(w, err)
+			return
+		}
+		if !principal.HasScope(scope) {
+			helper.WriteError(w, apperror.Forbidden("token does not have the %s scope", scope), "")
+			return
+		}
+		next.ServeHTTP(w, r.WithContext(auth.WithPrincipal(r.Context(), principal)))
+	})
+}
+
+// 認証任意。Authorization ヘッダーを送った場合は、無効なトークンやスコープ不足を未ログインとして扱わず拒否する
+func (m *SessionMiddleware) OptionalScope(scope string, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if _, ok := bearerToken(r); !ok {
+			m.Optional(next).ServeHTTP(w, r)
 			return
 		}
-		next.ServeHTTP(w, r)
+		m.RequiredScope(scope, next).ServeHTTP(w, r)
+	})
+}
+
+func (m *SessionMiddleware) authenticate(r *http.Request) (*auth.Principal, error) {
+	if token, ok := bearerToken(r); ok {
+		if result, ok := r.Context().Value(authResultKey{}).(*authResult); ok {
+			return result.principal, result.err
+		}
+		return m.authenticateToken(token)
+	}
+	return m.authenticateSession(r)
+}
+
+// Cookie で認証される状態変更リクエスト (GET/HEAD/OPTIONS 以外) に CSRF トークンを要求する。
+// トークンはこのリクエストの Cookie で確定したセッション (未ログインの場合は anonymous) に対して検証する。
+// Authorization: Bearer のリクエストはクロスサイトから送信できないため、トークンで認証できた場合のみ対象外とする。
+// 認証できない Bearer を付けただけのリクエストは、Cookie のリクエストと同じく CSRF トークンを要求する。
+// /api/auth/refresh はこのミドルウェアを通さない (main.go)
+func (m *SessionMiddleware) CSRF(storeKey string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			switch r.Method {
+			case http.MethodGet, http.MethodHead, http.MethodOptions:
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			// 後続の Required や RequiredScope は同じ認証結果を使う
+			var principal *auth.Principal
+			var err error
+			if token, ok := bearerToken(r); ok {
+				principal, err = m.authenticateToken(token)
+				r = r.WithContext(withAuthResult(r.Context(), principal, err))
+				if err == nil {
+					next.ServeHTTP(w, r)
+					return
+				}
+			} else {
+				principal, err = m.authenticateSession(r)
+				r = r.WithContext(withAuthResult(r.Context(), principal, err))
+			}
+
+			var subject *auth.Principal
+			if err == nil {
+				subject = principal
+			}
+			if !helper.VerifyCSRFToken(r, storeKey, helper.CSRFSubject(subject)) {
+				helper.WriteError(w, apperror.InvalidCSRFToken(), "")
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// CSRF で確定した、このリクエストの Cookie または Bearer トークンの認証結果
+type authResult struct {
+	principal *auth.Principal
+	err       error
+}
+
+type authResultKey struct{}
+
+func withAuthResult(ctx context.Context, principal *auth.Principal, err error) context.Context {
+	return context.WithValue(ctx, authResultKey{}, &authResult{principal: principal, err: err})
+}
+
+func (m *SessionMiddleware) authenticateSession(r *http.Request) (*auth.Principal, error) {
+	// Bearer のリクエストは CSRF の検証を経ていないため、Cookie では認証しない
+	if _, ok := bearerToken(r); ok {
+		return nil, fmt.Errorf("bearer token is not accepted on this route")
+	}
+
+	if result, ok := r.Context().Value(authResultKey{}).(*authResult); ok {
+		return result.principal, result.err
+	}
+
+	cookie, err := r.Cookie(helper.AuthCookieName)
+	if err != nil {
+		return nil, err
+	}
+	if cookie.Value == "" {
+		return nil, fmt.Errorf("auth_token is empty")
+	}
+
+	claims, err := auth.ParseToken(m.keyRing, cookie.Value)
+	if err != nil {
+		return nil, err
+	}
+
+	// ログアウト・失効済みのセッションは拒否する
+	session, err := m.sessionRepo.FindActiveByID(claims.ID)
+	if err != nil {
+		return nil, fmt.Errorf("session %q is not active: %w", claims.ID, err)
+	}
+	if session.UserID != claims.UserID {
+		return nil, fmt.Errorf("session %q does not belong to user %d", claims.ID, claims.UserID)
+	}
+
+	if now := time.Now(); now.Sub(session.LastSeenAt) > sessionTouchInterval {
+		if err := m.sessionRepo.Touch(session.ID, now); err != nil {
+			fmt.Println(err)
+		}
+	}
+
+	return m.loadUser(claims.Principal())
+}
+
+func (m *SessionMiddleware) authenticateToken(token string) (*auth.Principal, error) {
+	if !auth.IsPersonalAccessToken(token) {
+		return nil, fmt.Errorf("bearer token is not a personal access token")
+	}
+
+	// 失効・期限切れのトークンは拒否する
+	accessToken, err := m.tokenRepo.FindActiveByHash(auth.HashPersonalAccessToken(token))
+	if err != nil {
+		return nil, fmt.Errorf("personal access token is not active: %w", err)
+	}
+
+	if now := time.Now(); accessToken.LastUsedAt == nil || now.Sub(*accessToken.LastUsedAt) > sessionTouchInterval {
+		if err := m.tokenRepo.TouchLastUsed(accessToken.ID, now); err != nil {
+			fmt.Println(err)
+		}
+	}
+
+	return m.loadUser(&auth.Principal{
+		UserID:     accessToken.UserID,
+		AuthMethod: auth.AuthMethodPersonalAccessToken,
+		TokenID:    accessToken.ID,
+		Scopes:     accessToken.Scopes,
 	})
-}
\ No newline at end of file
+}
+
+// ロールの変更や利用停止がすぐに反映されるよう、ユーザーの状態はリクエストごとに読み込む
+func (m *SessionMiddleware) loadUser(principal *auth.Principal) (*auth.Principal, error) {
+	user, err := m.userRepo.FindForAuthentication(principal.UserID)
+	if err != nil {
+		return nil, fmt.Errorf("user %d: %w", principal.UserID, err)
+	}
+
+	// 期限付きの利用停止は期限を過ぎると自動的に無効になる
+	_, err = m.suspensionRepo.FindActiveByUserID(principal.UserID)
+	if err == nil {
+		return nil, apperror.AccountSuspended()
+	}
+	if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, err
+	}
+
+	principal.Role = user.Role
+	return principal, nil
+}
+
+func writeAuthenticationError(w http.ResponseWriter, err error) {
+	fmt.Println(err)
+	if errors.Is(err, apperror.ErrAccountSuspended) {
+		helper.WriteError(w, err, "")
+		return
+	}
+	helper.WriteError(w, apperror.Unauthorized("authentication is required"), "")
+}
+
+// Required などの後に使い、ログインユーザーが role 以上のロールを持つ場合のみ通す
+func RequireRole(role string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			principal, ok := auth.PrincipalFromContext(r.Context())
+			if !ok {
+				helper.WriteError(w, apperror.Unauthorized("authentication is required"), "")
+				return
+			}
+			if !principal.HasRole(role) {
+				helper.WriteError(w, apperror.Forbidden("%s role is required", role), "")
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+func bearerToken(r *http.Request) (string, bool) {
+	header := r.Header.Get("Authorization")
+	if header == "" {
+		return "", false
+	}
+	scheme, token, ok := strings.Cut(header, " ")
+	if !ok || !strings.EqualFold(scheme, "Bearer") {
+		return "", false
+	}
+	return strings.TrimSpace(token), true
+}
@@ -0,0 +1,441 @@
+package middleware
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"proto-pulse-plat/auth"
+	"proto-pulse-plat/config"
+	"proto-pulse-plat/domain/entity"
+	"proto-pulse-plat/domain/repository"
+	"proto-pulse-plat/helper"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v4"
+	"gorm.io/gorm"
+)
+
+const testCSRFStoreKey = "test-store-key"
+
+type fakeUsersRepository struct {
+	repository.UsersRepository
+	users map[uint]*entity.User
+}
+
+func (f *fakeUsersRepository) FindForAuthentication(id uint) (*entity.User, error) {
+	user, ok := f.users[id]
+	if !ok {
+		return nil, gorm.ErrRecordNotFound
+	}
+	return user, nil
+}
+
+type fakeSuspensionsRepository struct {
+	repository.UserSuspensionsRepository
+	suspended map[uint]bool
+}
+
+func (f *fakeSuspensionsRepository) FindActiveByUserID(userID uint) (*entity.UserSuspension, error) {
+	if !f.suspended[userID] {
+		return nil, gorm.ErrRecordNotFound
+	}
+	return &entity.UserSuspension{UserID: userID}, nil
+}
+
+type fakeSessionsRepository struct {
+	repository.SessionsRepository
+	sessions map[string]*entity.Session
+}
+
+func (f *fakeSessionsRepository) FindActiveByID(id string) (*entity.Session, error) {
+	session, ok := f.sessions[id]
+	if !ok {
+		return nil, gorm.ErrRecordNotFound
+	}
+	return session, nil
+}
+
+func (f *fakeSessionsRepository) Touch(id string, lastSeenAt time.Time) error {
+	return nil
+}
+
+type fakeTokensRepository struct {
+	repository.PersonalAccessTokensRepository
+	tokens  map[string]*entity.PersonalAccessToken
+	lookups int
+}
+
+func (f *fakeTokensRepository) FindActiveByHash(tokenHash string) (*entity.PersonalAccessToken, error) {
+	f.lookups++
+	token, ok := f.tokens[tokenHash]
+	if !ok {
+		return nil, gorm.ErrRecordNotFound
+	}
+	return token, nil
+}
+
+func (f *fakeTokensRepository) TouchLastUsed(id uint, lastUsedAt time.Time) error {
+	return nil
+}
+
+type testEnv struct {
+	middleware  *SessionMiddleware
+	keyRing     *config.KeyRing
+	users       *fakeUsersRepository
+	suspensions *fakeSuspensionsRepository
+	sessions    *fakeSessionsRepository
+	tokens      *fakeTokensRepository
+}
+
+func newTestEnv(t *testing.T) *testEnv {
+	t.Helper()
+	t.Setenv("JWT_SECRET_KEY", "test-secret")
+	t.Setenv("JWT_KEYS", "")
+	t.Setenv("JWT_ACTIVE_KID", "")
+	keyRing, err := config.LoadKeyRing()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	env := &testEnv{
+		keyRing:     keyRing,
+		users:       &fakeUsersRepository{users: map[uint]*entity.User{}},
+		suspensions: &fakeSuspensionsRepository{suspended: map[uint]bool{}},
+		sessions:    &fakeSessionsRepository{sessions: map[string]*entity.Session{}},
+		tokens:      &fakeTokensRepository{tokens: map[string]*entity.PersonalAccessToken{}},
+	}
+	env.middleware = NewSessionMiddleware(keyRing, env.users, env.suspensions, env.sessions, env.tokens)
+	return env
+}
+
+func (env *testEnv) addUser(id uint, role string) {
+	env.users.users[id] = &entity.User{ID: id, Role: role}
+}
+
+// ユーザーのパーソナルアクセストークンを発行し、平文を返す
+func (env *testEnv) addToken(t *testing.T, userID uint, scopes ...string) string {
+	t.Helper()
+	token, prefix, hash, err := auth.NewPersonalAccessToken()
+	if err != nil {
+		t.Fatal(err)
+	}
+	env.tokens.tokens[hash] = &entity.PersonalAccessToken{
+		ID:          uint(len(env.tokens.tokens) + 1),
+		UserID:      userID,
+		TokenPrefix: prefix,
+		TokenHash:   hash,
+		Scopes:      scopes,
+	}
+	return token
+}
+
+// ユーザーのセッションを作り、認証 Cookie の値を返す
+func (env *testEnv) addSession(t *testing.T, userID uint, sessionID string) string {
+	t.Helper()
+	now := time.Now()
+	env.sessions.sessions[sessionID] = &entity.Session{
+		ID:         sessionID,
+		UserID:     userID,
+		LastSeenAt: now,
+		ExpiresAt:  now.Add(time.Hour),
+	}
+	token, err := auth.SignToken(env.keyRing, &auth.Claims{
+		UserID: userID,
+		RegisteredClaims: jwt.RegisteredClaims{
+			ID:        sessionID,
+			ExpiresAt: jwt.NewNumericDate(now.Add(time.Hour)),
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return token
+}
+
+// subject に結び付いた CSRF トークンを Cookie とヘッダーに設定する
+func setCSRFToken(t *testing.T, r *http.Request, subject string) {
+	t.Helper()
+	recorder := httptest.NewRecorder()
+	token, err := helper.IssueCSRFToken(recorder, httptest.NewRequest(http.MethodGet, "/api/csrf", nil), testCSRFStoreKey, subject)
+	if err != nil {
+		t.Fatal(err)
+	}
+	r.AddCookie(&http.Cookie{Name: helper.CSRFCookieName, Value: token})
+	r.Header.Set(helper.CSRFHeaderName, token)
+}
+
+// handler にリクエストを送り、ステータスとエラーコードを返す
+func serve(handler http.Handler, r *http.Request) (int, string) {
+	recorder := httptest.NewRecorder()
+	handler.ServeHTTP(recorder, r)
+
+	var body struct {
+		Error struct {
+			Code string `json:"code"`
+		} `json:"error"`
+	}
+	json.Unmarshal(recorder.Body.Bytes(), &body)
+	return recorder.Code, body.Error.Code
+}
+
+var okHandler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+	w.WriteHeader(http.StatusOK)
+})
+
+// Bearer のリクエストを CSRF の対象外にするのは、トークンで認証できた場合のみ
+func TestCSRFSkipsOnlyAuthenticatedBearerRequests(t *testing.T) {
+	env := newTestEnv(t)
+	env.addUser(1, auth.RoleUser)
+	token := env.addToken(t, 1, auth.ScopePostsWrite)
+	handler := env.middleware.CSRF(testCSRFStoreKey)(env.middleware.RequiredScope(auth.ScopePostsWrite, okHandler))
+
+	tests := []struct {
+		name       string
+		bearer     string
+		csrf       bool
+		wantStatus int
+		wantCode   string
+	}{
+		{name: "valid token without CSRF token", bearer: token, wantStatus: http.StatusOK},
+		{name: "unknown token without CSRF token", bearer: auth.PersonalAccessTokenPrefix + "unknown", wantStatus: http.StatusForbidden, wantCode: "invalid_csrf_token"},
+		{name: "non-token bearer without CSRF token", bearer: "garbage", wantStatus: http.StatusForbidden, wantCode: "invalid_csrf_token"},
+		{name: "unknown token with CSRF token", bearer: auth.PersonalAccessTokenPrefix + "unknown", csrf: true, wantStatus: http.StatusUnauthorized, wantCode: "unauthorized"},
+		{name: "no credentials without CSRF token", wantStatus: http.StatusForbidden, wantCode: "invalid_csrf_token"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodPost, "/api/post/add", nil)
+			if tt.bearer != "" {
+				r.Header.Set("Authorization", "Bearer "+tt.bearer)
+			}
+			if tt.csrf {
+				setCSRFToken(t, r, helper.CSRFSubject(nil))
+			}
+
+			status, code := serve(handler, r)
+			if status != tt.wantStatus || code != tt.wantCode {
+				t.Errorf("got %d %q, want %d %q", status, code, tt.wantStatus, tt.wantCode)
+			}
+		})
+	}
+}
+
+// CSRF で認証したトークンを RequiredScope で再び検索しない
+func TestCSRFSharesBearerResultWithRequiredScope(t *testing.T) {
+	env := newTestEnv(t)
+	env.addUser(1, auth.RoleUser)
+	token := env.addToken(t, 1, auth.ScopePostsWrite)
+	handler := env.middleware.CSRF(testCSRFStoreKey)(env.middleware.RequiredScope(auth.ScopePostsWrite, okHandler))
+
+	r := httptest.NewRequest(http.MethodPost, "/api/post/add", nil)
+	r.Header.Set("Authorization", "Bearer "+token)
+	if status, _ := serve(handler, r); status != http.StatusOK {
+		t.Fatalf("got %d, want 200", status)
+	}
+	if env.tokens.lookups != 1 {
+		t.Errorf("token was looked up %d times, want 1", env.tokens.lookups)
+	}
+}
+
+// Cookie のセッションで認証されたリクエストは、そのセッションに結び付いた CSRF トークンのみ受け付ける
+func TestCSRFBindsTokenToSession(t *testing.T) {
+	env := newTestEnv(t)
+	env.addUser(1, auth.RoleUser)
+	authCookie := env.addSession(t, 1, "session-1")
+	handler := env.middleware.CSRF(testCSRFStoreKey)(env.middleware.Required(okHandler))
+
+	tests := []struct {
+		name       string
+		subject    string
+		wantStatus int
+	}{
+		{name: "token for the session", subject: "session:session-1", wantStatus: http.StatusOK},
+		{name: "token for another session", subject: "session:session-2", wantStatus: http.StatusForbidden},
+		{name: "anonymous token", subject: helper.CSRFSubject(nil), wantStatus: http.StatusForbidden},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodPost, "/api/logout/all", nil)
+			r.AddCookie(&http.Cookie{Name: helper.AuthCookieName, Value: authCookie})
+			setCSRFToken(t, r, tt.subject)
+
+			if status, _ := serve(handler, r); status != tt.wantStatus {
+				t.Errorf("got %d, want %d", status, tt.wantStatus)
+			}
+		})
+	}
+}
+
+// パーソナルアクセストークンはスコープを持つルートのみ、スコープを持つ場合のみ受け付ける
+func TestRequiredScope(t *testing.T) {
+	env := newTestEnv(t)
+	env.addUser(1, auth.RoleUser)
+	readToken := env.addToken(t, 1, auth.ScopePostsRead)
+	writeToken := env.addToken(t, 1, auth.ScopePostsRead, auth.ScopePostsWrite)
+	authCookie := env.addSession(t, 1, "session-1")
+	handler := env.middleware.RequiredScope(auth.ScopePostsWrite, okHandler)
+
+	tests := []struct {
+		name       string
+		bearer     string
+		cookie     string
+		wantStatus int
+		wantCode   string
+	}{
+		{name: "token with the scope", bearer: writeToken, wantStatus: http.StatusOK},
+		{name: "token without the scope", bearer: readToken, wantStatus: http.StatusForbidden, wantCode: "forbidden"},
+		{name: "unknown token", bearer: auth.PersonalAccessTokenPrefix + "unknown", wantStatus: http.StatusUnauthorized, wantCode: "unauthorized"},
+		{name: "session has every scope", cookie: authCookie, wantStatus: http.StatusOK},
+		{name: "token does not fall back to the session", bearer: readToken, cookie: authCookie, wantStatus: http.StatusForbidden, wantCode: "forbidden"},
+		{name: "no credentials", wantStatus: http.StatusUnauthorized, wantCode: "unauthorized"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodGet, "/api/post/add", nil)
+			if tt.bearer != "" {
+				r.Header.Set("Authorization", "Bearer "+tt.bearer)
+			}
+			if tt.cookie != "" {
+				r.AddCookie(&http.Cookie{Name: helper.AuthCookieName, Value: tt.cookie})
+			}
+
+			status, code := serve(handler, r)
+			if status != tt.wantStatus || code != tt.wantCode {
+				t.Errorf("got %d %q, want %d %q", status, code, tt.wantStatus, tt.wantCode)
+			}
+		})
+	}
+}
+
+// 認証任意のルートでも、送られたトークンが無効またはスコープ不足の場合は未ログインとして扱わない
+func TestOptionalScope(t *testing.T) {
+	env := newTestEnv(t)
+	env.addUser(1, auth.RoleUser)
+	readToken := env.addToken(t, 1, auth.ScopePostsRead)
+	writeOnlyToken := env.addToken(t, 1, auth.ScopePostsWrite)
+
+	var gotPrincipal *auth.Principal
+	handler := env.middleware.OptionalScope(auth.ScopePostsRead, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotPrincipal, _ = auth.PrincipalFromContext(r.Context())
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	tests := []struct {
+		name          string
+		bearer        string
+		wantStatus    int
+		wantPrincipal bool
+	}{
+		{name: "token with the scope", bearer: readToken, wantStatus: http.StatusOK, wantPrincipal: true},
+		{name: "token without the scope", bearer: writeOnlyToken, wantStatus: http.StatusForbidden},
+		{name: "unknown token", bearer: auth.PersonalAccessTokenPrefix + "unknown", wantStatus: http.StatusUnauthorized},
+		{name: "anonymous", wantStatus: http.StatusOK},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gotPrincipal = nil
+			r := httptest.NewRequest(http.MethodGet, "/api/post/list", nil)
+			if tt.bearer != "" {
+				r.Header.Set("Authorization", "Bearer "+tt.bearer)
+			}
+
+			status, _ := serve(handler, r)
+			if status != tt.wantStatus {
+				t.Errorf("got %d, want %d", status, tt.wantStatus)
+			}
+			if (gotPrincipal != nil) != tt.wantPrincipal {
+				t.Errorf("principal = %+v, want present = %v", gotPrincipal, tt.wantPrincipal)
+			}
+		})
+	}
+}
+
+// 管理画面と同じく Required の後に RequireRole を重ね、ロールが足りない場合は拒否する
+func TestRequireRole(t *testing.T) {
+	env := newTestEnv(t)
+	cookies := map[string]string{}
+	for i, role := range []string{auth.RoleUser, auth.RoleModerator, auth.RoleAdmin} {
+		userID := uint(i + 1)
+		env.addUser(userID, role)
+		cookies[role] = env.addSession(t, userID, "session-"+role)
+	}
+
+	tests := []struct {
+		name       string
+		required   string
+		role       string
+		wantStatus int
+	}{
+		{name: "user on moderator route", required: auth.RoleModerator, role: auth.RoleUser, wantStatus: http.StatusForbidden},
+		{name: "moderator on moderator route", required: auth.RoleModerator, role: auth.RoleModerator, wantStatus: http.StatusOK},
+		{name: "admin on moderator route", required: auth.RoleModerator, role: auth.RoleAdmin, wantStatus: http.StatusOK},
+		{name: "moderator on admin route", required: auth.RoleAdmin, role: auth.RoleModerator, wantStatus: http.StatusForbidden},
+		{name: "admin on admin route", required: auth.RoleAdmin, role: auth.RoleAdmin, wantStatus: http.StatusOK},
+		{name: "anonymous on moderator route", required: auth.RoleModerator, wantStatus: http.StatusUnauthorized},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			handler := env.middleware.Required(RequireRole(tt.required)(okHandler))
+			r := httptest.NewRequest(http.MethodGet, "/api/admin/settings", nil)
+			if tt.role != "" {
+				r.AddCookie(&http.Cookie{Name: helper.AuthCookieName, Value: cookies[tt.role]})
+			}
+
+			if status, _ := serve(handler, r); status != tt.wantStatus {
+				t.Errorf("got %d, want %d", status, tt.wantStatus)
+			}
+		})
+	}
+}
+
+// ロールの変更はセッションを作り直さなくても次のリクエストから反映される
+func TestRequireRoleUsesCurrentRole(t *testing.T) {
+	env := newTestEnv(t)
+	env.addUser(1, auth.RoleAdmin)
+	authCookie := env.addSession(t, 1, "session-1")
+	handler := env.middleware.Required(RequireRole(auth.RoleAdmin)(okHandler))
+
+	env.users.users[1].Role = auth.RoleUser
+	r := httptest.NewRequest(http.MethodGet, "/api/admin/settings", nil)
+	r.AddCookie(&http.Cookie{Name: helper.AuthCookieName, Value: authCookie})
+	if status, _ := serve(handler, r); status != http.StatusForbidden {
+		t.Errorf("got %d, want 403 after the role was lowered", status)
+	}
+}
+
+// 利用停止中のユーザーはセッションでもトークンでも認証できず、管理者であっても拒否する
+func TestSuspendedUserIsRejected(t *testing.T) {
+	env := newTestEnv(t)
+	env.addUser(1, auth.RoleAdmin)
+	env.suspensions.suspended[1] = true
+	authCookie := env.addSession(t, 1, "session-1")
+	token := env.addToken(t, 1, auth.ScopePostsRead, auth.ScopePostsWrite)
+
+	tests := []struct {
+		name    string
+		handler http.Handler
+		bearer  string
+		cookie  string
+	}{
+		{name: "session on admin route", handler: env.middleware.Required(RequireRole(auth.RoleAdmin)(okHandler)), cookie: authCookie},
+		{name: "token on scoped route", handler: env.middleware.RequiredScope(auth.ScopePostsWrite, okHandler), bearer: token},
+		{name: "token on optional route", handler: env.middleware.OptionalScope(auth.ScopePostsRead, okHandler), bearer: token},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodGet, "/api/admin/settings", nil)
+			if tt.bearer != "" {
+				r.Header.Set("Authorization", "Bearer "+tt.bearer)
+			}
+			if tt.cookie != "" {
+				r.AddCookie(&http.Cookie{Name: helper.AuthCookieName, Value: tt.cookie})
+			}
+
+			status, code := serve(tt.handler, r)
+			if status != http.StatusForbidden || code != "account_suspended" {
+				t.Errorf("got %d %q, want 403 \"account_suspended\"", status, code)
+			}
+		})
+	}
+}
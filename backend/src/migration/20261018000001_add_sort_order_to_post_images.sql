@@ -0,0 +1,16 @@
+-- +goose Up
+ALTER TABLE post_images ADD COLUMN sort_order INTEGER NOT NULL DEFAULT 0;
+
+UPDATE post_images AS pi
+SET sort_order = ordered.rn
+FROM (
+    SELECT id, ROW_NUMBER() OVER (PARTITION BY post_id ORDER BY id) - 1 AS rn
+    FROM post_images
+) AS ordered
+WHERE pi.id = ordered.id;
+
+CREATE INDEX idx_post_images_post_id_sort_order ON post_images (post_id, sort_order);
+
+-- +goose Down
+DROP INDEX IF EXISTS idx_post_images_post_id_sort_order;
+ALTER TABLE post_images DROP COLUMN sort_order;
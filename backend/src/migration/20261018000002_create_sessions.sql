@@ -0,0 +1,16 @@
+-- +goose Up
+CREATE TABLE sessions (
+    id           VARCHAR(64)  PRIMARY KEY,
+    user_id      BIGINT       NOT NULL,
+    user_agent   VARCHAR(512) NOT NULL DEFAULT '',
+    last_seen_at TIMESTAMPTZ  NOT NULL,
+    expires_at   TIMESTAMPTZ  NOT NULL,
+    revoked_at   TIMESTAMPTZ,
+    created_at   TIMESTAMPTZ  NOT NULL,
+    updated_at   TIMESTAMPTZ  NOT NULL
+);
+
+CREATE INDEX idx_sessions_user_id ON sessions (user_id);
+
+-- +goose Down
+DROP TABLE IF EXISTS sessions;
@@ -0,0 +1,16 @@
+-- +goose Up
+CREATE TABLE refresh_tokens (
+    id         BIGSERIAL   PRIMARY KEY,
+    session_id VARCHAR(64) NOT NULL REFERENCES sessions (id) ON DELETE CASCADE,
+    user_id    BIGINT      NOT NULL,
+    token_hash VARCHAR(64) NOT NULL UNIQUE,
+    expires_at TIMESTAMPTZ NOT NULL,
+    used_at    TIMESTAMPTZ,
+    created_at TIMESTAMPTZ NOT NULL,
+    updated_at TIMESTAMPTZ NOT NULL
+);
+
+CREATE INDEX idx_refresh_tokens_session_id ON refresh_tokens (session_id);
+
+-- +goose Down
+DROP TABLE IF EXISTS refresh_tokens;
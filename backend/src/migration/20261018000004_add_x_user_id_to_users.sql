@@ -0,0 +1,19 @@
+-- +goose Up
+-- 既存データは user_name にスクリーンネーム、account_id に表示名が入っているため入れ替える
+UPDATE users SET user_name = account_id, account_id = user_name;
+
+-- スクリーンネームは変更・再利用されるため一意キーにしない
+ALTER TABLE users DROP CONSTRAINT IF EXISTS uni_users_account_id;
+ALTER TABLE users DROP CONSTRAINT IF EXISTS users_account_id_key;
+CREATE INDEX idx_users_account_id ON users (account_id);
+
+-- X の数値ユーザーID (id_str)。既存ユーザーは cmd/backfillxids で X に問い合わせて設定する。スクリーンネームでは照合しない
+ALTER TABLE users ADD COLUMN x_user_id VARCHAR(32);
+CREATE UNIQUE INDEX idx_users_x_user_id ON users (x_user_id);
+
+-- +goose Down
+DROP INDEX IF EXISTS idx_users_x_user_id;
+ALTER TABLE users DROP COLUMN x_user_id;
+DROP INDEX IF EXISTS idx_users_account_id;
+UPDATE users SET user_name = account_id, account_id = user_name;
+ALTER TABLE users ADD CONSTRAINT uni_users_account_id UNIQUE (account_id);
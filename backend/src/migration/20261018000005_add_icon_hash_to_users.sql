@@ -0,0 +1,6 @@
+-- +goose Up
+ALTER TABLE users ADD COLUMN icon_hash VARCHAR(64) NOT NULL DEFAULT '';
+UPDATE users SET icon_hash = encode(sha256(icon_data), 'hex') WHERE icon_data IS NOT NULL;
+
+-- +goose Down
+ALTER TABLE users DROP COLUMN icon_hash;
@@ -0,0 +1,15 @@
+-- +goose Up
+-- X 以外のプロバイダーでもログインできるよう、外部IDをプロバイダーごとに管理する
+DROP INDEX IF EXISTS idx_users_x_user_id;
+ALTER TABLE users RENAME COLUMN x_user_id TO external_id;
+ALTER TABLE users ALTER COLUMN external_id TYPE VARCHAR(255);
+ALTER TABLE users ADD COLUMN provider VARCHAR(32) NOT NULL DEFAULT 'x';
+CREATE UNIQUE INDEX idx_users_provider_external_id ON users (provider, external_id);
+
+-- +goose Down
+DROP INDEX IF EXISTS idx_users_provider_external_id;
+DELETE FROM users WHERE provider <> 'x';
+ALTER TABLE users DROP COLUMN provider;
+ALTER TABLE users ALTER COLUMN external_id TYPE VARCHAR(32);
+ALTER TABLE users RENAME COLUMN external_id TO x_user_id;
+CREATE UNIQUE INDEX idx_users_x_user_id ON users (x_user_id);
@@ -0,0 +1,41 @@
+-- +goose Up
+-- 1人のユーザーに複数のログイン方法を紐付けられるよう、外部IDを別テーブルで管理する
+CREATE TABLE user_identities (
+    id          BIGSERIAL    PRIMARY KEY,
+    user_id     BIGINT       NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+    provider    VARCHAR(32)  NOT NULL,
+    external_id VARCHAR(255) NOT NULL,
+    handle      VARCHAR(255) NOT NULL DEFAULT '',
+    created_at  TIMESTAMPTZ  NOT NULL,
+    updated_at  TIMESTAMPTZ  NOT NULL
+);
+
+CREATE UNIQUE INDEX idx_user_identities_provider_external_id ON user_identities (provider, external_id);
+CREATE INDEX idx_user_identities_user_id ON user_identities (user_id);
+
+INSERT INTO user_identities (user_id, provider, external_id, handle, created_at, updated_at)
+SELECT id, provider, external_id, account_id, created_at, updated_at
+FROM users
+WHERE external_id IS NOT NULL;
+
+DROP INDEX IF EXISTS idx_users_provider_external_id;
+ALTER TABLE users DROP COLUMN provider;
+ALTER TABLE users DROP COLUMN external_id;
+
+-- +goose Down
+ALTER TABLE users ADD COLUMN provider VARCHAR(32) NOT NULL DEFAULT 'x';
+ALTER TABLE users ADD COLUMN external_id VARCHAR(255);
+
+-- 複数のログイン方法がある場合は最初に紐付けたものを残す
+UPDATE users
+SET provider = first_identity.provider, external_id = first_identity.external_id
+FROM (
+    SELECT DISTINCT ON (user_id) user_id, provider, external_id
+    FROM user_identities
+    ORDER BY user_id, id
+) AS first_identity
+WHERE users.id = first_identity.user_id;
+
+CREATE UNIQUE INDEX idx_users_provider_external_id ON users (provider, external_id);
+
+DROP TABLE IF EXISTS user_identities;
@@ -0,0 +1,19 @@
+-- +goose Up
+CREATE TABLE personal_access_tokens (
+    id           BIGSERIAL    PRIMARY KEY,
+    user_id      BIGINT       NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+    name         VARCHAR(100) NOT NULL,
+    token_prefix VARCHAR(16)  NOT NULL,
+    token_hash   VARCHAR(64)  NOT NULL UNIQUE,
+    scopes       VARCHAR(255) NOT NULL,
+    expires_at   TIMESTAMPTZ,
+    last_used_at TIMESTAMPTZ,
+    revoked_at   TIMESTAMPTZ,
+    created_at   TIMESTAMPTZ  NOT NULL,
+    updated_at   TIMESTAMPTZ  NOT NULL
+);
+
+CREATE INDEX idx_personal_access_tokens_user_id ON personal_access_tokens (user_id);
+
+-- +goose Down
+DROP TABLE IF EXISTS personal_access_tokens;
@@ -0,0 +1,10 @@
+-- +goose Up
+-- 最初の管理者は UPDATE users SET role = 'admin' WHERE id = ...; で設定する
+ALTER TABLE users ADD COLUMN role VARCHAR(16) NOT NULL DEFAULT 'user';
+ALTER TABLE users ADD CONSTRAINT chk_users_role CHECK (role IN ('user', 'moderator', 'admin'));
+ALTER TABLE users ADD COLUMN suspended_at TIMESTAMPTZ;
+
+-- +goose Down
+ALTER TABLE users DROP COLUMN suspended_at;
+ALTER TABLE users DROP CONSTRAINT chk_users_role;
+ALTER TABLE users DROP COLUMN role;
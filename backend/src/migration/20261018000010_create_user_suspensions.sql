@@ -0,0 +1,37 @@
+-- +goose Up
+-- 理由と期限付きの利用停止。lifted_at が NULL で期限内のものを有効な停止とする
+CREATE TABLE user_suspensions (
+    id         BIGSERIAL   PRIMARY KEY,
+    user_id    BIGINT      NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+    reason     TEXT        NOT NULL,
+    created_by BIGINT      REFERENCES users (id) ON DELETE SET NULL,
+    expires_at TIMESTAMPTZ,
+    lifted_at  TIMESTAMPTZ,
+    lifted_by  BIGINT      REFERENCES users (id) ON DELETE SET NULL,
+    created_at TIMESTAMPTZ NOT NULL,
+    updated_at TIMESTAMPTZ NOT NULL
+);
+
+CREATE INDEX idx_user_suspensions_user_id ON user_suspensions (user_id);
+
+INSERT INTO user_suspensions (user_id, reason, created_at, updated_at)
+SELECT id, '', suspended_at, suspended_at
+FROM users
+WHERE suspended_at IS NOT NULL;
+
+ALTER TABLE users DROP COLUMN suspended_at;
+
+-- +goose Down
+ALTER TABLE users ADD COLUMN suspended_at TIMESTAMPTZ;
+
+UPDATE users
+SET suspended_at = active.created_at
+FROM (
+    SELECT user_id, MIN(created_at) AS created_at
+    FROM user_suspensions
+    WHERE lifted_at IS NULL AND (expires_at IS NULL OR expires_at > NOW())
+    GROUP BY user_id
+) AS active
+WHERE users.id = active.user_id;
+
+DROP TABLE IF EXISTS user_suspensions;
@@ -0,0 +1,13 @@
+-- +goose Up
+ALTER TABLE post_images ADD COLUMN mime_type VARCHAR(64) NOT NULL DEFAULT '';
+UPDATE post_images SET mime_type = CASE
+    WHEN substring(data FROM 1 FOR 3) = '\xffd8ff'::bytea THEN 'image/jpeg'
+    WHEN substring(data FROM 1 FOR 8) = '\x89504e470d0a1a0a'::bytea THEN 'image/png'
+    WHEN substring(data FROM 1 FOR 6) IN ('GIF87a'::bytea, 'GIF89a'::bytea) THEN 'image/gif'
+    WHEN substring(data FROM 1 FOR 4) = 'RIFF'::bytea AND substring(data FROM 9 FOR 4) = 'WEBP'::bytea THEN 'image/webp'
+    ELSE 'application/octet-stream'
+END
+WHERE data IS NOT NULL;
+
+-- +goose Down
+ALTER TABLE post_images DROP COLUMN mime_type;
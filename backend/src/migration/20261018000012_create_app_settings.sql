@@ -0,0 +1,18 @@
+-- +goose Up
+-- 管理者が変更できるアプリケーション全体の設定
+CREATE TABLE app_settings (
+    key        VARCHAR(64) PRIMARY KEY,
+    value      TEXT        NOT NULL,
+    updated_by BIGINT      REFERENCES users (id) ON DELETE SET NULL,
+    created_at TIMESTAMPTZ NOT NULL,
+    updated_at TIMESTAMPTZ NOT NULL
+);
+
+-- keep_upload_gps が有効な場合のみ、投稿者本人向けに撮影位置を残す
+ALTER TABLE post_images ADD COLUMN gps_latitude DOUBLE PRECISION;
+ALTER TABLE post_images ADD COLUMN gps_longitude DOUBLE PRECISION;
+
+-- +goose Down
+ALTER TABLE post_images DROP COLUMN gps_longitude;
+ALTER TABLE post_images DROP COLUMN gps_latitude;
+DROP TABLE app_settings;
@@ -0,0 +1,19 @@
+-- +goose Up
+-- 画像の内容は BlobStore に保存し、DB にはキーと属性のみを持つ。
+-- 既存の data, icon_data は cmd/migrateblobs で BlobStore にコピーする
+ALTER TABLE post_images ADD COLUMN storage_key VARCHAR(255);
+ALTER TABLE post_images ADD COLUMN size BIGINT NOT NULL DEFAULT 0;
+ALTER TABLE post_images ADD COLUMN checksum VARCHAR(64) NOT NULL DEFAULT '';
+CREATE INDEX idx_post_images_storage_key ON post_images (storage_key);
+
+ALTER TABLE users ADD COLUMN icon_storage_key VARCHAR(255);
+ALTER TABLE users ADD COLUMN icon_mime_type VARCHAR(64) NOT NULL DEFAULT '';
+
+-- +goose Down
+ALTER TABLE users DROP COLUMN icon_mime_type;
+ALTER TABLE users DROP COLUMN icon_storage_key;
+
+DROP INDEX idx_post_images_storage_key;
+ALTER TABLE post_images DROP COLUMN checksum;
+ALTER TABLE post_images DROP COLUMN size;
+ALTER TABLE post_images DROP COLUMN storage_key;
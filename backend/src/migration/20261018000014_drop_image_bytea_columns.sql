@@ -0,0 +1,20 @@
+-- +goose Up
+-- cmd/migrateblobs でコピーしていないデータが残っている場合は失敗させる
+-- +goose StatementBegin
+DO $$
+BEGIN
+    IF EXISTS (SELECT 1 FROM post_images WHERE data IS NOT NULL AND storage_key IS NULL)
+        OR EXISTS (SELECT 1 FROM users WHERE icon_data IS NOT NULL AND icon_storage_key IS NULL) THEN
+        RAISE EXCEPTION 'image data has not been copied to the blob store; run cmd/migrateblobs first';
+    END IF;
+END
+$$;
+-- +goose StatementEnd
+
+ALTER TABLE post_images DROP COLUMN data;
+ALTER TABLE users DROP COLUMN icon_data;
+
+-- +goose Down
+-- 内容は BlobStore に残っているため復元しない
+ALTER TABLE users ADD COLUMN icon_data BYTEA;
+ALTER TABLE post_images ADD COLUMN data BYTEA;
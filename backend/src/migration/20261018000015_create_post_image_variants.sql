@@ -0,0 +1,29 @@
+-- +goose Up
+-- アップロード時に生成する縮小画像。内容は BlobStore に storage_key で保存する。
+-- 既存の画像は cmd/regenerateimages で生成する
+CREATE TABLE post_image_variants (
+    id            BIGSERIAL    PRIMARY KEY,
+    post_image_id BIGINT       NOT NULL REFERENCES post_images (id) ON DELETE CASCADE,
+    name          VARCHAR(16)  NOT NULL,
+    storage_key   VARCHAR(255) NOT NULL,
+    mime_type     VARCHAR(64)  NOT NULL,
+    width         INTEGER      NOT NULL,
+    height        INTEGER      NOT NULL,
+    size          BIGINT       NOT NULL,
+    checksum      VARCHAR(64)  NOT NULL,
+    created_at    TIMESTAMPTZ  NOT NULL,
+    updated_at    TIMESTAMPTZ  NOT NULL,
+    UNIQUE (post_image_id, name)
+);
+
+CREATE INDEX idx_post_image_variants_storage_key ON post_image_variants (storage_key);
+
+-- srcset の元画像の幅に使う。0 は未計測
+ALTER TABLE post_images ADD COLUMN width INTEGER NOT NULL DEFAULT 0;
+ALTER TABLE post_images ADD COLUMN height INTEGER NOT NULL DEFAULT 0;
+
+-- +goose Down
+ALTER TABLE post_images DROP COLUMN height;
+ALTER TABLE post_images DROP COLUMN width;
+
+DROP TABLE IF EXISTS post_image_variants;
@@ -0,0 +1,8 @@
+-- +goose Up
+-- 画像の読み込み中に表示するプレースホルダー。既存の画像は cmd/regenerateimages で計算する
+ALTER TABLE post_images ADD COLUMN blur_hash VARCHAR(64) NOT NULL DEFAULT '';
+ALTER TABLE post_images ADD COLUMN dominant_color VARCHAR(7) NOT NULL DEFAULT '';
+
+-- +goose Down
+ALTER TABLE post_images DROP COLUMN dominant_color;
+ALTER TABLE post_images DROP COLUMN blur_hash;
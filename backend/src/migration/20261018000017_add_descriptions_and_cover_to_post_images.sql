@@ -0,0 +1,13 @@
+-- +goose Up
+ALTER TABLE post_images ADD COLUMN alt_text VARCHAR(255)  NOT NULL DEFAULT '';
+ALTER TABLE post_images ADD COLUMN caption  VARCHAR(1000) NOT NULL DEFAULT '';
+-- カバー画像が無い投稿は sort_order が最小の画像を一覧に表示する
+ALTER TABLE post_images ADD COLUMN is_cover BOOLEAN       NOT NULL DEFAULT FALSE;
+
+CREATE UNIQUE INDEX uniq_post_images_post_id_cover ON post_images (post_id) WHERE is_cover;
+
+-- +goose Down
+DROP INDEX IF EXISTS uniq_post_images_post_id_cover;
+ALTER TABLE post_images DROP COLUMN is_cover;
+ALTER TABLE post_images DROP COLUMN caption;
+ALTER TABLE post_images DROP COLUMN alt_text;
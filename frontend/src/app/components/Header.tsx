@@ -3,6 +3,11 @@
 import React, { useState, useEffect } from "react";
 import axios from "axios";
 import Image from "next/image";
+import { installAuthRefreshInterceptor } from "@/app/lib/authRefresh";
+import { installCsrfInterceptor } from "@/app/lib/csrf";
+
+installCsrfInterceptor();
+installAuthRefreshInterceptor();
 
 export const Header: React.FC = () => {
   const [isAuthenticated, setIsAuthenticated] = useState(false);
@@ -16,6 +21,7 @@ export const Header: React.FC = () => {
 
     try {
       const response = await axios.get(oauthURL, {
+        withCredentials: true,
         headers: {
           "Content-Type": "application/json",
         },
@@ -47,7 +53,7 @@ export const Header: React.FC = () => {
 
   const logout = () => {
     try {
-      const logoutAPI = `${process.env.NEXT_PUBLIC_API_URL}/logout`;
+      const logoutAPI = `${process.env.NEXT_PUBLIC_API_URL}/auth/logout`;
       axios.post(logoutAPI, {}, { withCredentials: true }).then(() => {
         localStorage.removeItem("auth_token");
         setIsAuthenticated(false);
@@ -1,6 +1,7 @@
 import Image from "next/image";
 import { Post } from "../types/post";
 import Link from "next/link";
+import { resolveImageURL } from "../lib/imageURL";
 
 interface PostCardProps {
   post: Post;
@@ -21,10 +22,15 @@ const PostCard: React.FC<PostCardProps> = ({ post }) => {
                   },
                 }}
               >
-                <div className="w-[250px] h-[250px] relative">
+                <div
+                  className="w-[250px] h-[250px] relative rounded-lg"
+                  style={{
+                    backgroundColor: post.post_image_dominant_color || undefined,
+                  }}
+                >
                   <Image
-                    src={post.post_image_base64 || "/noimage.jpg"}
-                    alt="icon"
+                    src={resolveImageURL(post.post_image_url)}
+                    alt={post.post_image_alt_text || post.title}
                     layout="fill"
                     objectFit="cover"
                     className="rounded-lg border"
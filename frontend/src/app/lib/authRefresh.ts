@@ -0,0 +1,44 @@
+import axios, { AxiosError, InternalAxiosRequestConfig } from "axios";
+
+type RetriableConfig = InternalAxiosRequestConfig & { _retried?: boolean };
+
+const refreshURL = `${process.env.NEXT_PUBLIC_API_URL}/auth/refresh`;
+
+let refreshing: Promise<void> | null = null;
+let installed = false;
+
+// アクセストークンの期限切れ (401) 時にトークンを更新し、元のリクエストを1度だけ再送する
+export const installAuthRefreshInterceptor = () => {
+  if (installed) {
+    return;
+  }
+  installed = true;
+
+  axios.interceptors.response.use(undefined, async (error: AxiosError) => {
+    const config = error.config as RetriableConfig | undefined;
+    if (
+      error.response?.status !== 401 ||
+      !config ||
+      config._retried ||
+      config.url === refreshURL
+    ) {
+      return Promise.reject(error);
+    }
+    config._retried = true;
+
+    // 同時に発生した 401 ではリフレッシュを1回にまとめる
+    refreshing ??= axios
+      .post(refreshURL, {}, { withCredentials: true })
+      .then(() => undefined)
+      .finally(() => {
+        refreshing = null;
+      });
+
+    try {
+      await refreshing;
+    } catch {
+      return Promise.reject(error);
+    }
+    return axios(config);
+  });
+};
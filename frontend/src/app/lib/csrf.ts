@@ -0,0 +1,73 @@
+import axios, { AxiosError, InternalAxiosRequestConfig } from "axios";
+import { ApiErrorResponse } from "../types/error";
+
+type RetriableConfig = InternalAxiosRequestConfig & { _csrfRetried?: boolean };
+
+const apiURL = process.env.NEXT_PUBLIC_API_URL ?? "";
+const csrfURL = `${apiURL}/csrf`;
+const safeMethods = ["get", "head", "options"];
+
+let csrfToken: string | null = null;
+let fetching: Promise<string> | null = null;
+let installed = false;
+
+const fetchCsrfToken = (): Promise<string> => {
+  fetching ??= axios
+    .get<{ csrf_token: string }>(csrfURL, { withCredentials: true })
+    .then((response) => {
+      csrfToken = response.data.csrf_token;
+      return csrfToken;
+    })
+    .finally(() => {
+      fetching = null;
+    });
+  return fetching;
+};
+
+// 認可エラーの 403 と区別するため、サーバーのエラーコードで判定する
+const isCsrfFailure = (error: AxiosError<ApiErrorResponse>) =>
+  error.response?.status === 403 &&
+  error.response.data?.error?.code === "invalid_csrf_token";
+
+const needsCsrfToken = (config: InternalAxiosRequestConfig) =>
+  !safeMethods.includes((config.method ?? "get").toLowerCase()) &&
+  (config.url ?? "").startsWith(apiURL);
+
+// API への GET 以外のリクエストに X-Csrf-Token ヘッダーを付ける。
+// トークンが無効 (403) になった場合は取得し直して1度だけ再送する
+export const installCsrfInterceptor = () => {
+  if (installed) {
+    return;
+  }
+  installed = true;
+
+  axios.interceptors.request.use(async (config) => {
+    if (!needsCsrfToken(config)) {
+      return config;
+    }
+    config.withCredentials = true;
+    config.headers.set("X-Csrf-Token", csrfToken ?? (await fetchCsrfToken()));
+    return config;
+  });
+
+  axios.interceptors.response.use(undefined, async (error: AxiosError<ApiErrorResponse>) => {
+    const config = error.config as RetriableConfig | undefined;
+    if (
+      !isCsrfFailure(error) ||
+      !config ||
+      config._csrfRetried ||
+      !needsCsrfToken(config)
+    ) {
+      return Promise.reject(error);
+    }
+    config._csrfRetried = true;
+
+    csrfToken = null;
+    try {
+      await fetchCsrfToken();
+    } catch {
+      return Promise.reject(error);
+    }
+    return axios(config);
+  });
+};
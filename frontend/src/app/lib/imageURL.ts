@@ -0,0 +1,8 @@
+const apiURL = process.env.NEXT_PUBLIC_API_URL ?? "";
+
+// API が返す "/api/..." の画像パスを API サーバーの URL にする。画像が無い場合は fallback を返す
+export const resolveImageURL = (path: string, fallback = "/noimage.jpg") => {
+  if (!path) return fallback;
+  if (!apiURL) return path;
+  return new URL(path, apiURL).toString();
+};
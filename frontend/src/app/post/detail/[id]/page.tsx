@@ -9,6 +9,7 @@ import { PostDetail } from "../../../types/post";
 import { User } from "../../../types/user";
 import { useRouter } from "next/navigation";
 import Link from "next/link";
+import { resolveImageURL } from "../../../lib/imageURL";
 
 const PostDetailPage: React.FC = () => {
   const router = useRouter();
@@ -33,7 +34,10 @@ const PostDetailPage: React.FC = () => {
           id: postDetail.id,
           title: postDetail.title,
           content: postDetail.content,
-          post_images_base64: postDetail.post_images_base64,
+          post_image_urls: postDetail.post_image_urls,
+          post_image_srcsets: postDetail.post_image_srcsets,
+          post_image_alt_texts: postDetail.post_image_alt_texts ?? [],
+          post_image_captions: postDetail.post_image_captions ?? [],
         });
       } catch (error) {
         console.error("Error fetching post details:", error);
@@ -50,7 +54,7 @@ const PostDetailPage: React.FC = () => {
           id: userDetail.id,
           user_name: userDetail.user_name,
           account_id: userDetail.account_id,
-          icon_image_base64: userDetail.icon_image_base64,
+          icon_image_url: userDetail.icon_image_url,
         });
 
         if (userDetail.id == Number(userId)) {
@@ -74,7 +78,7 @@ const PostDetailPage: React.FC = () => {
     if (postDetail) {
       setCurrentSlide((prevSlide) =>
         prevSlide === 0
-          ? postDetail.post_images_base64.length - 1
+          ? postDetail.post_image_urls.length - 1
           : prevSlide - 1
       );
     }
@@ -83,7 +87,7 @@ const PostDetailPage: React.FC = () => {
   const handleNextSlide = () => {
     if (postDetail) {
       setCurrentSlide((prevSlide) =>
-        prevSlide === postDetail.post_images_base64.length - 1
+        prevSlide === postDetail.post_image_urls.length - 1
           ? 0
           : prevSlide + 1
       );
@@ -187,18 +191,26 @@ const PostDetailPage: React.FC = () => {
               <Image
                 src={
                   postDetail != null
-                    ? postDetail.post_images_base64[currentSlide]
+                    ? resolveImageURL(postDetail.post_image_urls[currentSlide])
                     : "/noimage.jpg"
                 }
                 className="w-full max-h-96 object-contain"
-                alt="carousel image"
+                alt={
+                  postDetail?.post_image_alt_texts[currentSlide] ||
+                  "carousel image"
+                }
                 layout="intrinsic"
                 width={0}
                 height={0}
               />
             </div>
+            {postDetail?.post_image_captions[currentSlide] && (
+              <p className="mt-2 text-sm text-gray-600 whitespace-pre-wrap">
+                {postDetail.post_image_captions[currentSlide]}
+              </p>
+            )}
             {postDetail != null
-              ? postDetail.post_images_base64.length > 1 && (
+              ? postDetail.post_image_urls.length > 1 && (
                   <nav className="inline-flex w-full justify-between mt-4">
                     <button
                       className="flex items-center py-2 px-3 rounded font-medium select-none border text-gray-900 dark:text-white bg-white dark:bg-gray-800 transition-colors hover:border-blue-600 hover:bg-blue-400 hover:text-white dark:hover:text-white"
@@ -221,7 +233,7 @@ const PostDetailPage: React.FC = () => {
             <Link
               href={{
                 pathname: `https://twitter.com/${
-                  userDetail != null ? userDetail.user_name : ""
+                  userDetail != null ? userDetail.account_id : ""
                 }`,
               }}
             >
@@ -235,7 +247,7 @@ const PostDetailPage: React.FC = () => {
                           className="w-16 h-16 bg-gray-100 object-cover object-center flex-shrink-0 rounded-full mr-4"
                           src={
                             userDetail != null
-                              ? userDetail.icon_image_base64
+                              ? resolveImageURL(userDetail.icon_image_url)
                               : "/noimage.jpg"
                           }
                           width={64}
@@ -243,10 +255,10 @@ const PostDetailPage: React.FC = () => {
                         />
                         <div className="flex-grow">
                           <h2 className="text-gray-900 title-font font-medium">
-                            {userDetail != null ? userDetail.account_id : ""}
+                            {userDetail != null ? userDetail.user_name : ""}
                           </h2>
                           <p className="text-gray-500">
-                            @{userDetail != null ? userDetail.user_name : ""}
+                            @{userDetail != null ? userDetail.account_id : ""}
                           </p>
                         </div>
                       </div>
@@ -0,0 +1,12 @@
+export type FieldError = {
+  field: string;
+  message: string;
+};
+
+export type ApiErrorResponse = {
+  error: {
+    code: string;
+    message: string;
+    details: FieldError[];
+  };
+};
@@ -1,13 +1,26 @@
+// srcset の候補。幅の小さい順で、最後は元画像
+export type ImageSource = {
+  variant: "thumbnail" | "medium" | "original";
+  url: string;
+  width: number;
+  height: number;
+};
+
 export type Post = {
   id: number;
   title: string;
   content: string;
   content_title: string;
   location: string;
-  post_image_base64: string;
+  post_image_url: string;
+  post_image_srcset: ImageSource[] | null;
+  post_image_alt_text: string;
+  // 画像の読み込み前に表示するプレースホルダー。未計算の場合は空文字
+  post_image_blur_hash: string;
+  post_image_dominant_color: string;
   user_name: string;
   account_id: string;
-  icon_image_base64: string;
+  icon_image_url: string;
   is_own_post: boolean;
   user_id: number;
   created_at: string;
@@ -17,7 +30,11 @@ export type PostDetail = {
   id: number;
   title: string;
   content: string;
-  post_images_base64: string[];
+  post_image_urls: string[];
+  post_image_srcsets: ImageSource[][];
+  // post_image_urls と同じ順。未入力の場合は空文字
+  post_image_alt_texts: string[];
+  post_image_captions: string[];
 };
 
 export type Paging = {
@@ -2,5 +2,5 @@ export type User = {
   id: number;
   user_name: string;
   account_id: string;
-  icon_image_base64: string;
+  icon_image_url: string;
 };